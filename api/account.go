@@ -1,14 +1,13 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"net/http"
 
 	db "github.com/WilliamOdinson/simplebank/db/sqlc"
 	"github.com/WilliamOdinson/simplebank/token"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // only allow the owner and currency to be set when creating an account
@@ -21,10 +20,9 @@ type getAccountRequest struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
 
-// to list accounts with pagination
+// to list accounts page by page, oldest first
 type listAccountsRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	pageRequest
 }
 
 func (server *Server) createAccount(ctx *gin.Context) {
@@ -43,12 +41,9 @@ func (server *Server) createAccount(ctx *gin.Context) {
 	})
 
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
+		if errors.Is(err, db.ErrForeignKeyViolation) || errors.Is(err, db.ErrUniqueViolation) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -64,25 +59,34 @@ func (server *Server) getAccount(ctx *gin.Context) {
 		return
 	}
 
-	account, err := server.store.GetAccount(ctx, req.ID)
+	account, valid := server.readableAccount(ctx, req.ID)
+	if !valid {
+		return
+	}
 
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	ctx.JSON(http.StatusOK, account)
+}
 
-	if err == sql.ErrNoRows {
+// readableAccount loads the account with given ID if the authenticated user may read it.
+func (server *Server) readableAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if errors.Is(err, db.ErrRecordNotFound) {
 		ctx.JSON(http.StatusNotFound, errorResponse(err))
-		return
+		return account, false
 	} else if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		return account, false
 	}
 
-	if authPayload.Username != account.Owner {
+	// Bankers and admins may read any account, everyone else only their own
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Username != account.Owner && !hasScope(authPayload, scopeAccountsReadAny) {
 		err := errors.New("account does not belong to the authenticated user")
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
-		return
+		return account, false
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	return account, true
 }
 
 func (server *Server) listAccounts(ctx *gin.Context) {
@@ -92,18 +96,34 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 		return
 	}
 
+	cursor, err := decodeCursor(req.Cursor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	accounts, err := server.store.ListAccounts(ctx, db.ListAccountsParams{
-		Owner:  authPayload.Username,
-		Limit:  req.PageSize,
-		Offset: (req.PageID - 1) * req.PageSize,
-	})
+	createdAt, id := cursor.position(false)
+	arg := db.ListAccountsAfterParams{
+		Owner:           authPayload.Username,
+		CursorCreatedAt: createdAt,
+		CursorID:        id,
+		PageLimit:       req.PageSize + 1,
+	}
 
+	var accounts []db.Account
+	if cursor.backward() {
+		accounts, err = server.store.ListAccountsBefore(ctx, db.ListAccountsBeforeParams(arg))
+	} else {
+		accounts, err = server.store.ListAccountsAfter(ctx, arg)
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
+	ctx.JSON(http.StatusOK, newPageResponse(accounts, req.PageSize, cursor, func(account db.Account) (pgtype.Timestamptz, int64) {
+		return account.CreatedAt, account.ID
+	}))
 }
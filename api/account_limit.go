@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// setAccountLimitsRequest overrides the default transfer limits of the account's currency.
+// A limit left out or set to 0 falls back to the default.
+type setAccountLimitsRequest struct {
+	PerTransfer int64 `json:"per_transfer" binding:"omitempty,gt=0"`
+	Daily       int64 `json:"daily" binding:"omitempty,gt=0"`
+	Monthly     int64 `json:"monthly" binding:"omitempty,gt=0"`
+}
+
+// accountLimitsResponse shows the transfer limits in force on an account and the ones it overrides.
+// Amounts are in the account's currency and 0 means no limit, or no override.
+type accountLimitsResponse struct {
+	AccountID int64               `json:"account_id"`
+	Currency  string              `json:"currency"`
+	Limits    util.TransferLimits `json:"limits"`
+	Overrides util.TransferLimits `json:"overrides"`
+}
+
+func newAccountLimitsResponse(account db.Account, defaults util.TransferLimits, override db.AccountLimit) accountLimitsResponse {
+	return accountLimitsResponse{
+		AccountID: account.ID,
+		Currency:  account.Currency,
+		Limits:    db.EffectiveTransferLimits(defaults, override),
+		Overrides: util.TransferLimits{
+			PerTransfer: override.PerTransfer.Int64,
+			Daily:       override.Daily.Int64,
+			Monthly:     override.Monthly.Int64,
+		},
+	}
+}
+
+// getAccountLimits shows the transfer limits of an account to its owner, bankers and admins.
+func (server *Server) getAccountLimits(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.readableAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	override, err := server.store.GetAccountLimits(ctx, account.ID)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountLimitsResponse(account, server.config.TransferLimits[account.Currency], override))
+}
+
+// setAccountLimits replaces the limit overrides of an account. Only bankers and admins may change
+// limits, so a stolen depositor token cannot raise them before draining the account.
+func (server *Server) setAccountLimits(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req setAccountLimitsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	override, err := server.store.SetAccountLimits(ctx, db.SetAccountLimitsParams{
+		AccountID:   account.ID,
+		PerTransfer: pgtype.Int8{Int64: req.PerTransfer, Valid: req.PerTransfer > 0},
+		Daily:       pgtype.Int8{Int64: req.Daily, Valid: req.Daily > 0},
+		Monthly:     pgtype.Int8{Int64: req.Monthly, Valid: req.Monthly > 0},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountLimitsResponse(account, server.config.TransferLimits[account.Currency], override))
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGetAccountLimitsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	account := randomAccountForUser(user.Username)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Defaults",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					GetAccountLimits(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.AccountLimit{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				checkAccountLimits(t, recorder,
+					util.TransferLimits{PerTransfer: 1000, Daily: 5000, Monthly: 20000},
+					util.TransferLimits{})
+			},
+		},
+		{
+			name: "Overridden",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					GetAccountLimits(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.AccountLimit{AccountID: account.ID, Daily: pgtype.Int8{Int64: 200, Valid: true}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				checkAccountLimits(t, recorder,
+					util.TransferLimits{PerTransfer: 1000, Daily: 200, Monthly: 20000},
+					util.TransferLimits{Daily: 200})
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/limits", account.ID)
+			request := httptest.NewRequest(http.MethodGet, url, nil)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSetAccountLimitsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	banker, _ := randomUser(t)
+	account := randomAccountForUser(user.Username)
+
+	testCases := []struct {
+		name          string
+		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{"per_transfer": 300, "daily": 600},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, banker.Username, util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.SetAccountLimitsParams{
+					AccountID:   account.ID,
+					PerTransfer: pgtype.Int8{Int64: 300, Valid: true},
+					Daily:       pgtype.Int8{Int64: 600, Valid: true},
+				}
+				store.EXPECT().
+					SetAccountLimits(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.AccountLimit{AccountID: account.ID, PerTransfer: arg.PerTransfer, Daily: arg.Daily}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				checkAccountLimits(t, recorder,
+					util.TransferLimits{PerTransfer: 300, Daily: 600, Monthly: 20000},
+					util.TransferLimits{PerTransfer: 300, Daily: 600})
+			},
+		},
+		{
+			// a stolen depositor token must not be able to raise its own limits
+			name: "Depositor",
+			body: map[string]any{"daily": 1000000},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().SetAccountLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusForbidden {
+					t.Errorf("expected status code 403, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "NegativeLimit",
+			body: map[string]any{"monthly": -1},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, banker.Username, util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().SetAccountLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "AccountNotFound",
+			body: map[string]any{"daily": 600},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, banker.Username, util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().SetAccountLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, _ := json.Marshal(tc.body)
+			url := fmt.Sprintf("/accounts/%d/limits", account.ID)
+			request := httptest.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// checkAccountLimits checks that the response shows the given limits in force and overrides
+func checkAccountLimits(t *testing.T, recorder *httptest.ResponseRecorder, wantLimits, wantOverrides util.TransferLimits) {
+	t.Helper()
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", recorder.Code)
+	}
+
+	var got accountLimitsResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if got.Limits != wantLimits {
+		t.Errorf("expected limits %+v, got %+v", wantLimits, got.Limits)
+	}
+	if got.Overrides != wantOverrides {
+		t.Errorf("expected overrides %+v, got %+v", wantOverrides, got.Overrides)
+	}
+}
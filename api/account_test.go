@@ -2,19 +2,20 @@ package api
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
 	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
 	db "github.com/WilliamOdinson/simplebank/db/sqlc"
 	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/brianvoe/gofakeit/v7"
-	"github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/mock/gomock"
 )
 
@@ -72,6 +73,24 @@ func TestGetAccountAPI(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:      "BankerReadsAnyAccount",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, "banker_user", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
 		{
 			name:      "NoAuthorization",
 			accountID: account.ID,
@@ -99,7 +118,7 @@ func TestGetAccountAPI(t *testing.T) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
-					Return(db.Account{}, sql.ErrNoRows) // Simulate not found error
+					Return(db.Account{}, db.ErrRecordNotFound) // Simulate not found error
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusNotFound {
@@ -227,6 +246,25 @@ func TestCreateAccountAPI(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "BankerCannotCreate",
+			body: map[string]any{
+				"currency": "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusForbidden {
+					t.Errorf("expected status code 403, got %d", recorder.Code)
+				}
+			},
+		},
 		{
 			name: "NoAuthorization",
 			body: map[string]any{
@@ -379,7 +417,7 @@ func TestCreateAccountAPI(t *testing.T) {
 				store.EXPECT().
 					CreateAccount(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.Account{}, &pq.Error{Code: "23503"})
+					Return(db.Account{}, db.ErrForeignKeyViolation)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusForbidden {
@@ -399,7 +437,7 @@ func TestCreateAccountAPI(t *testing.T) {
 				store.EXPECT().
 					CreateAccount(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.Account{}, &pq.Error{Code: "23505"})
+					Return(db.Account{}, db.ErrUniqueViolation)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusForbidden {
@@ -448,28 +486,59 @@ func TestListAccountsAPI(t *testing.T) {
 	for i := 0; i < n; i++ {
 		accounts[i] = randomAccountForUser(user.Username)
 	}
+	createdAt, id := pageCursor{}.position(false)
+	cursor := pageCursor{CreatedAt: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), ID: 7, Direction: cursorNext}
 
 	testCases := []struct {
 		name          string
-		pageID        int32
-		pageSize      int32
+		query         url.Values
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
-			name:     "OK",
-			pageID:   1,
-			pageSize: 5,
+			name:  "OK",
+			query: url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccountsAfter(gomock.Any(), db.ListAccountsAfterParams{
+						Owner:           user.Username,
+						CursorCreatedAt: createdAt,
+						CursorID:        id,
+						PageLimit:       6,
+					}).
+					Times(1).
+					Return(accounts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got pageResponse[db.Account]
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(got.Items) != n || got.HasMore || got.NextCursor != "" || got.PrevCursor != "" {
+					t.Errorf("expected a single page of %d accounts, got %+v", n, got)
+				}
+			},
+		},
+		{
+			name:  "DefaultPageSize",
+			query: url.Values{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), db.ListAccountsParams{
-						Owner:  user.Username,
-						Limit:  5,
-						Offset: 0,
+					ListAccountsAfter(gomock.Any(), db.ListAccountsAfterParams{
+						Owner:           user.Username,
+						CursorCreatedAt: createdAt,
+						CursorID:        id,
+						PageLimit:       21,
 					}).
 					Times(1).
 					Return(accounts, nil)
@@ -481,15 +550,14 @@ func TestListAccountsAPI(t *testing.T) {
 			},
 		},
 		{
-			name:     "NoAuthorization",
-			pageID:   1,
-			pageSize: 5,
+			name:  "NoAuthorization",
+			query: url.Values{"page_size": {"5"}},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				// Don't add authorization
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Any()).
+					ListAccountsAfter(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -499,44 +567,52 @@ func TestListAccountsAPI(t *testing.T) {
 			},
 		},
 		{
-			name:     "SecondPage",
-			pageID:   2,
-			pageSize: 5,
+			name:  "NextPage",
+			query: url.Values{"page_size": {"2"}, "cursor": {cursor.encode()}},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), db.ListAccountsParams{
-						Owner:  user.Username,
-						Limit:  5,
-						Offset: 5,
+					ListAccountsAfter(gomock.Any(), db.ListAccountsAfterParams{
+						Owner:           user.Username,
+						CursorCreatedAt: pgtype.Timestamptz{Time: cursor.CreatedAt, Valid: true},
+						CursorID:        cursor.ID,
+						PageLimit:       3,
 					}).
 					Times(1).
-					Return(accounts, nil)
+					Return(accounts[:3], nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusOK {
-					t.Errorf("expected status code 200, got %d", recorder.Code)
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got pageResponse[db.Account]
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(got.Items) != 2 || !got.HasMore || got.NextCursor == "" || got.PrevCursor == "" {
+					t.Errorf("expected two accounts with cursors both ways, got %+v", got)
 				}
 			},
 		},
 		{
-			name:     "MaxPageSize",
-			pageID:   1,
-			pageSize: 10,
+			name:  "PreviousPage",
+			query: url.Values{"page_size": {"2"}, "cursor": {pageCursor{CreatedAt: cursor.CreatedAt, ID: cursor.ID, Direction: cursorPrev}.encode()}},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Any()).Times(0)
 				store.EXPECT().
-					ListAccounts(gomock.Any(), db.ListAccountsParams{
-						Owner:  user.Username,
-						Limit:  10,
-						Offset: 0,
+					ListAccountsBefore(gomock.Any(), db.ListAccountsBeforeParams{
+						Owner:           user.Username,
+						CursorCreatedAt: pgtype.Timestamptz{Time: cursor.CreatedAt, Valid: true},
+						CursorID:        cursor.ID,
+						PageLimit:       3,
 					}).
 					Times(1).
-					Return(accounts, nil)
+					Return(accounts[:2], nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusOK {
@@ -545,15 +621,14 @@ func TestListAccountsAPI(t *testing.T) {
 			},
 		},
 		{
-			name:     "InternalError",
-			pageID:   1,
-			pageSize: 5,
+			name:  "InternalError",
+			query: url.Values{"page_size": {"5"}},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Any()).
+					ListAccountsAfter(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(nil, fmt.Errorf("internal error"))
 			},
@@ -564,33 +639,14 @@ func TestListAccountsAPI(t *testing.T) {
 			},
 		},
 		{
-			name:     "InvalidPageID",
-			pageID:   0,
-			pageSize: 5,
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
-			},
-			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Any()).
-					Times(0)
-			},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusBadRequest {
-					t.Errorf("expected status code 400, got %d", recorder.Code)
-				}
-			},
-		},
-		{
-			name:     "NegativePageID",
-			pageID:   -1,
-			pageSize: 5,
+			name:  "InvalidCursor",
+			query: url.Values{"page_size": {"5"}, "cursor": {"%%%"}},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Any()).
+					ListAccountsAfter(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -600,15 +656,14 @@ func TestListAccountsAPI(t *testing.T) {
 			},
 		},
 		{
-			name:     "PageSizeTooSmall",
-			pageID:   1,
-			pageSize: 4,
+			name:  "PageSizeTooSmall",
+			query: url.Values{"page_size": {"0"}},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Any()).
+					ListAccountsAfter(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -618,15 +673,14 @@ func TestListAccountsAPI(t *testing.T) {
 			},
 		},
 		{
-			name:     "PageSizeTooLarge",
-			pageID:   1,
-			pageSize: 11,
+			name:  "PageSizeTooLarge",
+			query: url.Values{"page_size": {"101"}},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Any()).
+					ListAccountsAfter(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -652,8 +706,7 @@ func TestListAccountsAPI(t *testing.T) {
 			recorder := httptest.NewRecorder()
 
 			// 4. Create request
-			url := fmt.Sprintf("/accounts?page_id=%d&page_size=%d", tc.pageID, tc.pageSize)
-			request := httptest.NewRequest(http.MethodGet, url, nil)
+			request := httptest.NewRequest(http.MethodGet, "/accounts?"+tc.query.Encode(), nil)
 
 			// 5. Serve the request
 			tc.setupAuth(t, request, server.tokenMaker)
@@ -663,70 +716,6 @@ func TestListAccountsAPI(t *testing.T) {
 	}
 }
 
-func TestListAccountsAPI_MissingParams(t *testing.T) {
-	user, _ := randomUser(t)
-
-	testCases := []struct {
-		name          string
-		url           string
-		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
-	}{
-		{
-			name: "MissingPageID",
-			url:  "/accounts?page_size=5",
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusBadRequest {
-					t.Errorf("expected status code 400, got %d", recorder.Code)
-				}
-			},
-		},
-		{
-			name: "MissingPageSize",
-			url:  "/accounts?page_id=1",
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusBadRequest {
-					t.Errorf("expected status code 400, got %d", recorder.Code)
-				}
-			},
-		},
-		{
-			name: "MissingAllParams",
-			url:  "/accounts",
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusBadRequest {
-					t.Errorf("expected status code 400, got %d", recorder.Code)
-				}
-			},
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			// 1. Setup controller and mock store
-			ctrl := gomock.NewController(t)
-			defer ctrl.Finish()
-
-			// 2. Create mock store
-			store := mockdb.NewMockStore(ctrl)
-			store.EXPECT().
-				ListAccounts(gomock.Any(), gomock.Any()).
-				Times(0)
-
-			// 3. Create server and recorder
-			server := newTestServer(t, store)
-			recorder := httptest.NewRecorder()
-
-			// 4. Create request
-			request := httptest.NewRequest(http.MethodGet, tc.url, nil)
-			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
-
-			// 5. Serve the request
-			server.router.ServeHTTP(recorder, request)
-			tc.checkResponse(t, recorder)
-		})
-	}
-}
-
 func TestServerStart(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
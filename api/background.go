@@ -0,0 +1,26 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// backgroundTaskTimeout bounds a background task, so a slow mail server cannot pile up goroutines
+const backgroundTaskTimeout = 30 * time.Second
+
+// runInBackground runs task after the handler returns, with a context of its own because the
+// request context is cancelled once the response is sent. Failures can only be logged.
+func (server *Server) runInBackground(name string, task func(ctx context.Context) error) {
+	server.background.Add(1)
+	go func() {
+		defer server.background.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundTaskTimeout)
+		defer cancel()
+
+		if err := task(ctx); err != nil {
+			log.Printf("cannot %s: %v", name, err)
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestShutdownWaitsForBackgroundTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	server.runInBackground("wait for release", func(ctx context.Context) error {
+		<-release
+		close(finished)
+		return nil
+	})
+
+	// a task still running keeps Shutdown waiting until its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	close(release)
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("expected Shutdown to return after the task finished")
+	}
+}
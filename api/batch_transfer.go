@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// batchTransferLeg moves Amount in Currency between two accounts in that currency.
+// FromAccountID may be left out to use the batch's source account.
+type batchTransferLeg struct {
+	FromAccountID int64  `json:"from_account_id" binding:"omitempty,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	transferDetails
+}
+
+// batchTransferRequest executes all legs or none. FromAccountID is the source account of
+// the legs that do not name one, e.g. the payroll account paying many employees.
+type batchTransferRequest struct {
+	FromAccountID int64              `json:"from_account_id" binding:"omitempty,min=1"`
+	Legs          []batchTransferLeg `json:"legs" binding:"required,min=1,max=500,dive"`
+	// MFACode is the TOTP code batches moving more than the MFA_STEP_UP_AMOUNT of a currency in total need
+	MFACode string `json:"mfa_code,omitempty" binding:"omitempty,numeric,len=6"`
+}
+
+func (server *Server) createBatchTransfer(ctx *gin.Context) {
+	var req batchTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	legs, valid := server.validBatchLegs(ctx, req)
+	if !valid {
+		return
+	}
+
+	// the legs are added up, so splitting a large amount into small legs needs a code just the same
+	totals := make(map[string]int64)
+	for _, leg := range req.Legs {
+		totals[leg.Currency] += leg.Amount
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !server.stepUpVerified(ctx, authPayload.Username, totals, req.MFACode) {
+		return
+	}
+
+	result, err := server.store.BatchTransferTx(ctx, db.BatchTransferTxParams{Legs: legs})
+	if err != nil {
+		status, resp := transferError(err)
+		var legErr *db.BatchLegError
+		if errors.As(err, &legErr) {
+			resp["leg"] = legErr.Leg
+		}
+		ctx.JSON(status, resp)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// validBatchLegs checks every leg of a batch before any money moves: both accounts must exist and
+// be in the leg's currency, and the authenticated user must own the source account. Errors are
+// reported with the index of the offending leg.
+func (server *Server) validBatchLegs(ctx *gin.Context, req batchTransferRequest) ([]db.TransferTxParams, bool) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// each account is looked up once however many legs use it
+	accounts := make(map[int64]db.Account)
+	getAccount := func(id int64) (db.Account, error) {
+		if account, ok := accounts[id]; ok {
+			return account, nil
+		}
+		account, err := server.store.GetAccount(ctx, id)
+		if err == nil {
+			accounts[id] = account
+		}
+		return account, err
+	}
+
+	checkLeg := func(fromAccountID int64, leg batchTransferLeg) (int, error) {
+		if fromAccountID == 0 {
+			return http.StatusBadRequest, errors.New("from_account_id is required")
+		}
+		if fromAccountID == leg.ToAccountID {
+			return http.StatusBadRequest, errors.New("from and to account must differ")
+		}
+
+		for _, id := range []int64{fromAccountID, leg.ToAccountID} {
+			account, err := getAccount(id)
+			if errors.Is(err, db.ErrRecordNotFound) {
+				return http.StatusNotFound, err
+			} else if err != nil {
+				return http.StatusInternalServerError, err
+			}
+
+			if account.Currency != leg.Currency {
+				return http.StatusBadRequest, fmt.Errorf("account ID %d currency mismatch: expected %s, got %s", id, leg.Currency, account.Currency)
+			}
+			if id == fromAccountID && account.Owner != authPayload.Username {
+				return http.StatusUnauthorized, fmt.Errorf("from account %d does not belong to the authenticated user", id)
+			}
+		}
+
+		return http.StatusOK, nil
+	}
+
+	legs := make([]db.TransferTxParams, len(req.Legs))
+	for i, leg := range req.Legs {
+		fromAccountID := leg.FromAccountID
+		if fromAccountID == 0 {
+			fromAccountID = req.FromAccountID
+		}
+
+		if status, err := checkLeg(fromAccountID, leg); err != nil {
+			resp := errorResponse(err)
+			resp["leg"] = i
+			ctx.JSON(status, resp)
+			return nil, false
+		}
+
+		legs[i] = leg.transferTxParams(fromAccountID, leg.ToAccountID, leg.Amount)
+	}
+
+	return legs, true
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateBatchTransferAPI(t *testing.T) {
+	employer, _ := randomUser(t)
+	employee, _ := randomUser(t)
+
+	payroll := db.Account{ID: 1, Owner: employer.Username, Balance: 10000, AvailableBalance: 10000, Currency: util.USD}
+	savings := db.Account{ID: 2, Owner: employer.Username, Balance: 500, AvailableBalance: 500, Currency: util.USD}
+	salary1 := db.Account{ID: 3, Owner: employee.Username, Balance: 0, Currency: util.USD}
+	salary2 := db.Account{ID: 4, Owner: employee.Username, Balance: 0, Currency: util.EUR}
+
+	payrollLegs := []db.TransferTxParams{
+		{
+			FromAccountID: payroll.ID,
+			ToAccountID:   salary1.ID,
+			Amount:        3000,
+			Description:   pgtype.Text{String: "March salary", Valid: true},
+			Reference:     pgtype.Text{String: "PAY-2026-03", Valid: true},
+		},
+		{FromAccountID: savings.ID, ToAccountID: salary1.ID, Amount: 200},
+	}
+
+	stubAccounts := func(store *mockdb.MockStore, accounts ...db.Account) {
+		for _, account := range accounts {
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{
+				"from_account_id": payroll.ID,
+				"legs": []map[string]any{
+					{"to_account_id": salary1.ID, "amount": 3000, "currency": util.USD, "description": "March salary", "reference": "PAY-2026-03"},
+					{"from_account_id": savings.ID, "to_account_id": salary1.ID, "amount": 200, "currency": util.USD},
+				},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, employer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// salary1 is used by both legs but only looked up once
+				stubAccounts(store, payroll, salary1, savings)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Eq(db.BatchTransferTxParams{Legs: payrollLegs})).
+					Times(1).
+					Return(db.BatchTransferTxResult{Legs: []db.TransferTxResult{
+						{Transfer: db.Transfer{ID: 10, FromAccountID: payroll.ID, ToAccountID: salary1.ID, Amount: 3000}},
+						{Transfer: db.Transfer{ID: 11, FromAccountID: savings.ID, ToAccountID: salary1.ID, Amount: 200}},
+					}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got db.BatchTransferTxResult
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(got.Legs) != 2 || got.Legs[0].Transfer.ID != 10 || got.Legs[1].Transfer.ID != 11 {
+					t.Errorf("unexpected legs %+v", got.Legs)
+				}
+			},
+		},
+		{
+			name: "LegFailsInTransaction",
+			body: map[string]any{
+				"from_account_id": payroll.ID,
+				"legs": []map[string]any{
+					{"to_account_id": salary1.ID, "amount": 3000, "currency": util.USD},
+					{"from_account_id": savings.ID, "to_account_id": salary1.ID, "amount": 200, "currency": util.USD},
+				},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, employer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store, payroll, salary1, savings)
+				err := &db.BatchLegError{Leg: 1, Err: &db.InsufficientFundsError{AccountID: savings.ID, Available: 100, Requested: 200}}
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.BatchTransferTxResult{}, err)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Fatalf("expected status code 422, got %d", recorder.Code)
+				}
+				var resp struct {
+					Code string `json:"code"`
+					Leg  int    `json:"leg"`
+				}
+				if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if resp.Code != errCodeInsufficientFunds || resp.Leg != 1 {
+					t.Errorf("expected insufficient funds in leg 1, got %+v", resp)
+				}
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: map[string]any{
+				"from_account_id": payroll.ID,
+				"legs": []map[string]any{
+					{"to_account_id": salary1.ID, "amount": 3000, "currency": util.USD},
+					{"to_account_id": salary2.ID, "amount": 3000, "currency": util.USD},
+				},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, employer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store, payroll, salary1, salary2)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				checkBatchLegError(t, recorder, http.StatusBadRequest, 1)
+			},
+		},
+		{
+			name: "SourceOfAnotherUser",
+			body: map[string]any{
+				"legs": []map[string]any{
+					{"from_account_id": payroll.ID, "to_account_id": savings.ID, "amount": 100, "currency": util.USD},
+				},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, employee.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store, payroll)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				checkBatchLegError(t, recorder, http.StatusUnauthorized, 0)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			body: map[string]any{
+				"from_account_id": payroll.ID,
+				"legs": []map[string]any{
+					{"to_account_id": 99, "amount": 100, "currency": util.USD},
+				},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, employer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store, payroll)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(99))).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				checkBatchLegError(t, recorder, http.StatusNotFound, 0)
+			},
+		},
+		{
+			name: "MissingSource",
+			body: map[string]any{
+				"legs": []map[string]any{
+					{"to_account_id": salary1.ID, "amount": 100, "currency": util.USD},
+				},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, employer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				checkBatchLegError(t, recorder, http.StatusBadRequest, 0)
+			},
+		},
+		{
+			name: "SameAccount",
+			body: map[string]any{
+				"from_account_id": payroll.ID,
+				"legs": []map[string]any{
+					{"to_account_id": salary1.ID, "amount": 100, "currency": util.USD},
+					{"to_account_id": payroll.ID, "amount": 100, "currency": util.USD},
+				},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, employer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store, payroll, salary1)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				checkBatchLegError(t, recorder, http.StatusBadRequest, 1)
+			},
+		},
+		{
+			name: "NoLegs",
+			body: map[string]any{
+				"from_account_id": payroll.ID,
+				"legs":            []map[string]any{},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, employer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "InvalidLeg",
+			body: map[string]any{
+				"from_account_id": payroll.ID,
+				"legs": []map[string]any{
+					{"to_account_id": salary1.ID, "amount": -1, "currency": util.USD},
+				},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, employer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			if err != nil {
+				t.Fatalf("failed to marshal body: %v", err)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewReader(data))
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// checkBatchLegError checks the status code and that the error names the offending leg
+func checkBatchLegError(t *testing.T, recorder *httptest.ResponseRecorder, wantStatus int, wantLeg int) {
+	t.Helper()
+	if recorder.Code != wantStatus {
+		t.Errorf("expected status code %d, got %d", wantStatus, recorder.Code)
+	}
+
+	var resp struct {
+		Error string `json:"error"`
+		Leg   *int   `json:"leg"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if resp.Leg == nil {
+		t.Fatalf("expected error in leg %d, got no leg: %s", wantLeg, resp.Error)
+	}
+	if *resp.Leg != wantLeg {
+		t.Errorf("expected error in leg %d, got leg %d: %s", wantLeg, *resp.Leg, resp.Error)
+	}
+}
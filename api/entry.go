@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// to filter an account's entries or transfers when rendering a statement, newest first.
+// Amounts are compared in the account's currency, ignoring the sign.
+type listHistoryRequest struct {
+	pageRequest
+	StartTime time.Time `form:"start_time"`
+	EndTime   time.Time `form:"end_time" binding:"omitempty,gtfield=StartTime"`
+	MinAmount int64     `form:"min_amount" binding:"omitempty,min=1"`
+	MaxAmount int64     `form:"max_amount" binding:"omitempty,gtefield=MinAmount"`
+	Direction string    `form:"direction" binding:"omitempty,oneof=incoming outgoing"`
+	Reference string    `form:"reference" binding:"omitempty,max=64"`
+}
+
+func (req listHistoryRequest) startTime() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: req.StartTime, Valid: !req.StartTime.IsZero()}
+}
+
+func (req listHistoryRequest) endTime() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: req.EndTime, Valid: !req.EndTime.IsZero()}
+}
+
+func (req listHistoryRequest) minAmount() pgtype.Int8 {
+	return pgtype.Int8{Int64: req.MinAmount, Valid: req.MinAmount > 0}
+}
+
+func (req listHistoryRequest) maxAmount() pgtype.Int8 {
+	return pgtype.Int8{Int64: req.MaxAmount, Valid: req.MaxAmount > 0}
+}
+
+func (req listHistoryRequest) direction() pgtype.Text {
+	return pgtype.Text{String: req.Direction, Valid: req.Direction != ""}
+}
+
+func (req listHistoryRequest) reference() pgtype.Text {
+	return pgtype.Text{String: req.Reference, Valid: req.Reference != ""}
+}
+
+// listAccountEntries lists the balance changes of an account, newest first.
+func (server *Server) listAccountEntries(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listHistoryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	cursor, err := decodeCursor(req.Cursor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, valid := server.readableAccount(ctx, uri.ID); !valid {
+		return
+	}
+
+	createdAt, id := cursor.position(true)
+	arg := db.ListAccountEntriesBeforeParams{
+		AccountID:       uri.ID,
+		CursorCreatedAt: createdAt,
+		CursorID:        id,
+		StartTime:       req.startTime(),
+		EndTime:         req.endTime(),
+		MinAmount:       req.minAmount(),
+		MaxAmount:       req.maxAmount(),
+		Direction:       req.direction(),
+		Reference:       req.reference(),
+		PageLimit:       req.PageSize + 1,
+	}
+
+	var entries []db.Entry
+	if cursor.backward() {
+		entries, err = server.store.ListAccountEntriesAfter(ctx, db.ListAccountEntriesAfterParams(arg))
+	} else {
+		entries, err = server.store.ListAccountEntriesBefore(ctx, arg)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(entries, req.PageSize, cursor, func(entry db.Entry) (pgtype.Timestamptz, int64) {
+		return entry.CreatedAt, entry.ID
+	}))
+}
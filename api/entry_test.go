@@ -0,0 +1,340 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListAccountEntriesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := db.Account{ID: 10, Owner: user.Username, Balance: 1000, Currency: "USD"}
+	entries := []db.Entry{
+		{ID: 2, AccountID: account.ID, Amount: -50},
+		{ID: 1, AccountID: account.ID, Amount: 100},
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	cursorTime := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		query         url.Values
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				createdAt, id := pageCursor{}.position(true)
+				arg := db.ListAccountEntriesBeforeParams{AccountID: account.ID, CursorCreatedAt: createdAt, CursorID: id, PageLimit: 6}
+				store.EXPECT().
+					ListAccountEntriesBefore(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got pageResponse[db.Entry]
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(got.Items) != len(entries) || got.Items[0].ID != entries[0].ID {
+					t.Errorf("expected entries %+v, got %+v", entries, got.Items)
+				}
+				if got.HasMore || got.NextCursor != "" || got.PrevCursor != "" {
+					t.Errorf("expected a single page, got %+v", got)
+				}
+			},
+		},
+		{
+			name:      "PreviousPage",
+			accountID: account.ID,
+			query: url.Values{
+				"page_size": {"5"},
+				"cursor":    {pageCursor{CreatedAt: cursorTime, ID: 3, Direction: cursorPrev}.encode()},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountEntriesBefore(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					ListAccountEntriesAfter(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ListAccountEntriesAfterParams) ([]db.Entry, error) {
+						if !arg.CursorCreatedAt.Time.Equal(cursorTime) || arg.CursorID != 3 || arg.PageLimit != 6 {
+							t.Errorf("unexpected params %+v", arg)
+						}
+						// rows come back oldest first
+						return []db.Entry{entries[1], entries[0]}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got pageResponse[db.Entry]
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(got.Items) != 2 || got.Items[0].ID != entries[0].ID {
+					t.Errorf("expected entries newest first, got %+v", got.Items)
+				}
+				if got.NextCursor == "" || got.PrevCursor != "" {
+					t.Errorf("expected only a next cursor, got %+v", got)
+				}
+			},
+		},
+		{
+			name:      "InvalidCursor",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}, "cursor": {"not-a-cursor"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountEntriesBefore(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:      "WithFilters",
+			accountID: account.ID,
+			query: url.Values{
+				"page_size":  {"10"},
+				"start_time": {start.Format(time.RFC3339)},
+				"end_time":   {end.Format(time.RFC3339)},
+				"min_amount": {"10"},
+				"max_amount": {"500"},
+				"direction":  {"outgoing"},
+				"reference":  {"INV-2026-03"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountEntriesBefore(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ListAccountEntriesBeforeParams) ([]db.Entry, error) {
+						createdAt, id := pageCursor{}.position(true)
+						want := db.ListAccountEntriesBeforeParams{
+							AccountID:       account.ID,
+							CursorCreatedAt: createdAt,
+							CursorID:        id,
+							StartTime:       pgtype.Timestamptz{Time: start, Valid: true},
+							EndTime:         pgtype.Timestamptz{Time: end, Valid: true},
+							MinAmount:       pgtype.Int8{Int64: 10, Valid: true},
+							MaxAmount:       pgtype.Int8{Int64: 500, Valid: true},
+							Direction:       pgtype.Text{String: "outgoing", Valid: true},
+							Reference:       pgtype.Text{String: "INV-2026-03", Valid: true},
+							PageLimit:       11,
+						}
+						if !arg.StartTime.Time.Equal(want.StartTime.Time) || !arg.EndTime.Time.Equal(want.EndTime.Time) {
+							t.Errorf("expected time range %v to %v, got %v to %v", start, end, arg.StartTime.Time, arg.EndTime.Time)
+						}
+						arg.StartTime.Time, arg.EndTime.Time = want.StartTime.Time, want.EndTime.Time
+						if arg != want {
+							t.Errorf("expected params %+v, got %+v", want, arg)
+						}
+						return entries[:1], nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:      "InvalidDirection",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}, "direction": {"sideways"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountEntriesBefore(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:      "EndTimeBeforeStartTime",
+			accountID: account.ID,
+			query: url.Values{
+				"page_size":  {"5"},
+				"start_time": {end.Format(time.RFC3339)},
+				"end_time":   {start.Format(time.RFC3339)},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountEntriesBefore(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:      "MaxAmountBelowMinAmount",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}, "min_amount": {"100"}, "max_amount": {"10"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountEntriesBefore(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "other_user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountEntriesBefore(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:      "BankerReadsAnyAccount",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, "banker_user", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountEntriesBefore(gomock.Any(), gomock.Any()).Times(1).Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:      "AccountNotFound",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().ListAccountEntriesBefore(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountEntriesBefore(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, fmt.Errorf("internal error"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:      "InvalidAccountID",
+			accountID: 0,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			path := fmt.Sprintf("/accounts/%d/entries?%s", tc.accountID, tc.query.Encode())
+			request := httptest.NewRequest(http.MethodGet, path, nil)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
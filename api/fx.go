@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/fx"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type createFXQuoteRequest struct {
+	FromCurrency string `json:"from_currency" binding:"required,currency"`
+	ToCurrency   string `json:"to_currency" binding:"required,currency,nefield=FromCurrency"`
+	Amount       int64  `json:"amount" binding:"required,gt=0"`
+}
+
+type fxQuoteResponse struct {
+	ID           uuid.UUID `json:"id"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         float64   `json:"rate"`
+	SpreadBps    int32     `json:"spread_bps"`
+	FromAmount   int64     `json:"from_amount"`
+	ToAmount     int64     `json:"to_amount"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// parseFXQuoteResponse converts a db.FxQuote to a fxQuoteResponse.
+func parseFXQuoteResponse(quote db.FxQuote) fxQuoteResponse {
+	return fxQuoteResponse{
+		ID:           quote.ID,
+		FromCurrency: quote.FromCurrency,
+		ToCurrency:   quote.ToCurrency,
+		Rate:         quote.Rate,
+		SpreadBps:    quote.SpreadBps,
+		FromAmount:   quote.FromAmount,
+		ToAmount:     quote.ToAmount,
+		ExpiresAt:    quote.ExpiresAt.Time,
+	}
+}
+
+// createFXQuote locks the current rate for converting an amount for FX_QUOTE_DURATION.
+// The returned quote ID is passed to POST /transfers to move money between accounts in different currencies.
+func (server *Server) createFXQuote(ctx *gin.Context) {
+	var req createFXQuoteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	rate, err := server.fxProvider.Rate(ctx, req.FromCurrency, req.ToCurrency)
+	if errors.Is(err, fx.ErrRateNotFound) {
+		err = fmt.Errorf("no fx rate from %s to %s: %w", req.FromCurrency, req.ToCurrency, err)
+		ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeFXRateUnavailable, err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	toAmount, err := rate.Convert(req.Amount)
+	if errors.Is(err, fx.ErrAmountOutOfRange) {
+		ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAmountOutOfRange, err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if toAmount <= 0 {
+		err := fmt.Errorf("amount %d %s is too small to convert to %s", req.Amount, req.FromCurrency, req.ToCurrency)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	quoteID, err := uuid.NewRandom()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	quote, err := server.store.CreateFXQuote(ctx, db.CreateFXQuoteParams{
+		ID:           quoteID,
+		Username:     authPayload.Username,
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		Rate:         rate.Rate,
+		SpreadBps:    rate.SpreadBps,
+		FromAmount:   req.Amount,
+		ToAmount:     toAmount,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(server.config.FXQuoteDuration), Valid: true},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, parseFXQuoteResponse(quote))
+}
+
+// validQuote checks if the quote with given ID belongs to the user and converts from the given currency.
+func (server *Server) validQuote(ctx *gin.Context, quoteID uuid.UUID, username string, currency string) (db.FxQuote, bool) {
+	quote, err := server.store.GetFXQuote(ctx, quoteID)
+	if errors.Is(err, db.ErrRecordNotFound) || (err == nil && quote.Username != username) {
+		err := fmt.Errorf("fx quote %s not found", quoteID)
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return quote, false
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return quote, false
+	}
+
+	if quote.FromCurrency != currency {
+		err := fmt.Errorf("fx quote %s converts from %s, not %s", quoteID, quote.FromCurrency, currency)
+		ctx.JSON(http.StatusBadRequest, errorCodeResponse(errCodeFXQuoteMismatch, err))
+		return quote, false
+	}
+
+	return quote, true
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateFXQuoteAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	usdToEUR := db.FxRate{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.9, SpreadBps: 100}
+
+	testCases := []struct {
+		name          string
+		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{"from_currency": "USD", "to_currency": "EUR", "amount": 10000},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetFXRate(gomock.Any(), gomock.Eq(db.GetFXRateParams{FromCurrency: "USD", ToCurrency: "EUR"})).
+					Times(1).
+					Return(usdToEUR, nil)
+				store.EXPECT().
+					CreateFXQuote(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateFXQuoteParams) (db.FxQuote, error) {
+						if arg.Username != user.Username || arg.FromAmount != 10000 || arg.ToAmount != 8910 {
+							t.Errorf("unexpected quote params: %+v", arg)
+						}
+						if arg.Rate != usdToEUR.Rate || arg.SpreadBps != usdToEUR.SpreadBps {
+							t.Errorf("expected the provider's rate, got %+v", arg)
+						}
+						if until := time.Until(arg.ExpiresAt.Time); until <= 0 || until > time.Minute {
+							t.Errorf("expected the quote to expire within a minute, got %v", until)
+						}
+						return db.FxQuote{
+							ID:           arg.ID,
+							Username:     arg.Username,
+							FromCurrency: arg.FromCurrency,
+							ToCurrency:   arg.ToCurrency,
+							Rate:         arg.Rate,
+							SpreadBps:    arg.SpreadBps,
+							FromAmount:   arg.FromAmount,
+							ToAmount:     arg.ToAmount,
+							ExpiresAt:    arg.ExpiresAt,
+							CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var quote fxQuoteResponse
+				if err := json.NewDecoder(recorder.Body).Decode(&quote); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if quote.ToAmount != 8910 || quote.FromCurrency != "USD" || quote.ToCurrency != "EUR" {
+					t.Errorf("unexpected quote %+v", quote)
+				}
+			},
+		},
+		{
+			name: "RateNotFound",
+			body: map[string]any{"from_currency": "USD", "to_currency": "CAD", "amount": 10000},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetFXRate(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(db.FxRate{}, db.ErrRecordNotFound)
+				store.EXPECT().CreateFXQuote(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeFXRateUnavailable)
+			},
+		},
+		{
+			name: "AmountTooSmall",
+			body: map[string]any{"from_currency": "USD", "to_currency": "EUR", "amount": 1},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetFXRate(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(usdToEUR, nil)
+				store.EXPECT().CreateFXQuote(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			// the converted amount would not fit in an int64
+			name: "AmountOutOfRange",
+			body: map[string]any{"from_currency": "USD", "to_currency": "EUR", "amount": int64(math.MaxInt64)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetFXRate(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.FxRate{FromCurrency: "USD", ToCurrency: "EUR", Rate: 2}, nil)
+				store.EXPECT().CreateFXQuote(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeAmountOutOfRange)
+			},
+		},
+		{
+			name: "SameCurrency",
+			body: map[string]any{"from_currency": "USD", "to_currency": "USD", "amount": 10000},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFXRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: map[string]any{"from_currency": "USD", "to_currency": "EUR", "amount": 10000},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFXRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "InternalError",
+			body: map[string]any{"from_currency": "USD", "to_currency": "EUR", "amount": 10000},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetFXRate(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(usdToEUR, nil)
+				store.EXPECT().
+					CreateFXQuote(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.FxQuote{}, fmt.Errorf("internal error"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, _ := json.Marshal(tc.body)
+			request := httptest.NewRequest(http.MethodPost, "/fx/quotes", bytes.NewReader(body))
+			request.Header.Set("Content-Type", "application/json")
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// checkErrorCode checks the machine-readable code of an error response
+func checkErrorCode(t *testing.T, recorder *httptest.ResponseRecorder, want string) {
+	t.Helper()
+	var resp struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if resp.Code != want {
+		t.Errorf("expected code %s, got %s", want, resp.Code)
+	}
+}
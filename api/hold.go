@@ -0,0 +1,183 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// createHoldRequest authorizes a pending transfer of Amount in Currency between two accounts
+// in that currency. The amount is held on the source account until the hold is captured,
+// voided or expires after HOLD_DURATION.
+type createHoldRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	// MFACode is the TOTP code holds above the MFA_STEP_UP_AMOUNT of their currency need,
+	// since the recipient can capture them without the payer
+	MFACode string `json:"mfa_code,omitempty" binding:"omitempty,numeric,len=6"`
+}
+
+// captureHoldRequest settles Amount of a hold. Without an amount the whole hold is captured;
+// whatever is not captured is released.
+type captureHoldRequest struct {
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+// to get hold by id from the URI
+type getHoldRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) createHold(ctx *gin.Context) {
+	var req createHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if fromAccount.Owner != authPayload.Username {
+		err := fmt.Errorf("from account %d does not belong to the authenticated user", req.FromAccountID)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !server.stepUpVerified(ctx, authPayload.Username, map[string]int64{req.Currency: req.Amount}, req.MFACode) {
+		return
+	}
+
+	if _, valid := server.validAccount(ctx, req.ToAccountID, req.Currency); !valid {
+		return
+	}
+
+	result, err := server.store.AuthorizeTx(ctx, db.AuthorizeTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		ExpiresAt:     time.Now().Add(server.config.HoldDuration),
+	})
+	if err != nil {
+		transferErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (server *Server) getHold(ctx *gin.Context) {
+	var req getHoldRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hold, valid := server.authorizedHold(ctx, req.ID, false)
+	if !valid {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, hold)
+}
+
+// captureHold settles a pending hold. Only the owner of the destination account may capture it.
+func (server *Server) captureHold(ctx *gin.Context) {
+	var uri getHoldRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// the body is optional
+	var req captureHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, valid := server.authorizedHold(ctx, uri.ID, true); !valid {
+		return
+	}
+
+	result, err := server.store.CaptureTx(ctx, db.CaptureTxParams{
+		HoldID: uri.ID,
+		Amount: req.Amount,
+	})
+	if err != nil {
+		transferErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// voidHold releases a pending hold without moving any money. Only the owner of the destination
+// account may void it; the payer has to wait for the hold to expire.
+func (server *Server) voidHold(ctx *gin.Context) {
+	var req getHoldRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, valid := server.authorizedHold(ctx, req.ID, true); !valid {
+		return
+	}
+
+	result, err := server.store.VoidHoldTx(ctx, req.ID)
+	if err != nil {
+		transferErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// authorizedHold loads the hold with given ID if the authenticated user owns one of its accounts,
+// or the destination account when settling it. Bankers and admins may read any hold.
+func (server *Server) authorizedHold(ctx *gin.Context, id int64, settle bool) (db.Hold, bool) {
+	hold, err := server.store.GetHold(ctx, id)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return hold, false
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return hold, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !settle && hasScope(authPayload, scopeAccountsReadAny) {
+		return hold, true
+	}
+
+	accountIDs := []int64{hold.ToAccountID}
+	if !settle {
+		accountIDs = append(accountIDs, hold.FromAccountID)
+	}
+
+	owner, err := server.ownsAnyAccount(ctx, authPayload.Username, accountIDs...)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return hold, false
+	}
+	if !owner {
+		err := errors.New("hold does not belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return hold, false
+	}
+
+	return hold, true
+}
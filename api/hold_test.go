@@ -0,0 +1,456 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateHoldAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccountForUser(user1.Username)
+	account2 := randomAccountForUser(user2.Username)
+	account1.ID, account2.ID = 1, 2
+	account1.Currency, account2.Currency = util.USD, util.USD
+
+	testCases := []struct {
+		name          string
+		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					AuthorizeTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.AuthorizeTxParams) (db.HoldTxResult, error) {
+						if arg.FromAccountID != account1.ID || arg.ToAccountID != account2.ID || arg.Amount != 100 {
+							t.Errorf("unexpected params %+v", arg)
+						}
+						if expiresIn := time.Until(arg.ExpiresAt); expiresIn <= 0 || expiresIn > time.Hour {
+							t.Errorf("expected the hold to expire within the hold duration, got %v", expiresIn)
+						}
+
+						held := account1
+						held.HeldAmount = 100
+						held.AvailableBalance = account1.Balance - 100
+						return db.HoldTxResult{
+							Hold:        db.Hold{ID: 5, FromAccountID: arg.FromAccountID, ToAccountID: arg.ToAccountID, Amount: arg.Amount, Status: db.HoldPending},
+							FromAccount: held,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got db.HoldTxResult
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if got.Hold.Status != db.HoldPending || got.FromAccount.Balance != account1.Balance || got.FromAccount.AvailableBalance != account1.Balance-100 {
+					t.Errorf("unexpected result %+v", got)
+				}
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				err := &db.InsufficientFundsError{AccountID: account1.ID, Available: 50, Requested: 100}
+				store.EXPECT().AuthorizeTx(gomock.Any(), gomock.Any()).Times(1).Return(db.HoldTxResult{}, err)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeInsufficientFunds)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().AuthorizeTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "SameAccount",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account1.ID,
+				"amount":          100,
+				"currency":        util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AuthorizeTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			if err != nil {
+				t.Fatalf("failed to marshal body: %v", err)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/holds", bytes.NewReader(data))
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSettleHoldAPI(t *testing.T) {
+	payer, _ := randomUser(t)
+	payee, _ := randomUser(t)
+	account1 := randomAccountForUser(payer.Username)
+	account2 := randomAccountForUser(payee.Username)
+	account1.ID, account2.ID = 1, 2
+
+	hold := db.Hold{
+		ID:            5,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		Status:        db.HoldPending,
+		ExpiresAt:     pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	captured := hold
+	captured.Status = db.HoldCaptured
+	captured.CapturedAmount = 60
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "GetByPayer",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/holds/%d", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, payer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got db.Hold
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if got.ID != hold.ID || got.Amount != hold.Amount || got.Status != hold.Status {
+					t.Errorf("expected hold %+v, got %+v", hold, got)
+				}
+			},
+		},
+		{
+			name:   "GetByBanker",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/holds/%d", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, "banker_user", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "GetNotFound",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/holds/%d", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, payer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(db.Hold{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "PartialCapture",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body:   map[string]any{"amount": 60},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, payee.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					CaptureTx(gomock.Any(), gomock.Eq(db.CaptureTxParams{HoldID: hold.ID, Amount: 60})).
+					Times(1).
+					Return(db.CaptureTxResult{
+						TransferTxResult: db.TransferTxResult{Transfer: db.Transfer{ID: 9, Amount: 60, HoldID: pgtype.Int8{Int64: hold.ID, Valid: true}}},
+						Hold:             captured,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got db.CaptureTxResult
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if got.Hold.Status != db.HoldCaptured || got.Transfer.Amount != 60 || got.Transfer.HoldID.Int64 != hold.ID {
+					t.Errorf("unexpected result %+v", got)
+				}
+			},
+		},
+		{
+			name:   "FullCaptureWithoutBody",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/holds/%d/capture", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, payee.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					CaptureTx(gomock.Any(), gomock.Eq(db.CaptureTxParams{HoldID: hold.ID})).
+					Times(1).
+					Return(db.CaptureTxResult{Hold: captured}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "PayerCannotCapture",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/holds/%d/capture", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, payer.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().CaptureTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "CaptureTooMuch",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body:   map[string]any{"amount": 150},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, payee.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				err := fmt.Errorf("%w: requested 150, held 100", db.ErrInvalidCaptureAmount)
+				store.EXPECT().CaptureTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CaptureTxResult{}, err)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeInvalidCaptureAmount)
+			},
+		},
+		{
+			name:   "CaptureExpired",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/holds/%d/capture", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, payee.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().CaptureTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CaptureTxResult{}, db.ErrHoldExpired)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeHoldExpired)
+			},
+		},
+		{
+			name:   "Void",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/holds/%d/void", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, payee.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				voided := hold
+				voided.Status = db.HoldVoided
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(db.HoldTxResult{Hold: voided, FromAccount: account1}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "VoidClosed",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/holds/%d/void", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, payee.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(captured, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				err := fmt.Errorf("%w: hold %d is captured", db.ErrHoldClosed, hold.ID)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(db.HoldTxResult{}, err)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeHoldClosed)
+			},
+		},
+		{
+			name:   "VoidWithoutTransferScope",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/holds/%d/void", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, "banker_user", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusForbidden {
+					t.Errorf("expected status code 403, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				if err := json.NewEncoder(&body).Encode(tc.body); err != nil {
+					t.Fatalf("failed to marshal body: %v", err)
+				}
+			}
+
+			request := httptest.NewRequest(tc.method, tc.path, &body)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+	"go.uber.org/mock/gomock"
+)
+
+func writeTestSigningKey(t *testing.T) string {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("cannot marshal key: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "token_private.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("cannot write key: %v", err)
+	}
+
+	return path
+}
+
+func TestGetJWKSAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		tokenType     string
+		privateKey    func(t *testing.T) string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "AsymmetricKey",
+			tokenType:  util.PasetoPublicTokenType,
+			privateKey: writeTestSigningKey,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var set token.JWKSet
+				if err := json.NewDecoder(recorder.Body).Decode(&set); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(set.Keys) != 1 {
+					t.Fatalf("expected 1 key, got %d", len(set.Keys))
+				}
+				if set.Keys[0].KeyType != "OKP" || set.Keys[0].KeyID == "" {
+					t.Errorf("unexpected key %+v", set.Keys[0])
+				}
+			},
+		},
+		{
+			name:      "SymmetricKey",
+			tokenType: util.PasetoTokenType,
+			privateKey: func(t *testing.T) string {
+				return ""
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServerWithConfig(t, util.Config{
+				TokenType:           tc.tokenType,
+				TokenSymmetricKey:   gofakeit.LetterN(32),
+				TokenSymmetricKeyID: "primary",
+				TokenPrivateKeyPath: tc.privateKey(t),
+				MailOutbox:          true,
+			})
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func newTestServerWithConfig(t *testing.T, config util.Config) *Server {
+	ctrl := gomock.NewController(t)
+	server, err := NewServer(config, mockdb.NewMockStore(ctrl))
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+	return server
+}
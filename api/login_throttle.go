@@ -0,0 +1,213 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// errIncorrectCredentials is the only error a failed login reports, so it cannot tell whether a username exists
+var errIncorrectCredentials = errors.New("incorrect username or password")
+
+// dummyPasswordHash is checked against the password of unknown usernames,
+// so logging in as them takes as long as logging in with a wrong password
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hashedPassword, _ := util.HashPassword("not the password of any user")
+	return hashedPassword
+})
+
+// loginThrottle counts failed logins per client IP, so one client cannot guess the passwords of many users.
+// Like revocationList it is kept in process: the lockout of each user is what is shared through Postgres.
+// It also counts password reset requests by email and by client IP, every one of them as a failure.
+type loginThrottle struct {
+	mu          sync.Mutex
+	maxAttempts int
+	window      time.Duration
+	failures    map[string]*loginFailures // client IP -> failures in its current window
+	prunedAt    time.Time
+}
+
+type loginFailures struct {
+	count       int
+	windowStart time.Time
+}
+
+func newLoginThrottle(maxAttempts int, window time.Duration) *loginThrottle {
+	return &loginThrottle{
+		maxAttempts: maxAttempts,
+		window:      window,
+		failures:    make(map[string]*loginFailures),
+	}
+}
+
+// blockedFor returns how long the client IP must wait before it may try to log in again, or 0.
+func (throttle *loginThrottle) blockedFor(ip string, now time.Time) time.Duration {
+	throttle.mu.Lock()
+	defer throttle.mu.Unlock()
+
+	failures, ok := throttle.failures[ip]
+	if throttle.maxAttempts <= 0 || !ok || failures.count < throttle.maxAttempts {
+		return 0
+	}
+	return max(failures.windowStart.Add(throttle.window).Sub(now), 0)
+}
+
+// recordFailure counts a failed login from the client IP. Windows start at the first failure
+// and last LOGIN_LOCKOUT_DURATION, so a blocked IP is let in again when its window ends.
+func (throttle *loginThrottle) recordFailure(ip string, now time.Time) {
+	throttle.mu.Lock()
+	defer throttle.mu.Unlock()
+
+	failures, ok := throttle.failures[ip]
+	if ok && now.Before(failures.windowStart.Add(throttle.window)) {
+		failures.count++
+		return
+	}
+
+	// drop ended windows at most once per window, so the map does not grow with every IP ever seen
+	if now.Sub(throttle.prunedAt) >= throttle.window {
+		for ip, failures := range throttle.failures {
+			if !now.Before(failures.windowStart.Add(throttle.window)) {
+				delete(throttle.failures, ip)
+			}
+		}
+		throttle.prunedAt = now
+	}
+	throttle.failures[ip] = &loginFailures{count: 1, windowStart: now}
+}
+
+// loginDelay returns how long a user must wait to log in after their failures-th failed login in a row.
+// The wait starts at LOGIN_DELAY and doubles with every failure, and once LOGIN_MAX_ATTEMPTS
+// is reached the user is locked out for LOGIN_LOCKOUT_DURATION.
+func loginDelay(config util.Config, failures int32) time.Duration {
+	if config.LoginMaxAttempts > 0 && failures >= config.LoginMaxAttempts {
+		return config.LoginLockoutDuration
+	}
+
+	delay := config.LoginDelay
+	for i := int32(1); i < failures && delay < config.LoginLockoutDuration; i++ {
+		delay *= 2
+	}
+	return min(delay, config.LoginLockoutDuration)
+}
+
+// loginAllowed responds with 429 if the client IP must wait before trying to log in again
+func (server *Server) loginAllowed(ctx *gin.Context, now time.Time) bool {
+	wait := server.loginThrottle.blockedFor(ctx.ClientIP(), now)
+	if wait <= 0 {
+		return true
+	}
+
+	loginLockedResponse(ctx, wait)
+	return false
+}
+
+// loginLockedResponse responds with 429 and how long to wait in the Retry-After header
+func loginLockedResponse(ctx *gin.Context, wait time.Duration) {
+	tooManyRequestsResponse(ctx, wait, errCodeLoginLocked, errors.New("too many failed logins, try again later"))
+}
+
+// tooManyRequestsResponse responds with 429, the error code and how long to wait in the Retry-After header
+func tooManyRequestsResponse(ctx *gin.Context, wait time.Duration, code string, err error) {
+	ctx.Header("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+	ctx.JSON(http.StatusTooManyRequests, errorCodeResponse(code, err))
+}
+
+// claimLoginAttempt counts an attempt of the user to log in as failed before its password or second factor
+// is checked, and locks them out for the delay of that failure. The claim is a single conditional update,
+// so of concurrent attempts only one gets past the lockout, and the others wait for it: it returns false
+// when the user is locked out, or when an attempt that claimed first did not turn out right within
+// loginClaimWait. An attempt that turns out right is taken back by startSession, or by
+// ReleaseLoginAttempt when a second factor is still needed or a step-up code was right.
+func (server *Server) claimLoginAttempt(ctx *gin.Context, user db.User, now time.Time) (db.User, bool, error) {
+	claimed, err := server.store.ClaimLoginAttempt(ctx, server.loginClaim(user, now))
+	if errors.Is(err, db.ErrRecordNotFound) {
+		if user.LockedUntil.Time.After(now) && !server.claimInFlight(user, now) {
+			return user, false, nil
+		}
+		return server.awaitLoginClaim(ctx, user)
+	} else if err != nil {
+		return user, false, err
+	}
+
+	return claimed, true, nil
+}
+
+// loginClaim returns the claim of the next attempt of the user as read, which locks them out for the delay of its failure
+func (server *Server) loginClaim(user db.User, now time.Time) db.ClaimLoginAttemptParams {
+	return db.ClaimLoginAttemptParams{
+		Username:            user.Username,
+		FailedLoginAttempts: user.FailedLoginAttempts,
+		LockedUntil:         pgtype.Timestamptz{Time: now.Add(loginDelay(server.config, user.FailedLoginAttempts+1)), Valid: true},
+	}
+}
+
+// loginClaimWait is how long an attempt that lost its claim to a concurrent one waits for it to turn out right,
+// well beyond how long checking a password takes, and loginClaimPollInterval how often it looks
+const (
+	loginClaimWait         = time.Second
+	loginClaimPollInterval = 50 * time.Millisecond
+)
+
+// claimInFlight reports whether the user was locked out by a claim made less than loginClaimWait ago,
+// whose attempt may still be checking and unlock them again. The claim was made the delay
+// of its failure before the lockout ends.
+func (server *Server) claimInFlight(user db.User, now time.Time) bool {
+	claimedAt := user.LockedUntil.Time.Add(-loginDelay(server.config, user.FailedLoginAttempts))
+	return !claimedAt.After(now) && now.Sub(claimedAt) < loginClaimWait
+}
+
+// awaitLoginClaim claims an attempt of the user once the concurrent attempt that claimed first has unlocked them,
+// so two logins with the right password at the same time both succeed. It returns false, and the user
+// as last read, once the user is locked out by a claim that is no longer in flight or loginClaimWait has passed.
+func (server *Server) awaitLoginClaim(ctx *gin.Context, user db.User) (db.User, bool, error) {
+	deadline := time.Now().Add(loginClaimWait)
+	ticker := time.NewTicker(loginClaimPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return user, false, ctx.Request.Context().Err()
+		case <-ticker.C:
+		}
+
+		latest, err := server.store.GetUser(ctx, user.Username)
+		if err != nil {
+			return user, false, err
+		}
+		user = latest
+
+		now := time.Now()
+		if !user.LockedUntil.Time.After(now) {
+			claimed, err := server.store.ClaimLoginAttempt(ctx, server.loginClaim(user, now))
+			if err == nil {
+				return claimed, true, nil
+			} else if !errors.Is(err, db.ErrRecordNotFound) {
+				return user, false, err
+			}
+		} else if !server.claimInFlight(user, now) {
+			return user, false, nil
+		}
+
+		if !now.Before(deadline) {
+			return user, false, nil
+		}
+	}
+}
+
+// rejectLogin answers a login whose password was not checked, for an unknown or locked username,
+// like a wrong password and after as much work, so those usernames cannot be told apart from the others
+func (server *Server) rejectLogin(ctx *gin.Context, password string, now time.Time) {
+	util.CheckPassword(password, dummyPasswordHash())
+	server.loginThrottle.recordFailure(ctx.ClientIP(), now)
+	ctx.JSON(http.StatusUnauthorized, errorCodeResponse(errCodeIncorrectCredentials, errIncorrectCredentials))
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLoginDelay(t *testing.T) {
+	config := util.Config{
+		LoginDelay:           time.Second,
+		LoginMaxAttempts:     5,
+		LoginLockoutDuration: 15 * time.Minute,
+	}
+
+	testCases := []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{failures: 1, want: time.Second},
+		{failures: 2, want: 2 * time.Second},
+		{failures: 4, want: 8 * time.Second},
+		{failures: 5, want: 15 * time.Minute},
+		{failures: 12, want: 15 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		if got := loginDelay(config, tc.failures); got != tc.want {
+			t.Errorf("loginDelay(%d) = %v, want %v", tc.failures, got, tc.want)
+		}
+	}
+
+	// the delay never exceeds the lockout, even without a lockout threshold
+	config.LoginMaxAttempts = 0
+	if got := loginDelay(config, 40); got != config.LoginLockoutDuration {
+		t.Errorf("expected the delay to stop at %v, got %v", config.LoginLockoutDuration, got)
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	throttle := newLoginThrottle(2, time.Minute)
+	now := time.Now()
+
+	throttle.recordFailure("10.0.0.1", now)
+	if wait := throttle.blockedFor("10.0.0.1", now); wait != 0 {
+		t.Errorf("expected no block after 1 failure, got %v", wait)
+	}
+
+	throttle.recordFailure("10.0.0.1", now.Add(10*time.Second))
+	if wait := throttle.blockedFor("10.0.0.1", now.Add(20*time.Second)); wait != 40*time.Second {
+		t.Errorf("expected a block until the window ends, got %v", wait)
+	}
+	if wait := throttle.blockedFor("10.0.0.2", now); wait != 0 {
+		t.Errorf("expected other IPs not to be blocked, got %v", wait)
+	}
+
+	// a new window starts once the old one ended
+	if wait := throttle.blockedFor("10.0.0.1", now.Add(time.Minute)); wait != 0 {
+		t.Errorf("expected the block to end with the window, got %v", wait)
+	}
+	throttle.recordFailure("10.0.0.1", now.Add(time.Minute))
+	if wait := throttle.blockedFor("10.0.0.1", now.Add(time.Minute)); wait != 0 {
+		t.Errorf("expected no block after 1 failure in a new window, got %v", wait)
+	}
+}
+
+func TestLoginBlocksClientIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// two unknown usernames from the same client, then a third try is not even looked up
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return(db.User{}, db.ErrRecordNotFound)
+
+	server := newTestServer(t, store)
+	server.loginThrottle = newLoginThrottle(2, time.Minute)
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		recorder := httptest.NewRecorder()
+		body, _ := json.Marshal(map[string]any{
+			"username": gofakeit.LetterN(10),
+			"password": gofakeit.Password(true, true, true, false, false, 16),
+		})
+		request := httptest.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+
+		server.router.ServeHTTP(recorder, request)
+		if recorder.Code != want {
+			t.Errorf("attempt %d: expected status code %d, got %d", i+1, want, recorder.Code)
+		}
+	}
+}
+
+func TestConcurrentLogins(t *testing.T) {
+	user, password := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the store keeps the lockout of the user like the users table does
+	store := mockdb.NewMockStore(ctrl)
+	var mu sync.Mutex
+	stored := user
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().DoAndReturn(
+		func(_ context.Context, _ string) (db.User, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return stored, nil
+		},
+	)
+	store.EXPECT().ClaimLoginAttempt(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(_ context.Context, arg db.ClaimLoginAttemptParams) (db.User, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if arg.FailedLoginAttempts != stored.FailedLoginAttempts || stored.LockedUntil.Time.After(time.Now()) {
+				return db.User{}, db.ErrRecordNotFound
+			}
+			stored.FailedLoginAttempts++
+			stored.LockedUntil = arg.LockedUntil
+			return stored, nil
+		},
+	)
+	store.EXPECT().UnlockUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().DoAndReturn(
+		func(_ context.Context, _ string) (db.User, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			stored.FailedLoginAttempts = 0
+			stored.LockedUntil = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			return stored, nil
+		},
+	)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(2).Return(db.Session{}, nil)
+
+	server := newTestServer(t, store)
+
+	// both logins have the right password, so whichever loses the claim waits for the other instead of failing
+	data, _ := json.Marshal(map[string]any{"username": user.Username, "password": password})
+	recorders := make([]*httptest.ResponseRecorder, 2)
+	var wg sync.WaitGroup
+	for i := range recorders {
+		recorders[i] = httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+		request.Header.Set("Content-Type", "application/json")
+		wg.Go(func() { server.router.ServeHTTP(recorders[i], request) })
+	}
+	wg.Wait()
+
+	for i, recorder := range recorders {
+		if recorder.Code != http.StatusOK {
+			t.Errorf("login %d: expected status code 200, got %d", i+1, recorder.Code)
+		}
+	}
+	if stored.FailedLoginAttempts != 0 {
+		t.Errorf("expected no failed logins to be counted, got %d", stored.FailedLoginAttempts)
+	}
+}
+
+// expectClaimLoginAttempt stubs the claim of a login attempt of user, which counts it as failed until it succeeds
+func expectClaimLoginAttempt(store *mockdb.MockStore, user db.User) {
+	claimed := user
+	claimed.FailedLoginAttempts++
+	store.EXPECT().ClaimLoginAttempt(gomock.Any(), gomock.Any()).Times(1).Return(claimed, nil)
+}
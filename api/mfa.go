@@ -0,0 +1,330 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// mfaIssuer names the bank in authenticator apps
+	mfaIssuer = "SimpleBank"
+	// recoveryCodeCount is the number of recovery codes issued when MFA is enabled
+	recoveryCodeCount = 10
+)
+
+type enrollTOTPResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// enrollTOTP starts TOTP enrollment with a new secret. MFA is only enabled once a code
+// from the secret is confirmed, so starting over replaces a pending secret.
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	secret, err := util.RandomTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.SetUserTOTPSecret(ctx, db.SetUserTOTPSecretParams{
+		Username:   authPayload.Username,
+		TotpSecret: pgtype.Text{String: secret, Valid: true},
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		err := errors.New("mfa is already enabled")
+		ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeMFAAlreadyEnabled, err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTOTPResponse{
+		Secret:     secret,
+		OTPAuthURI: util.TOTPURI(mfaIssuer, authPayload.Username, secret),
+	})
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+type confirmTOTPResponse struct {
+	// RecoveryCodes each replace a TOTP code once. They are only ever shown here.
+	RecoveryCodes []string     `json:"recovery_codes"`
+	User          userResponse `json:"user"`
+}
+
+// confirmTOTP enables MFA once the user proves their authenticator app has the pending secret
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.MfaEnabled {
+		err := errors.New("mfa is already enabled")
+		ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeMFAAlreadyEnabled, err))
+		return
+	}
+	if !user.TotpSecret.Valid {
+		err := errors.New("no totp enrollment is pending")
+		ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeMFANotPending, err))
+		return
+	}
+
+	step, valid := util.ValidateTOTP(user.TotpSecret.String, req.Code, time.Now())
+	if !valid {
+		err := errors.New("invalid totp code")
+		ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeInvalidMFACode, err))
+		return
+	}
+
+	recoveryCodes := make([]string, recoveryCodeCount)
+	recoveryCodeHashes := make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		recoveryCodes[i], err = util.RandomOneTimeCode()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		recoveryCodeHashes[i] = util.HashOneTimeCode(recoveryCodes[i])
+	}
+
+	user, err = server.store.EnableMFATx(ctx, db.EnableMFATxParams{
+		Username:           user.Username,
+		TOTPStep:           step,
+		RecoveryCodeHashes: recoveryCodeHashes,
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		// a concurrent request enabled MFA first
+		err := errors.New("no totp enrollment is pending")
+		ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeMFANotPending, err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, confirmTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+		User:          parseUserResponse(user),
+	})
+}
+
+type mfaChallengeResponse struct {
+	MFARequired       bool      `json:"mfa_required"`
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+// mfaChallenge answers a correct password of a user with MFA with a challenge token for loginMFA
+func (server *Server) mfaChallenge(ctx *gin.Context, user db.User) {
+	mfaToken, payload, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.MFAChallengeToken, server.config.MFAChallengeDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, mfaChallengeResponse{
+		MFARequired:       true,
+		MFAToken:          mfaToken,
+		MFATokenExpiresAt: payload.ExpiredAt,
+	})
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// Code is a TOTP code or a recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+// loginMFA is the second step of logging in with MFA: it exchanges the challenge token from
+// loginUser and a TOTP or recovery code for the session and tokens loginUser gives other users
+func (server *Server) loginMFA(ctx *gin.Context) {
+	var req loginMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(req.MFAToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if payload.Type != token.MFAChallengeToken || server.revocations.isRevoked(payload) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrWrongTokenType))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// wrong codes count towards the lockout like wrong passwords, so codes cannot be guessed either
+	now := time.Now()
+	if !server.loginAllowed(ctx, now) {
+		return
+	}
+
+	// the challenge already proves the password, so being locked out reveals nothing here
+	user, claimed, err := server.claimLoginAttempt(ctx, user, now)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !claimed {
+		loginLockedResponse(ctx, user.LockedUntil.Time.Sub(now))
+		return
+	}
+
+	valid, err := server.verifySecondFactor(ctx, user, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		server.loginThrottle.recordFailure(ctx.ClientIP(), now)
+		err := errors.New("invalid mfa code")
+		ctx.JSON(http.StatusUnauthorized, errorCodeResponse(errCodeInvalidMFACode, err))
+		return
+	}
+
+	server.startSession(ctx, user)
+}
+
+// verifySecondFactor checks a TOTP code, or else a recovery code, of a user with MFA and uses it up
+func (server *Server) verifySecondFactor(ctx *gin.Context, user db.User, code string) (bool, error) {
+	if !user.MfaEnabled {
+		return false, nil
+	}
+	if len(code) == util.TOTPDigits {
+		return server.verifyTOTP(ctx, user, code)
+	}
+
+	_, err := server.store.UseMFARecoveryCode(ctx, db.UseMFARecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: util.HashOneTimeCode(code),
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// verifyTOTP checks a TOTP code of a user with MFA. A code is only accepted once,
+// even if it is still current, so an intercepted code cannot be replayed.
+func (server *Server) verifyTOTP(ctx *gin.Context, user db.User, code string) (bool, error) {
+	if !user.MfaEnabled {
+		return false, nil
+	}
+
+	step, valid := util.ValidateTOTP(user.TotpSecret.String, code, time.Now())
+	if !valid {
+		return false, nil
+	}
+
+	_, err := server.store.UseUserTOTPStep(ctx, db.UseUserTOTPStepParams{
+		Username:     user.Username,
+		TotpLastStep: step,
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// stepUpVerified checks the TOTP code of a request that commits money, given the amounts it commits by
+// currency, if any of them is above the MFA_STEP_UP_AMOUNT of its currency, and otherwise lets it through.
+// Every path that moves money (transfers, batches, holds and scheduled transfers) goes through it, and
+// users without MFA cannot commit such amounts at all.
+func (server *Server) stepUpVerified(ctx *gin.Context, username string, amounts map[string]int64, code string) bool {
+	var required error
+	for currency, amount := range amounts {
+		if threshold, ok := server.config.MFAStepUpAmounts[currency]; ok && amount > threshold {
+			required = fmt.Errorf("moving more than %d %s requires a totp code", threshold, currency)
+			break
+		}
+	}
+	if required == nil {
+		return true
+	}
+
+	user, err := server.store.GetUser(ctx, username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !user.MfaEnabled {
+		err := fmt.Errorf("%w, enable mfa first", required)
+		ctx.JSON(http.StatusForbidden, errorCodeResponse(errCodeMFARequired, err))
+		return false
+	}
+	if code == "" {
+		ctx.JSON(http.StatusForbidden, errorCodeResponse(errCodeMFARequired, required))
+		return false
+	}
+
+	// wrong codes count towards the same lockout as logins, so a stolen session cannot guess codes either
+	now := time.Now()
+	if !server.loginAllowed(ctx, now) {
+		return false
+	}
+
+	user, claimed, err := server.claimLoginAttempt(ctx, user, now)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if !claimed {
+		loginLockedResponse(ctx, user.LockedUntil.Time.Sub(now))
+		return false
+	}
+
+	valid, err := server.verifyTOTP(ctx, user, code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if !valid {
+		server.loginThrottle.recordFailure(ctx.ClientIP(), now)
+		err := errors.New("invalid totp code")
+		ctx.JSON(http.StatusForbidden, errorCodeResponse(errCodeInvalidMFACode, err))
+		return false
+	}
+
+	if _, err := server.store.ReleaseLoginAttempt(ctx, user.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	return true
+}
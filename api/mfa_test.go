@@ -0,0 +1,909 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+// randomMFAUser returns a random user with TOTP enabled
+func randomMFAUser(t *testing.T) (db.User, string) {
+	user, password := randomUser(t)
+	secret, err := util.RandomTOTPSecret()
+	if err != nil {
+		t.Fatal("Cannot create totp secret:", err)
+	}
+	user.TotpSecret = pgtype.Text{String: secret, Valid: true}
+	user.MfaEnabled = true
+	return user, password
+}
+
+// currentTOTPCode returns the TOTP code of a user for the current time step
+func currentTOTPCode(t *testing.T, user db.User) string {
+	t.Helper()
+	code, err := util.TOTPCode(user.TotpSecret.String, util.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatal("Cannot create totp code:", err)
+	}
+	return code
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.SetUserTOTPSecretParams) (db.User, error) {
+						if arg.Username != user.Username {
+							t.Errorf("expected username %s, got %s", user.Username, arg.Username)
+						}
+						enrolling := user
+						enrolling.TotpSecret = arg.TotpSecret
+						return enrolling, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var resp enrollTOTPResponse
+				if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(resp.Secret) != 32 {
+					t.Errorf("expected a 32 character secret, got %q", resp.Secret)
+				}
+				if !strings.HasPrefix(resp.OTPAuthURI, "otpauth://totp/") || !strings.Contains(resp.OTPAuthURI, "secret="+resp.Secret) {
+					t.Errorf("expected an otpauth uri with the secret, got %s", resp.OTPAuthURI)
+				}
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeMFAAlreadyEnabled)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetUserTOTPSecret(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(http.MethodPost, "/users/mfa/totp", nil)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	enrolled, _ := randomMFAUser(t)
+	pending := enrolled
+	pending.MfaEnabled = false
+	plain, _ := randomUser(t)
+	plain.Username = enrolled.Username
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: pending,
+			code: func(t *testing.T) string { return currentTOTPCode(t, pending) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					EnableMFATx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.EnableMFATxParams) (db.User, error) {
+						if arg.Username != pending.Username {
+							t.Errorf("expected username %s, got %s", pending.Username, arg.Username)
+						}
+						if arg.TOTPStep != util.TOTPStep(time.Now()) {
+							t.Errorf("expected the current step to be used up, got %d", arg.TOTPStep)
+						}
+						return enrolled, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var resp confirmTOTPResponse
+				if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(resp.RecoveryCodes) != recoveryCodeCount {
+					t.Errorf("expected %d recovery codes, got %d", recoveryCodeCount, len(resp.RecoveryCodes))
+				}
+				if !resp.User.MFAEnabled {
+					t.Error("expected mfa to be enabled")
+				}
+			},
+		},
+		{
+			name: "InvalidCode",
+			user: pending,
+			code: func(t *testing.T) string {
+				code := currentTOTPCode(t, pending)
+				if code == "000000" {
+					return "000001"
+				}
+				return "000000"
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().EnableMFATx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeInvalidMFACode)
+			},
+		},
+		{
+			name: "NotPending",
+			user: plain,
+			code: func(t *testing.T) string { return "123456" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().EnableMFATx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeMFANotPending)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			user: enrolled,
+			code: func(t *testing.T) string { return currentTOTPCode(t, enrolled) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().EnableMFATx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeMFAAlreadyEnabled)
+			},
+		},
+		{
+			// another request enabled MFA between reading the user and enabling it
+			name: "ConcurrentlyEnabled",
+			user: pending,
+			code: func(t *testing.T) string { return currentTOTPCode(t, pending) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					EnableMFATx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeMFANotPending)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).Times(1).Return(tc.user, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, _ := json.Marshal(map[string]any{"code": tc.code(t)})
+			request := httptest.NewRequest(http.MethodPost, "/users/mfa/totp/confirm", bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserMFAChallenge(t *testing.T) {
+	user, password := randomMFAUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	expectClaimLoginAttempt(store, user)
+	// the right password alone does not count as a successful login
+	store.EXPECT().ReleaseLoginAttempt(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().UnlockUser(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, _ := json.Marshal(map[string]any{"username": user.Username, "password": password})
+	request := httptest.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+	request.Header.Set("Content-Type", "application/json")
+	server.router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", recorder.Code)
+	}
+	var resp mfaChallengeResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if !resp.MFARequired || resp.MFAToken == "" {
+		t.Fatalf("expected an mfa challenge, got %+v", resp)
+	}
+
+	// the challenge token only proves the password, it is not an access token
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "/accounts", nil)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+resp.MFAToken)
+	server.router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("expected status code 401, got %d", recorder.Code)
+	}
+}
+
+func TestLoginMFAAPI(t *testing.T) {
+	user, _ := randomMFAUser(t)
+	plain, _ := randomUser(t)
+	recoveryCode := "ABCDEFGH234567QR"
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		tokenType     token.TokenType
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "TOTP",
+			user:      user,
+			tokenType: token.MFAChallengeToken,
+			code:      func(t *testing.T) string { return currentTOTPCode(t, user) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectClaimLoginAttempt(store, user)
+				store.EXPECT().UnlockUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				arg := db.UseUserTOTPStepParams{
+					Username:     user.Username,
+					TotpLastStep: util.TOTPStep(time.Now()),
+				}
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Eq(arg)).Times(1).Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var resp loginUserResponse
+				if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if resp.AccessToken == "" {
+					t.Error("expected access token to be non-empty")
+				}
+			},
+		},
+		{
+			name:      "RecoveryCode",
+			user:      user,
+			tokenType: token.MFAChallengeToken,
+			code:      func(t *testing.T) string { return strings.ToLower(recoveryCode) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectClaimLoginAttempt(store, user)
+				store.EXPECT().UnlockUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				arg := db.UseMFARecoveryCodeParams{
+					Username: user.Username,
+					CodeHash: util.HashOneTimeCode(recoveryCode),
+				}
+				store.EXPECT().UseMFARecoveryCode(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.MfaRecoveryCode{}, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:      "UsedRecoveryCode",
+			user:      user,
+			tokenType: token.MFAChallengeToken,
+			code:      func(t *testing.T) string { return recoveryCode },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectClaimLoginAttempt(store, user)
+				store.EXPECT().
+					UseMFARecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.MfaRecoveryCode{}, db.ErrRecordNotFound)
+				store.EXPECT().UnlockUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeInvalidMFACode)
+			},
+		},
+		{
+			// a code whose time step was already used is rejected
+			name:      "ReplayedTOTP",
+			user:      user,
+			tokenType: token.MFAChallengeToken,
+			code:      func(t *testing.T) string { return currentTOTPCode(t, user) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectClaimLoginAttempt(store, user)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().UnlockUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeInvalidMFACode)
+			},
+		},
+		{
+			name:      "MFANotEnabled",
+			user:      plain,
+			tokenType: token.MFAChallengeToken,
+			code:      func(t *testing.T) string { return "123456" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(plain.Username)).Times(1).Return(plain, nil)
+				expectClaimLoginAttempt(store, plain)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UnlockUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			// guessing codes locks the user out like guessing passwords
+			name:      "Locked",
+			user:      user,
+			tokenType: token.MFAChallengeToken,
+			code:      func(t *testing.T) string { return currentTOTPCode(t, user) },
+			buildStubs: func(store *mockdb.MockStore) {
+				locked := user
+				locked.LockedUntil = pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true}
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(locked, nil)
+				store.EXPECT().
+					ClaimLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusTooManyRequests {
+					t.Errorf("expected status code 429, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeLoginLocked)
+			},
+		},
+		{
+			// an access token is not a challenge token
+			name:      "AccessToken",
+			user:      user,
+			tokenType: token.AccessToken,
+			code:      func(t *testing.T) string { return currentTOTPCode(t, user) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			mfaToken, _, err := server.tokenMaker.CreateToken(tc.user.Username, util.DepositorRole, tc.tokenType, time.Minute)
+			if err != nil {
+				t.Fatalf("cannot create token: %v", err)
+			}
+
+			data, _ := json.Marshal(map[string]any{"mfa_token": mfaToken, "code": tc.code(t)})
+			request := httptest.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateTransferStepUp(t *testing.T) {
+	user, _ := randomMFAUser(t)
+	plain, _ := randomUser(t)
+	plain.Username = user.Username
+	recipient, _ := randomUser(t)
+
+	account1 := db.Account{ID: 1, Owner: user.Username, Balance: 1000, Currency: util.USD}
+	account2 := db.Account{ID: 2, Owner: recipient.Username, Balance: 500, Currency: util.USD}
+
+	testCases := []struct {
+		name          string
+		amount        int64
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "BelowThreshold",
+			amount: 500,
+			code:   func(t *testing.T) string { return "" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "ValidCode",
+			amount: 501,
+			code:   func(t *testing.T) string { return currentTOTPCode(t, user) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectClaimLoginAttempt(store, user)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().ReleaseLoginAttempt(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "MissingCode",
+			amount: 501,
+			code:   func(t *testing.T) string { return "" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusForbidden {
+					t.Errorf("expected status code 403, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeMFARequired)
+			},
+		},
+		{
+			name:   "ReplayedCode",
+			amount: 501,
+			code:   func(t *testing.T) string { return currentTOTPCode(t, user) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectClaimLoginAttempt(store, user)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().ReleaseLoginAttempt(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusForbidden {
+					t.Errorf("expected status code 403, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeInvalidMFACode)
+			},
+		},
+		{
+			// users without MFA cannot send transfers above the threshold at all
+			name:   "MFANotEnabled",
+			amount: 501,
+			code:   func(t *testing.T) string { return "123456" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(plain, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusForbidden {
+					t.Errorf("expected status code 403, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeMFARequired)
+			},
+		},
+		{
+			// a user locked out by wrong codes is not asked to verify another one
+			name:   "LockedOut",
+			amount: 501,
+			code:   func(t *testing.T) string { return currentTOTPCode(t, user) },
+			buildStubs: func(store *mockdb.MockStore) {
+				locked := user
+				locked.LockedUntil = pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true}
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(locked, nil)
+				store.EXPECT().
+					ClaimLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusTooManyRequests {
+					t.Errorf("expected status code 429, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeLoginLocked)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.MFAStepUpAmounts = map[string]int64{util.USD: 500}
+			recorder := httptest.NewRecorder()
+
+			body := map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          tc.amount,
+				"currency":        util.USD,
+			}
+			if code := tc.code(t); code != "" {
+				body["mfa_code"] = code
+			}
+			data, _ := json.Marshal(body)
+			request := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestStepUpOnOtherMoneyPaths(t *testing.T) {
+	user, _ := randomMFAUser(t)
+	recipient, _ := randomUser(t)
+
+	account1 := db.Account{ID: 1, Owner: user.Username, Balance: 1000, Currency: util.USD}
+	account2 := db.Account{ID: 2, Owner: recipient.Username, Balance: 500, Currency: util.USD}
+	schedule := randomScheduledTransfer(user.Username, account1.ID, account2.ID)
+	startAt := time.Now().Add(time.Minute)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          func(t *testing.T) map[string]any
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			// small legs are added up, so a large amount cannot be split to avoid the code
+			name:   "BatchWithoutCode",
+			method: http.MethodPost,
+			url:    "/transfers/batch",
+			body: func(t *testing.T) map[string]any {
+				leg := map[string]any{"to_account_id": account2.ID, "amount": 300, "currency": util.USD}
+				return map[string]any{"from_account_id": account1.ID, "legs": []any{leg, leg}}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: checkMFARequired,
+		},
+		{
+			name:   "BatchWithCode",
+			method: http.MethodPost,
+			url:    "/transfers/batch",
+			body: func(t *testing.T) map[string]any {
+				leg := map[string]any{"to_account_id": account2.ID, "amount": 300, "currency": util.USD}
+				return map[string]any{"from_account_id": account1.ID, "legs": []any{leg, leg}, "mfa_code": currentTOTPCode(t, user)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectClaimLoginAttempt(store, user)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().ReleaseLoginAttempt(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.BatchTransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			// the recipient could capture the hold without the payer
+			name:   "HoldWithoutCode",
+			method: http.MethodPost,
+			url:    "/holds",
+			body: func(t *testing.T) map[string]any {
+				return map[string]any{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": 501, "currency": util.USD}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().AuthorizeTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: checkMFARequired,
+		},
+		{
+			name:   "ScheduledTransferWithoutCode",
+			method: http.MethodPost,
+			url:    "/scheduled_transfers",
+			body: func(t *testing.T) map[string]any {
+				return map[string]any{
+					"from_account_id": account1.ID,
+					"to_account_id":   account2.ID,
+					"amount":          501,
+					"currency":        util.USD,
+					"frequency":       util.DailyFrequency,
+					"start_at":        startAt,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: checkMFARequired,
+		},
+		{
+			name:   "RaisedScheduledAmountWithoutCode",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			body: func(t *testing.T) map[string]any {
+				return map[string]any{"amount": 501}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: checkMFARequired,
+		},
+		{
+			name:   "RaisedScheduledAmountWithCode",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			body: func(t *testing.T) map[string]any {
+				return map[string]any{"amount": 501, "mfa_code": currentTOTPCode(t, user)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectClaimLoginAttempt(store, user)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().ReleaseLoginAttempt(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(1).Return(schedule, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.MFAStepUpAmounts = map[string]int64{util.USD: 500}
+			recorder := httptest.NewRecorder()
+
+			data, _ := json.Marshal(tc.body(t))
+			request := httptest.NewRequest(tc.method, tc.url, bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestStepUpLockout(t *testing.T) {
+	user, _ := randomMFAUser(t)
+	recipient, _ := randomUser(t)
+
+	account1 := db.Account{ID: 1, Owner: user.Username, Balance: 1000, Currency: util.USD}
+	account2 := db.Account{ID: 2, Owner: recipient.Username, Balance: 500, Currency: util.USD}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the store keeps the lockout of the user like the users table does
+	store := mockdb.NewMockStore(ctrl)
+	stored := user
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).AnyTimes().Return(account1, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().DoAndReturn(
+		func(_ context.Context, _ string) (db.User, error) { return stored, nil },
+	)
+	store.EXPECT().ClaimLoginAttempt(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(_ context.Context, arg db.ClaimLoginAttemptParams) (db.User, error) {
+			if arg.FailedLoginAttempts != stored.FailedLoginAttempts || stored.LockedUntil.Time.After(time.Now()) {
+				return db.User{}, db.ErrRecordNotFound
+			}
+			stored.FailedLoginAttempts++
+			stored.LockedUntil = arg.LockedUntil
+			return stored, nil
+		},
+	)
+	store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	server.config.MFAStepUpAmounts = map[string]int64{util.USD: 500}
+	server.config.LoginDelay = 0
+
+	wrongCode := "000000"
+	if currentTOTPCode(t, user) == wrongCode {
+		wrongCode = "000001"
+	}
+
+	// every wrong code is counted, and once LOGIN_MAX_ATTEMPTS is reached even the right code is refused
+	wants := make([]int, server.config.LoginMaxAttempts+1)
+	for i := range wants {
+		wants[i] = http.StatusForbidden
+	}
+	wants[len(wants)-1] = http.StatusTooManyRequests
+
+	for i, want := range wants {
+		code := wrongCode
+		if want == http.StatusTooManyRequests {
+			code = currentTOTPCode(t, user)
+		}
+
+		recorder := httptest.NewRecorder()
+		data, _ := json.Marshal(map[string]any{
+			"from_account_id": account1.ID,
+			"to_account_id":   account2.ID,
+			"amount":          501,
+			"currency":        util.USD,
+			"mfa_code":        code,
+		})
+		request := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+		request.Header.Set("Content-Type", "application/json")
+
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		if recorder.Code != want {
+			t.Errorf("attempt %d: expected status code %d, got %d", i+1, want, recorder.Code)
+		}
+	}
+}
+
+// checkMFARequired checks that a request was refused for lack of a TOTP code
+func checkMFARequired(t *testing.T, recorder *httptest.ResponseRecorder) {
+	t.Helper()
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("expected status code 403, got %d", recorder.Code)
+	}
+	checkErrorCode(t, recorder, errCodeMFARequired)
+}
@@ -3,9 +3,12 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
 	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,7 +18,29 @@ const (
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// Scopes name what a token may do; they are granted through the role embedded in the token.
+const (
+	scopeAccountsRead        = "accounts:read"
+	scopeAccountsReadAny     = "accounts:read_any"
+	scopeAccountsWrite       = "accounts:write"
+	scopeTransfersWrite      = "transfers:write"
+	scopeTransfersReverseAny = "transfers:reverse_any"
+	scopeUsersAdmin          = "users:admin"
+	scopeLimitsWrite         = "limits:write"
+)
+
+var roleScopes = map[string][]string{
+	util.DepositorRole: {scopeAccountsRead, scopeAccountsWrite, scopeTransfersWrite},
+	util.BankerRole:    {scopeAccountsRead, scopeAccountsReadAny, scopeLimitsWrite},
+	util.AdminRole:     {scopeAccountsRead, scopeAccountsReadAny, scopeAccountsWrite, scopeTransfersWrite, scopeTransfersReverseAny, scopeUsersAdmin, scopeLimitsWrite},
+}
+
+// hasScope checks if the role carried by the token grants the given scope.
+func hasScope(payload *token.Payload, scope string) bool {
+	return slices.Contains(roleScopes[payload.Role], scope)
+}
+
+func authMiddleware(tokenMaker token.Maker, revocations *revocationList) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// Get the authorization header
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
@@ -50,8 +75,76 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 			return
 		}
 
+		// Refresh and MFA challenge tokens outlive access tokens or prove less, so they cannot authorize requests
+		if payload.Type != token.AccessToken {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrWrongTokenType))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(token.ErrWrongTokenType))
+			return
+		}
+
+		// Reject tokens revoked by a logout or issued before the last password change
+		if revocations.isRevoked(payload) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+			return
+		}
+
 		// Set the authorization payload in the context for the next handlers
 		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next()
 	}
 }
+
+// requireRole only lets the request through when the token carries one of the given roles.
+// It must run after authMiddleware.
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !slices.Contains(roles, payload.Role) {
+			err := fmt.Errorf("role %q is not allowed to access this resource", payload.Role)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// requireScope only lets the request through when the token's role grants the given scope.
+// It must run after authMiddleware.
+func requireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !hasScope(payload, scope) {
+			err := fmt.Errorf("missing required scope: %s", scope)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// requireVerifiedEmail only lets the request through when the user has verified their email.
+// It does nothing unless required is set, and must run after authMiddleware.
+func requireVerifiedEmail(store db.Store, required bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !required {
+			ctx.Next()
+			return
+		}
+
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := store.GetUser(ctx, payload.Username)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !user.IsEmailVerified {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorCodeResponse(errCodeEmailNotVerified, ErrEmailNotVerified))
+			return
+		}
+
+		ctx.Next()
+	}
+}
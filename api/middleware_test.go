@@ -4,12 +4,17 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
 	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
 )
 
 func addAuthorization(
@@ -20,7 +25,19 @@ func addAuthorization(
 	username string,
 	duration time.Duration,
 ) {
-	tokenStr, err := tokenMaker.CreateToken(username, duration)
+	addAuthorizationWithRole(t, request, tokenMaker, authorizationType, username, util.DepositorRole, duration)
+}
+
+func addAuthorizationWithRole(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	role string,
+	duration time.Duration,
+) {
+	tokenStr, _, err := tokenMaker.CreateToken(username, role, token.AccessToken, duration)
 	if err != nil {
 		t.Fatalf("cannot create token: %v", err)
 	}
@@ -79,6 +96,22 @@ func TestAuthMiddleware(t *testing.T) {
 				}
 			},
 		},
+		{
+			// refresh tokens last far longer than access tokens, so they must not authorize requests
+			name: "RefreshToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				refreshToken, _, err := tokenMaker.CreateToken(gofakeit.LetterN(10), util.DepositorRole, token.RefreshToken, time.Hour)
+				if err != nil {
+					t.Fatalf("cannot create token: %v", err)
+				}
+				request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+refreshToken)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
 		{
 			name: "ExpiredToken",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
@@ -99,7 +132,7 @@ func TestAuthMiddleware(t *testing.T) {
 			authPath := "/auth"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.tokenMaker, server.revocations),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -114,3 +147,279 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMiddlewareRevokedToken(t *testing.T) {
+	testCases := []struct {
+		name          string
+		revoke        func(list *revocationList, payload *token.Payload)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "NotRevoked",
+			revoke: func(list *revocationList, payload *token.Payload) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "RevokedTokenID",
+			revoke: func(list *revocationList, payload *token.Payload) {
+				list.revokeToken(payload.ID, payload.ExpiredAt)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "RevokedByLogoutAll",
+			revoke: func(list *revocationList, payload *token.Payload) {
+				list.revokeUser(payload.Username, payload.IssuedAt.Add(time.Second))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "IssuedAfterLogoutAll",
+			revoke: func(list *revocationList, payload *token.Payload) {
+				list.revokeUser(payload.Username, payload.IssuedAt.Add(-time.Second))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, server.revocations),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			tokenStr, payload, err := server.tokenMaker.CreateToken(gofakeit.LetterN(10), util.DepositorRole, token.AccessToken, time.Minute)
+			if err != nil {
+				t.Fatalf("cannot create token: %v", err)
+			}
+			tc.revoke(server.revocations, payload)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, authPath, nil)
+			request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tokenStr))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRequireRoleAndScope(t *testing.T) {
+	testCases := []struct {
+		name         string
+		role         string
+		middleware   gin.HandlerFunc
+		expectedCode int
+	}{
+		{
+			name:         "RoleAllowed",
+			role:         util.AdminRole,
+			middleware:   requireRole(util.AdminRole),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "RoleAmongSeveral",
+			role:         util.BankerRole,
+			middleware:   requireRole(util.BankerRole, util.AdminRole),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "RoleForbidden",
+			role:         util.DepositorRole,
+			middleware:   requireRole(util.AdminRole),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "ScopeGranted",
+			role:         util.BankerRole,
+			middleware:   requireScope(scopeAccountsReadAny),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "ScopeMissing",
+			role:         util.BankerRole,
+			middleware:   requireScope(scopeTransfersWrite),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "UnknownRole",
+			role:         gofakeit.LetterN(10),
+			middleware:   requireScope(scopeAccountsRead),
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, server.revocations),
+				tc.middleware,
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, authPath, nil)
+
+			addAuthorizationWithRole(t, request, server.tokenMaker, authorizationTypeBearer, gofakeit.LetterN(10), tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			if recorder.Code != tc.expectedCode {
+				t.Errorf("expected status code %d, got %d", tc.expectedCode, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	user, _ := randomUser(t)
+	verified := user
+	verified.IsEmailVerified = true
+
+	testCases := []struct {
+		name         string
+		required     bool
+		buildStubs   func(store *mockdb.MockStore)
+		expectedCode int
+	}{
+		{
+			name:     "NotRequired",
+			required: false,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:     "Verified",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(verified, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:     "Unverified",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:     "InternalError",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, fmt.Errorf("internal error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, server.revocations),
+				requireVerifiedEmail(store, tc.required),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, authPath, nil)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			if recorder.Code != tc.expectedCode {
+				t.Errorf("expected status code %d, got %d", tc.expectedCode, recorder.Code)
+			}
+			if tc.expectedCode == http.StatusForbidden {
+				checkErrorCode(t, recorder, errCodeEmailNotVerified)
+			}
+		})
+	}
+}
+
+func TestMoneyRoutesRequireVerifiedEmail(t *testing.T) {
+	user, _ := randomUser(t)
+
+	routes := []struct {
+		method string
+		url    string
+	}{
+		{http.MethodPost, "/accounts"},
+		{http.MethodPost, "/transfers"},
+		{http.MethodPost, "/transfers/batch"},
+		{http.MethodPost, "/transfers/1/reverse"},
+		{http.MethodPost, "/holds"},
+		{http.MethodPost, "/holds/1/capture"},
+		{http.MethodPost, "/scheduled_transfers"},
+		{http.MethodPatch, "/scheduled_transfers/1"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.url, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the unverified user is turned away before the handler touches the store
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+
+			config := newTestServer(t, store).config
+			config.RequireVerifiedEmail = true
+			server, err := NewServer(config, store)
+			if err != nil {
+				t.Fatalf("cannot create server: %v", err)
+			}
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(route.method, route.url, strings.NewReader("{}"))
+			request.Header.Set("Content-Type", "application/json")
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			if recorder.Code != http.StatusForbidden {
+				t.Errorf("expected status code 403, got %d", recorder.Code)
+			}
+			checkErrorCode(t, recorder, errCodeEmailNotVerified)
+		})
+	}
+}
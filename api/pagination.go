@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageRequest selects a page of a list ordered by (created_at, id).
+// Cursor is a next_cursor or prev_cursor from an earlier page; without it the first page is returned.
+type pageRequest struct {
+	PageSize int32  `form:"page_size,default=20" binding:"min=1,max=100"`
+	Cursor   string `form:"cursor"`
+}
+
+// pageResponse is the envelope of every list endpoint. HasMore reports whether
+// more items follow in the direction the page was requested in.
+type pageResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// pageCursor is the position of an item in a list and which way to page from it.
+// It is handed to clients as an opaque string.
+type pageCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        int64     `json:"id"`
+	Direction string    `json:"direction"`
+}
+
+func (cursor pageCursor) encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses the cursor of a page request. An empty cursor decodes to the zero pageCursor.
+func decodeCursor(encoded string) (pageCursor, error) {
+	var cursor pageCursor
+	if encoded == "" {
+		return cursor, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || (cursor.Direction != cursorNext && cursor.Direction != cursorPrev) {
+		return pageCursor{}, errInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// backward reports whether the page before the cursor was requested
+func (cursor pageCursor) backward() bool {
+	return cursor.Direction == cursorPrev
+}
+
+// position returns the keyset position to fetch rows from. Without a cursor it is the start of
+// the list: before every row for lists in ascending order and after every row for descending ones.
+func (cursor pageCursor) position(descending bool) (pgtype.Timestamptz, int64) {
+	switch {
+	case cursor.Direction != "":
+		return pgtype.Timestamptz{Time: cursor.CreatedAt, Valid: true}, cursor.ID
+	case descending:
+		return pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}, math.MaxInt64
+	default:
+		return pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Valid: true}, 0
+	}
+}
+
+// newPageResponse builds the envelope for rows fetched with a limit of pageSize+1, in
+// list order when paging forward and in reverse list order when paging backward.
+func newPageResponse[T any](rows []T, pageSize int32, cursor pageCursor, position func(T) (pgtype.Timestamptz, int64)) pageResponse[T] {
+	hasMore := len(rows) > int(pageSize)
+	if hasMore {
+		rows = rows[:pageSize]
+	}
+	if cursor.backward() {
+		slices.Reverse(rows)
+	}
+
+	page := pageResponse[T]{Items: rows, HasMore: hasMore}
+	if len(rows) == 0 {
+		return page
+	}
+
+	// a page reached backward always has items after it, and one reached forward from a cursor items before it
+	if hasMore || cursor.backward() {
+		createdAt, id := position(rows[len(rows)-1])
+		page.NextCursor = pageCursor{CreatedAt: createdAt.Time, ID: id, Direction: cursorNext}.encode()
+	}
+	if (hasMore && cursor.backward()) || cursor.Direction == cursorNext {
+		createdAt, id := position(rows[0])
+		page.PrevCursor = pageCursor{CreatedAt: createdAt.Time, ID: id, Direction: cursorPrev}.encode()
+	}
+
+	return page
+}
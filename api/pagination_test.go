@@ -0,0 +1,161 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type pageItem struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+func pageItemPosition(item pageItem) (pgtype.Timestamptz, int64) {
+	return pgtype.Timestamptz{Time: item.CreatedAt, Valid: true}, item.ID
+}
+
+func TestDecodeCursor(t *testing.T) {
+	cursor := pageCursor{CreatedAt: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), ID: 42, Direction: cursorNext}
+
+	testCases := []struct {
+		name    string
+		encoded string
+		want    pageCursor
+		wantErr bool
+	}{
+		{name: "Empty", encoded: "", want: pageCursor{}},
+		{name: "RoundTrip", encoded: cursor.encode(), want: cursor},
+		{name: "NotBase64", encoded: "%%%", wantErr: true},
+		{name: "NotJSON", encoded: "bm90IGpzb24", wantErr: true},
+		{name: "UnknownDirection", encoded: pageCursor{ID: 1, Direction: "sideways"}.encode(), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decodeCursor(tc.encoded)
+			if tc.wantErr {
+				if err != errInvalidCursor {
+					t.Errorf("expected errInvalidCursor, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.CreatedAt.Equal(tc.want.CreatedAt) || got.ID != tc.want.ID || got.Direction != tc.want.Direction {
+				t.Errorf("expected cursor %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewPageResponse(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := make([]pageItem, 4)
+	for i := range items {
+		items[i] = pageItem{CreatedAt: base.Add(time.Duration(i) * time.Hour), ID: int64(i + 1)}
+	}
+
+	testCases := []struct {
+		name      string
+		rows      []pageItem
+		cursor    pageCursor
+		wantIDs   []int64
+		wantMore  bool
+		wantNext  int64
+		wantPrev  int64
+		wantEmpty bool
+	}{
+		{
+			name:     "FirstPageWithMore",
+			rows:     items[:3],
+			wantIDs:  []int64{1, 2},
+			wantMore: true,
+			wantNext: 2,
+		},
+		{
+			name:    "OnlyPage",
+			rows:    items[:2],
+			wantIDs: []int64{1, 2},
+		},
+		{
+			name:     "ForwardFromCursor",
+			rows:     items[2:4],
+			cursor:   pageCursor{ID: 2, Direction: cursorNext},
+			wantIDs:  []int64{3, 4},
+			wantPrev: 3,
+		},
+		{
+			// rows are fetched in reverse list order when paging backward
+			name:     "BackwardWithMore",
+			rows:     []pageItem{items[2], items[1], items[0]},
+			cursor:   pageCursor{ID: 4, Direction: cursorPrev},
+			wantIDs:  []int64{2, 3},
+			wantMore: true,
+			wantNext: 3,
+			wantPrev: 2,
+		},
+		{
+			name:     "BackwardToStart",
+			rows:     []pageItem{items[1], items[0]},
+			cursor:   pageCursor{ID: 3, Direction: cursorPrev},
+			wantIDs:  []int64{1, 2},
+			wantNext: 2,
+		},
+		{
+			name:      "Empty",
+			rows:      nil,
+			cursor:    pageCursor{ID: 4, Direction: cursorNext},
+			wantEmpty: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rows := append([]pageItem(nil), tc.rows...)
+			page := newPageResponse(rows, 2, tc.cursor, pageItemPosition)
+
+			if page.HasMore != tc.wantMore {
+				t.Errorf("expected has_more %v, got %v", tc.wantMore, page.HasMore)
+			}
+			if tc.wantEmpty {
+				if len(page.Items) != 0 || page.NextCursor != "" || page.PrevCursor != "" {
+					t.Errorf("expected an empty page, got %+v", page)
+				}
+				return
+			}
+
+			ids := make([]int64, len(page.Items))
+			for i, item := range page.Items {
+				ids[i] = item.ID
+			}
+			if len(ids) != len(tc.wantIDs) || ids[0] != tc.wantIDs[0] || ids[len(ids)-1] != tc.wantIDs[len(tc.wantIDs)-1] {
+				t.Errorf("expected items %v, got %v", tc.wantIDs, ids)
+			}
+
+			checkPageCursor(t, "next", page.NextCursor, tc.wantNext, cursorNext)
+			checkPageCursor(t, "prev", page.PrevCursor, tc.wantPrev, cursorPrev)
+		})
+	}
+}
+
+// checkPageCursor checks that encoded points at the item with wantID, or is empty when wantID is 0
+func checkPageCursor(t *testing.T, name, encoded string, wantID int64, direction string) {
+	t.Helper()
+	if wantID == 0 {
+		if encoded != "" {
+			t.Errorf("expected no %s cursor, got %q", name, encoded)
+		}
+		return
+	}
+
+	cursor, err := decodeCursor(encoded)
+	if err != nil {
+		t.Fatalf("failed to decode %s cursor: %v", name, err)
+	}
+	if cursor.ID != wantID || cursor.Direction != direction {
+		t.Errorf("expected %s cursor at %d, got %+v", name, wantID, cursor)
+	}
+}
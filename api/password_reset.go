@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/mail"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword mails a one-time password reset code to the user with the given email.
+// It answers 202 straight away and does the work in the background for every email, so neither the status
+// nor the response time tells whether a user has that email.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// every request sends an email, so they are limited by address and by client to stop mail bombing
+	now := time.Now()
+	email := strings.ToLower(req.Email)
+	wait := max(server.resetEmailThrottle.blockedFor(email, now), server.resetIPThrottle.blockedFor(ctx.ClientIP(), now))
+	if wait > 0 {
+		err := errors.New("too many password reset requests, try again later")
+		tooManyRequestsResponse(ctx, wait, errCodeTooManyResets, err)
+		return
+	}
+	server.resetEmailThrottle.recordFailure(email, now)
+	server.resetIPThrottle.recordFailure(ctx.ClientIP(), now)
+
+	server.runInBackground("send password reset email", func(ctx context.Context) error {
+		return server.sendPasswordReset(ctx, req.Email)
+	})
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// sendPasswordReset stores a new reset code for the user with the given email and mails it to them.
+// Unknown emails are ignored.
+func (server *Server) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := server.store.GetUserByEmail(ctx, email)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	code, err := util.RandomOneTimeCode()
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		Username:  user.Username,
+		CodeHash:  util.HashOneTimeCode(code),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(server.config.PasswordResetDuration), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	return server.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your SimpleBank password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this code to reset your SimpleBank password: %s\n\n"+
+			"The code expires in %s. If you did not ask to reset your password, you can ignore this email.\n",
+			user.FullName, code, server.config.PasswordResetDuration),
+	})
+}
+
+type resetPasswordRequest struct {
+	Code        string `json:"code" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+// resetPassword sets a new password with a code from forgotPassword. Like a password change,
+// it blocks every session of the user and rejects every token issued to them before.
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		CodeHash:       util.HashOneTimeCode(req.Code),
+		HashedPassword: hashedPassword,
+	})
+	switch {
+	case errors.Is(err, db.ErrResetCodeInvalid):
+		ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeInvalidResetCode, err))
+		return
+	case errors.Is(err, db.ErrResetCodeExpired):
+		ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeResetCodeExpired, err))
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.revocations.revokeUser(user.Username, user.PasswordChangedAt.Time)
+
+	ctx.JSON(http.StatusOK, parseUserResponse(user))
+}
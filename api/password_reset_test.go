@@ -0,0 +1,333 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/mail"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+// resetCodePattern finds the code in a password reset email
+var resetCodePattern = regexp.MustCompile(`password: ([A-Z2-7]{16})`)
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          map[string]any
+		buildStubs    func(store *mockdb.MockStore, codeHash *string)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, outbox *mail.Outbox, codeHash string)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore, codeHash *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreatePasswordResetToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreatePasswordResetTokenParams) (db.PasswordResetToken, error) {
+						if arg.Username != user.Username {
+							t.Errorf("expected username %s, got %s", user.Username, arg.Username)
+						}
+						if d := time.Until(arg.ExpiresAt.Time); d < 14*time.Minute || d > 15*time.Minute {
+							t.Errorf("expected the code to expire in 15 minutes, got %v", d)
+						}
+						*codeHash = arg.CodeHash
+						return db.PasswordResetToken{ID: 1, Username: arg.Username, CodeHash: arg.CodeHash, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, outbox *mail.Outbox, codeHash string) {
+				if recorder.Code != http.StatusAccepted {
+					t.Fatalf("expected status code 202, got %d", recorder.Code)
+				}
+				messages := outbox.Messages()
+				if len(messages) != 1 {
+					t.Fatalf("expected 1 email, got %d", len(messages))
+				}
+				if messages[0].To != user.Email {
+					t.Errorf("expected email to %s, got %s", user.Email, messages[0].To)
+				}
+				match := resetCodePattern.FindStringSubmatch(messages[0].Body)
+				if match == nil {
+					t.Fatalf("expected a reset code in the email, got %q", messages[0].Body)
+				}
+				if util.HashOneTimeCode(match[1]) != codeHash {
+					t.Errorf("expected the stored hash to match the mailed code")
+				}
+			},
+		},
+		{
+			// unknown emails get the same answer, so the endpoint does not reveal who has an account
+			name: "UnknownEmail",
+			body: map[string]any{"email": "nobody@example.com"},
+			buildStubs: func(store *mockdb.MockStore, codeHash *string) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, outbox *mail.Outbox, codeHash string) {
+				if recorder.Code != http.StatusAccepted {
+					t.Errorf("expected status code 202, got %d", recorder.Code)
+				}
+				if len(outbox.Messages()) != 0 {
+					t.Errorf("expected no email, got %d", len(outbox.Messages()))
+				}
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: map[string]any{"email": "invalid-email"},
+			buildStubs: func(store *mockdb.MockStore, codeHash *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, outbox *mail.Outbox, codeHash string) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			// failures happen after the response is sent, so they do not reveal that the email has an account either
+			name: "InternalError",
+			body: map[string]any{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore, codeHash *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreatePasswordResetToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PasswordResetToken{}, fmt.Errorf("internal error"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, outbox *mail.Outbox, codeHash string) {
+				if recorder.Code != http.StatusAccepted {
+					t.Errorf("expected status code 202, got %d", recorder.Code)
+				}
+				if len(outbox.Messages()) != 0 {
+					t.Errorf("expected no email, got %d", len(outbox.Messages()))
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var codeHash string
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, &codeHash)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, _ := json.Marshal(tc.body)
+			request := httptest.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			server.router.ServeHTTP(recorder, request)
+			server.background.Wait()
+			tc.checkResponse(t, recorder, server.mailer.(*mail.Outbox), codeHash)
+		})
+	}
+}
+
+func TestForgotPasswordRateLimit(t *testing.T) {
+	testCases := []struct {
+		name string
+		// request returns the email and client address of the i-th request
+		request func(i int) (email string, remoteAddr string)
+	}{
+		{
+			// one address cannot be bombed from many clients
+			name: "SameEmail",
+			request: func(i int) (string, string) {
+				return "victim@example.com", fmt.Sprintf("192.0.2.%d:1234", i+1)
+			},
+		},
+		{
+			// one client cannot bomb many addresses
+			name: "SameClient",
+			request: func(i int) (string, string) {
+				return fmt.Sprintf("user%d@example.com", i), "192.0.2.1:1234"
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the request over the limit does not even look the email up
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUserByEmail(gomock.Any(), gomock.Any()).
+				Times(2).
+				Return(db.User{}, db.ErrRecordNotFound)
+
+			server := newTestServer(t, store)
+			server.resetEmailThrottle = newLoginThrottle(2, time.Minute)
+			server.resetIPThrottle = newLoginThrottle(2, time.Minute)
+
+			for i, want := range []int{http.StatusAccepted, http.StatusAccepted, http.StatusTooManyRequests} {
+				email, remoteAddr := tc.request(i)
+				recorder := httptest.NewRecorder()
+				data, _ := json.Marshal(map[string]any{"email": email})
+				request := httptest.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(data))
+				request.Header.Set("Content-Type", "application/json")
+				request.RemoteAddr = remoteAddr
+
+				server.router.ServeHTTP(recorder, request)
+				if recorder.Code != want {
+					t.Errorf("request %d: expected status code %d, got %d", i+1, want, recorder.Code)
+				}
+				if want == http.StatusTooManyRequests {
+					checkErrorCode(t, recorder, errCodeTooManyResets)
+					if recorder.Header().Get("Retry-After") == "" {
+						t.Error("expected a Retry-After header")
+					}
+				}
+			}
+			server.background.Wait()
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	code := "ABCDEFGH234567QR"
+
+	testCases := []struct {
+		name          string
+		body          map[string]any
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{"code": code, "new_password": "new-secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				reset := user
+				reset.PasswordChangedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ResetPasswordTxParams) (db.User, error) {
+						if arg.CodeHash != util.HashOneTimeCode(code) {
+							t.Errorf("expected the hash of the code to be looked up")
+						}
+						if err := util.CheckPassword("new-secret", arg.HashedPassword); err != nil {
+							t.Errorf("expected the new password to be hashed: %v", err)
+						}
+						reset.HashedPassword = arg.HashedPassword
+						return reset, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+				payload := &token.Payload{Username: user.Username, IssuedAt: time.Now().Add(-time.Minute)}
+				if !server.revocations.isRevoked(payload) {
+					t.Error("expected tokens issued before the reset to be revoked")
+				}
+			},
+		},
+		{
+			name: "InvalidCode",
+			body: map[string]any{"code": code, "new_password": "new-secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrResetCodeInvalid)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeInvalidResetCode)
+			},
+		},
+		{
+			name: "ExpiredCode",
+			body: map[string]any{"code": code, "new_password": "new-secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrResetCodeExpired)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeResetCodeExpired)
+			},
+		},
+		{
+			name: "TooShortPassword",
+			body: map[string]any{"code": code, "new_password": "short"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "InternalError",
+			body: map[string]any{"code": code, "new_password": "new-secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, fmt.Errorf("internal error"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, _ := json.Marshal(tc.body)
+			request := httptest.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server)
+		})
+	}
+}
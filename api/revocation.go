@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// revocationList is an in-process view of revoked tokens.
+// authMiddleware consults it on every request, so Postgres is only queried when the list is synced.
+type revocationList struct {
+	mu      sync.RWMutex
+	tokens  map[uuid.UUID]time.Time // token ID -> time the token expires anyway
+	cutoffs map[string]time.Time    // username -> tokens issued before this time are revoked, by a logout from all devices or a password change
+}
+
+func newRevocationList() *revocationList {
+	return &revocationList{
+		tokens:  make(map[uuid.UUID]time.Time),
+		cutoffs: make(map[string]time.Time),
+	}
+}
+
+// revokeToken marks a single token ID as revoked until it expires.
+func (list *revocationList) revokeToken(tokenID uuid.UUID, expiresAt time.Time) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	list.tokens[tokenID] = expiresAt
+}
+
+// revokeUser marks every token issued to the user before revokedAt as revoked,
+// after a logout from all devices, a password change or a change of role.
+func (list *revocationList) revokeUser(username string, revokedAt time.Time) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	if revokedAt.After(list.cutoffs[username]) {
+		list.cutoffs[username] = revokedAt
+	}
+}
+
+// isRevoked reports whether the token was revoked on its own, by a logout from all devices,
+// by a password change or by a change of role.
+func (list *revocationList) isRevoked(payload *token.Payload) bool {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+
+	if _, ok := list.tokens[payload.ID]; ok {
+		return true
+	}
+
+	cutoff, ok := list.cutoffs[payload.Username]
+	return ok && payload.IssuedAt.Before(cutoff)
+}
+
+// merge adds a snapshot loaded from the db and drops entries that can no longer match a live token.
+func (list *revocationList) merge(tokens []db.RevokedToken, cutoffs []db.ListUserTokenRevocationsRow, since time.Time) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	now := time.Now()
+	for id, expiresAt := range list.tokens {
+		if now.After(expiresAt) {
+			delete(list.tokens, id)
+		}
+	}
+	for username, revokedAt := range list.cutoffs {
+		if revokedAt.Before(since) {
+			delete(list.cutoffs, username)
+		}
+	}
+
+	for _, revoked := range tokens {
+		list.tokens[revoked.ID] = revoked.ExpiresAt.Time
+	}
+	for _, row := range cutoffs {
+		if row.TokensRevokedAt.Time.After(list.cutoffs[row.Username]) {
+			list.cutoffs[row.Username] = row.TokensRevokedAt.Time
+		}
+	}
+}
+
+// revokeToken persists the revocation of a single token and applies it to this process right away.
+func (server *Server) revokeToken(ctx context.Context, payload *token.Payload) error {
+	err := server.store.CreateRevokedToken(ctx, db.CreateRevokedTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: pgtype.Timestamptz{Time: payload.ExpiredAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	server.revocations.revokeToken(payload.ID, payload.ExpiredAt)
+	return nil
+}
+
+// syncRevocations loads all revocations that may still affect a live token into the in-process list.
+func (server *Server) syncRevocations(ctx context.Context) error {
+	if err := server.store.DeleteExpiredRevokedTokens(ctx); err != nil {
+		return err
+	}
+
+	tokens, err := server.store.ListActiveRevokedTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	// No token lives longer than the longest configured duration, so older cutoffs are irrelevant
+	lifetime := max(server.config.AccessTokenDuration, server.config.RefreshTokenDuration)
+	since := time.Now().Add(-lifetime)
+
+	cutoffs, err := server.store.ListUserTokenRevocations(ctx, pgtype.Timestamptz{Time: since, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	server.revocations.merge(tokens, cutoffs, since)
+	return nil
+}
+
+// WatchRevocations loads the revoked tokens once, then keeps the in-process list in sync
+// every interval until ctx is done, picking up revocations made by other instances.
+func (server *Server) WatchRevocations(ctx context.Context, interval time.Duration) error {
+	if err := server.syncRevocations(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := server.syncRevocations(ctx); err != nil {
+					log.Printf("cannot sync token revocations: %v", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRevocationListMerge(t *testing.T) {
+	list := newRevocationList()
+
+	expired := uuid.New()
+	list.revokeToken(expired, time.Now().Add(-time.Minute))
+	local := uuid.New()
+	list.revokeToken(local, time.Now().Add(time.Minute))
+	list.revokeUser("stale", time.Now().Add(-2*time.Hour))
+
+	loaded := db.RevokedToken{
+		ID:        uuid.New(),
+		Username:  gofakeit.LetterN(10),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+	}
+	cutoff := db.ListUserTokenRevocationsRow{
+		Username:        gofakeit.LetterN(10),
+		TokensRevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+
+	list.merge([]db.RevokedToken{loaded}, []db.ListUserTokenRevocationsRow{cutoff}, time.Now().Add(-time.Hour))
+
+	if _, ok := list.tokens[expired]; ok {
+		t.Error("expected expired token to be pruned")
+	}
+	if _, ok := list.tokens[local]; !ok {
+		t.Error("expected locally revoked token to be kept")
+	}
+	if _, ok := list.tokens[loaded.ID]; !ok {
+		t.Error("expected loaded token to be added")
+	}
+	if _, ok := list.cutoffs["stale"]; ok {
+		t.Error("expected stale cutoff to be pruned")
+	}
+	if !list.cutoffs[cutoff.Username].Equal(cutoff.TokensRevokedAt.Time) {
+		t.Errorf("expected cutoff %v, got %v", cutoff.TokensRevokedAt.Time, list.cutoffs[cutoff.Username])
+	}
+}
+
+func TestRevocationListRevokeUserKeepsLatestCutoff(t *testing.T) {
+	list := newRevocationList()
+	username := gofakeit.LetterN(10)
+
+	latest := time.Now()
+	list.revokeUser(username, latest)
+	list.revokeUser(username, latest.Add(-time.Hour))
+
+	payload := &token.Payload{
+		ID:       uuid.New(),
+		Username: username,
+		IssuedAt: latest.Add(-time.Minute),
+	}
+	if !list.isRevoked(payload) {
+		t.Error("expected token issued before the latest cutoff to be revoked")
+	}
+}
+
+func TestWatchRevocations(t *testing.T) {
+	revoked := db.RevokedToken{
+		ID:        uuid.New(),
+		Username:  gofakeit.LetterN(10),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+	}
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		wantErr    bool
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().ListActiveRevokedTokens(gomock.Any()).Times(1).Return([]db.RevokedToken{revoked}, nil)
+				store.EXPECT().ListUserTokenRevocations(gomock.Any(), gomock.Any()).Times(1).Return([]db.ListUserTokenRevocationsRow{}, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "DeleteExpiredError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any()).Times(1).Return(fmt.Errorf("internal error"))
+				store.EXPECT().ListActiveRevokedTokens(gomock.Any()).Times(0)
+			},
+			wantErr: true,
+		},
+		{
+			name: "ListTokensError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().ListActiveRevokedTokens(gomock.Any()).Times(1).Return(nil, fmt.Errorf("internal error"))
+				store.EXPECT().ListUserTokenRevocations(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			// cancel right away so only the initial synchronous load runs
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := server.WatchRevocations(ctx, time.Hour)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			payload := &token.Payload{ID: revoked.ID, Username: revoked.Username, IssuedAt: time.Now()}
+			if !server.revocations.isRevoked(payload) {
+				t.Error("expected loaded token to be revoked")
+			}
+		})
+	}
+}
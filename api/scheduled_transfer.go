@@ -0,0 +1,320 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// createScheduledTransferRequest schedules Amount in Currency to move between two accounts in that
+// currency at StartAt and then every IntervalCount days, weeks or months, until EndAt or MaxRuns.
+type createScheduledTransferRequest struct {
+	FromAccountID int64     `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64     `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        int64     `json:"amount" binding:"required,gt=0"`
+	Currency      string    `json:"currency" binding:"required,currency"`
+	Frequency     string    `json:"frequency" binding:"required,frequency"`
+	IntervalCount int32     `json:"interval_count" binding:"omitempty,min=1,max=366"`
+	StartAt       time.Time `json:"start_at" binding:"required"`
+	EndAt         time.Time `json:"end_at" binding:"omitempty,gtfield=StartAt"`
+	MaxRuns       int32     `json:"max_runs" binding:"omitempty,min=1"`
+	// MFACode is the TOTP code schedules above the MFA_STEP_UP_AMOUNT of their currency need
+	MFACode string `json:"mfa_code,omitempty" binding:"omitempty,numeric,len=6"`
+}
+
+// updateScheduledTransferRequest changes the fields that are set. Status pauses or resumes the schedule;
+// a resumed schedule whose next run is overdue runs once right away and then continues as usual.
+type updateScheduledTransferRequest struct {
+	Amount  int64     `json:"amount" binding:"omitempty,gt=0"`
+	EndAt   time.Time `json:"end_at"`
+	MaxRuns int32     `json:"max_runs" binding:"omitempty,min=1"`
+	Status  string    `json:"status" binding:"omitempty,oneof=active paused"`
+	// MFACode is the TOTP code a new amount above the MFA_STEP_UP_AMOUNT of the schedule's currency needs
+	MFACode string `json:"mfa_code,omitempty" binding:"omitempty,numeric,len=6"`
+}
+
+// to get scheduled transfer by id from the URI
+type getScheduledTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// to list scheduled transfers page by page, oldest first
+type listScheduledTransfersRequest struct {
+	pageRequest
+}
+
+// to list the runs of a scheduled transfer page by page, newest first
+type listScheduledTransferRunsRequest struct {
+	pageRequest
+}
+
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.StartAt.Before(time.Now()) {
+		err := errors.New("start_at must not be in the past")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if fromAccount.Owner != authPayload.Username {
+		err := fmt.Errorf("from account %d does not belong to the authenticated user", req.FromAccountID)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !server.stepUpVerified(ctx, authPayload.Username, map[string]int64{req.Currency: req.Amount}, req.MFACode) {
+		return
+	}
+
+	if _, valid := server.validAccount(ctx, req.ToAccountID, req.Currency); !valid {
+		return
+	}
+
+	intervalCount := req.IntervalCount
+	if intervalCount == 0 {
+		intervalCount = 1
+	}
+
+	schedule, err := server.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Frequency:     req.Frequency,
+		IntervalCount: intervalCount,
+		StartAt:       pgtype.Timestamptz{Time: req.StartAt, Valid: true},
+		EndAt:         pgtype.Timestamptz{Time: req.EndAt, Valid: !req.EndAt.IsZero()},
+		MaxRuns:       pgtype.Int4{Int32: req.MaxRuns, Valid: req.MaxRuns > 0},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedule)
+}
+
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	var req getScheduledTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	schedule, valid := server.authorizedScheduledTransfer(ctx, req.ID, true)
+	if !valid {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedule)
+}
+
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	cursor, err := decodeCursor(req.Cursor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	createdAt, id := cursor.position(false)
+	arg := db.ListScheduledTransfersAfterParams{
+		Owner:           authPayload.Username,
+		CursorCreatedAt: createdAt,
+		CursorID:        id,
+		PageLimit:       req.PageSize + 1,
+	}
+
+	var schedules []db.ScheduledTransfer
+	if cursor.backward() {
+		schedules, err = server.store.ListScheduledTransfersBefore(ctx, db.ListScheduledTransfersBeforeParams(arg))
+	} else {
+		schedules, err = server.store.ListScheduledTransfersAfter(ctx, arg)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(schedules, req.PageSize, cursor, func(schedule db.ScheduledTransfer) (pgtype.Timestamptz, int64) {
+		return schedule.CreatedAt, schedule.ID
+	}))
+}
+
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var uri getScheduledTransferRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	schedule, valid := server.authorizedScheduledTransfer(ctx, uri.ID, false)
+	if !valid {
+		return
+	}
+
+	if !req.EndAt.IsZero() && !req.EndAt.After(schedule.StartAt.Time) {
+		err := errors.New("end_at must be after start_at")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// schedules do not record their currency, so it is only looked up when a step-up may be needed
+	if req.Amount > 0 && len(server.config.MFAStepUpAmounts) > 0 {
+		fromAccount, err := server.store.GetAccount(ctx, schedule.FromAccountID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !server.stepUpVerified(ctx, schedule.Owner, map[string]int64{fromAccount.Currency: req.Amount}, req.MFACode) {
+			return
+		}
+	}
+
+	schedule, err := server.store.UpdateScheduledTransfer(ctx, db.UpdateScheduledTransferParams{
+		ID:      uri.ID,
+		Amount:  pgtype.Int8{Int64: req.Amount, Valid: req.Amount > 0},
+		EndAt:   pgtype.Timestamptz{Time: req.EndAt, Valid: !req.EndAt.IsZero()},
+		MaxRuns: pgtype.Int4{Int32: req.MaxRuns, Valid: req.MaxRuns > 0},
+		Status:  pgtype.Text{String: req.Status, Valid: req.Status != ""},
+	})
+	if err != nil {
+		scheduledTransferErrorResponse(ctx, uri.ID, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedule)
+}
+
+// cancelScheduledTransfer stops a scheduled transfer for good. Its runs are kept.
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	var req getScheduledTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, valid := server.authorizedScheduledTransfer(ctx, req.ID, false); !valid {
+		return
+	}
+
+	schedule, err := server.store.CancelScheduledTransfer(ctx, req.ID)
+	if err != nil {
+		scheduledTransferErrorResponse(ctx, req.ID, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedule)
+}
+
+// listScheduledTransferRuns lists the outcome of every run of a scheduled transfer, newest first.
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var uri getScheduledTransferRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listScheduledTransferRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	cursor, err := decodeCursor(req.Cursor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, valid := server.authorizedScheduledTransfer(ctx, uri.ID, true); !valid {
+		return
+	}
+
+	createdAt, id := cursor.position(true)
+	arg := db.ListScheduledTransferRunsBeforeParams{
+		ScheduledTransferID: uri.ID,
+		CursorCreatedAt:     createdAt,
+		CursorID:            id,
+		PageLimit:           req.PageSize + 1,
+	}
+
+	var runs []db.ScheduledTransferRun
+	if cursor.backward() {
+		runs, err = server.store.ListScheduledTransferRunsAfter(ctx, db.ListScheduledTransferRunsAfterParams(arg))
+	} else {
+		runs, err = server.store.ListScheduledTransferRunsBefore(ctx, arg)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(runs, req.PageSize, cursor, func(run db.ScheduledTransferRun) (pgtype.Timestamptz, int64) {
+		return run.CreatedAt, run.ID
+	}))
+}
+
+// authorizedScheduledTransfer loads the scheduled transfer with given ID if the authenticated user owns it.
+// With readOnly, bankers and admins may load any scheduled transfer too.
+func (server *Server) authorizedScheduledTransfer(ctx *gin.Context, id int64, readOnly bool) (db.ScheduledTransfer, bool) {
+	schedule, err := server.store.GetScheduledTransfer(ctx, id)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return schedule, false
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return schedule, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Username != schedule.Owner && !(readOnly && hasScope(authPayload, scopeAccountsReadAny)) {
+		err := errors.New("scheduled transfer does not belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return schedule, false
+	}
+
+	return schedule, true
+}
+
+// scheduledTransferErrorResponse writes the response for an error changing a scheduled transfer.
+// Changes only apply to active and paused schedules, so a missing row means it was completed or cancelled.
+func scheduledTransferErrorResponse(ctx *gin.Context, id int64, err error) {
+	if errors.Is(err, db.ErrRecordNotFound) {
+		err := fmt.Errorf("scheduled transfer %d is completed or cancelled", id)
+		ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeScheduledTransferClosed, err))
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+}
@@ -0,0 +1,566 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+func randomScheduledTransfer(owner string, fromAccountID, toAccountID int64) db.ScheduledTransfer {
+	startAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	return db.ScheduledTransfer{
+		ID:            int64(gofakeit.Number(1, 1000)),
+		Owner:         owner,
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        int64(gofakeit.Number(1, 1000)),
+		Frequency:     util.MonthlyFrequency,
+		IntervalCount: 1,
+		StartAt:       pgtype.Timestamptz{Time: startAt, Valid: true},
+		NextRunAt:     pgtype.Timestamptz{Time: startAt, Valid: true},
+		Status:        db.ScheduleActive,
+	}
+}
+
+func TestCreateScheduledTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccountForUser(user1.Username)
+	account2 := randomAccountForUser(user2.Username)
+	account3 := randomAccountForUser(user2.Username)
+	account1.ID, account2.ID, account3.ID = 1, 2, 3
+	account3.Currency = util.EUR
+
+	startAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	endAt := startAt.AddDate(1, 0, 0)
+
+	testCases := []struct {
+		name          string
+		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        util.USD,
+				"frequency":       util.MonthlyFrequency,
+				"start_at":        startAt,
+				"end_at":          endAt,
+				"max_runs":        12,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						want := db.CreateScheduledTransferParams{
+							Owner:         user1.Username,
+							FromAccountID: account1.ID,
+							ToAccountID:   account2.ID,
+							Amount:        100,
+							Frequency:     util.MonthlyFrequency,
+							IntervalCount: 1,
+							StartAt:       pgtype.Timestamptz{Time: startAt, Valid: true},
+							EndAt:         pgtype.Timestamptz{Time: endAt, Valid: true},
+							MaxRuns:       pgtype.Int4{Int32: 12, Valid: true},
+						}
+						if !arg.StartAt.Time.Equal(startAt) || !arg.EndAt.Time.Equal(endAt) {
+							t.Errorf("expected schedule from %v to %v, got %v to %v", startAt, endAt, arg.StartAt.Time, arg.EndAt.Time)
+						}
+						arg.StartAt.Time, arg.EndAt.Time = startAt, endAt
+						if arg != want {
+							t.Errorf("expected params %+v, got %+v", want, arg)
+						}
+						return db.ScheduledTransfer{ID: 1, Owner: arg.Owner, Status: db.ScheduleActive}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "StartInThePast",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        util.USD,
+				"frequency":       util.OnceFrequency,
+				"start_at":        time.Now().Add(-time.Hour),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "EndBeforeStart",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        util.USD,
+				"frequency":       util.DailyFrequency,
+				"start_at":        startAt,
+				"end_at":          startAt.Add(-time.Minute),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "InvalidFrequency",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        util.USD,
+				"frequency":       "yearly",
+				"start_at":        startAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        util.USD,
+				"frequency":       util.WeeklyFrequency,
+				"start_at":        startAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "ToAccountCurrencyMismatch",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account3.ID,
+				"amount":          100,
+				"currency":        util.USD,
+				"frequency":       util.WeeklyFrequency,
+				"start_at":        startAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			if err != nil {
+				t.Fatalf("failed to marshal body: %v", err)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/scheduled_transfers", bytes.NewReader(data))
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestManageScheduledTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	schedule := randomScheduledTransfer(user.Username, 1, 2)
+	paused := schedule
+	paused.Status = db.SchedulePaused
+	cancelled := schedule
+	cancelled.Status = db.ScheduleCancelled
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Get",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got db.ScheduledTransfer
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if got.ID != schedule.ID || got.Owner != schedule.Owner || got.Amount != schedule.Amount {
+					t.Errorf("expected scheduled transfer %+v, got %+v", schedule, got)
+				}
+			},
+		},
+		{
+			name:   "GetNotFound",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(db.ScheduledTransfer{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "GetOtherUser",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "other_user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "BankerGetsAny",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, "banker_user", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "List",
+			method: http.MethodGet,
+			path:   "/scheduled_transfers?page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				createdAt, id := pageCursor{}.position(false)
+				store.EXPECT().
+					ListScheduledTransfersAfter(gomock.Any(), gomock.Eq(db.ListScheduledTransfersAfterParams{
+						Owner:           user.Username,
+						CursorCreatedAt: createdAt,
+						CursorID:        id,
+						PageLimit:       6,
+					})).
+					Times(1).
+					Return([]db.ScheduledTransfer{schedule}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got pageResponse[db.ScheduledTransfer]
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(got.Items) != 1 || got.Items[0].ID != schedule.ID {
+					t.Errorf("expected scheduled transfer %d, got %+v", schedule.ID, got.Items)
+				}
+			},
+		},
+		{
+			name:   "Pause",
+			method: http.MethodPatch,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			body:   map[string]any{"status": db.SchedulePaused, "amount": 250},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil)
+				store.EXPECT().
+					UpdateScheduledTransfer(gomock.Any(), gomock.Eq(db.UpdateScheduledTransferParams{
+						ID:     schedule.ID,
+						Amount: pgtype.Int8{Int64: 250, Valid: true},
+						Status: pgtype.Text{String: db.SchedulePaused, Valid: true},
+					})).
+					Times(1).
+					Return(paused, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "UpdateInvalidStatus",
+			method: http.MethodPatch,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			body:   map[string]any{"status": db.ScheduleCompleted},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "UpdateEndBeforeStart",
+			method: http.MethodPatch,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			body:   map[string]any{"end_at": schedule.StartAt.Time.Add(-time.Minute)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "UpdateOtherUser",
+			method: http.MethodPatch,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			body:   map[string]any{"status": db.SchedulePaused},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, "admin_user", util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "UpdateClosed",
+			method: http.MethodPatch,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			body:   map[string]any{"status": db.ScheduleActive},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(cancelled, nil)
+				store.EXPECT().
+					UpdateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeScheduledTransferClosed)
+			},
+		},
+		{
+			name:   "Cancel",
+			method: http.MethodDelete,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(cancelled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "CancelTwice",
+			method: http.MethodDelete,
+			path:   fmt.Sprintf("/scheduled_transfers/%d", schedule.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(cancelled, nil)
+				store.EXPECT().
+					CancelScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).
+					Times(1).
+					Return(db.ScheduledTransfer{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:   "ListRuns",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/scheduled_transfers/%d/runs?page_size=5", schedule.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil)
+				createdAt, id := pageCursor{}.position(true)
+				store.EXPECT().
+					ListScheduledTransferRunsBefore(gomock.Any(), gomock.Eq(db.ListScheduledTransferRunsBeforeParams{
+						ScheduledTransferID: schedule.ID,
+						CursorCreatedAt:     createdAt,
+						CursorID:            id,
+						PageLimit:           6,
+					})).
+					Times(1).
+					Return([]db.ScheduledTransferRun{
+						{ID: 2, ScheduledTransferID: schedule.ID, Status: db.RunFailed, Error: pgtype.Text{String: "insufficient funds", Valid: true}},
+						{ID: 1, ScheduledTransferID: schedule.ID, Status: db.RunSucceeded, TransferID: pgtype.Int8{Int64: 9, Valid: true}},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got pageResponse[db.ScheduledTransferRun]
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(got.Items) != 2 || got.Items[0].Status != db.RunFailed || got.Items[1].TransferID.Int64 != 9 {
+					t.Errorf("unexpected runs %+v", got.Items)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				if err := json.NewEncoder(&body).Encode(tc.body); err != nil {
+					t.Fatalf("failed to marshal body: %v", err)
+				}
+			}
+
+			request := httptest.NewRequest(tc.method, tc.path, &body)
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
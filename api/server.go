@@ -1,9 +1,15 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
 
 	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/fx"
+	"github.com/WilliamOdinson/simplebank/mail"
 	"github.com/WilliamOdinson/simplebank/token"
 	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/gin-gonic/gin"
@@ -13,30 +19,61 @@ import (
 
 // Server serves HTTP requests for our banking service.
 type Server struct {
-	config     util.Config
-	store      db.Store
-	router     *gin.Engine
-	tokenMaker token.Maker
+	config        util.Config
+	store         db.Store
+	router        *gin.Engine
+	httpServer    *http.Server
+	tokenMaker    token.Maker
+	jwks          token.JWKSet
+	revocations   *revocationList
+	loginThrottle *loginThrottle
+	// resetEmailThrottle and resetIPThrottle count password reset requests by email and by client IP
+	resetEmailThrottle *loginThrottle
+	resetIPThrottle    *loginThrottle
+	fxProvider         fx.Provider
+	mailer             mail.Mailer
+	// background tracks the tasks started by runInBackground
+	background sync.WaitGroup
 }
 
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, err := token.NewMaker(config)
 	if err != nil {
 		panic(fmt.Errorf("Cannot create token maker: %w", err))
 	}
 
+	fxProvider, err := fx.NewProvider(config, store)
+	if err != nil {
+		panic(fmt.Errorf("Cannot create fx rate provider: %w", err))
+	}
+
+	mailer, err := mail.NewMailer(config)
+	if err != nil {
+		panic(fmt.Errorf("Cannot create mailer: %w", err))
+	}
+
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:             config,
+		store:              store,
+		tokenMaker:         tokenMaker,
+		jwks:               tokenMaker.JWKS(),
+		revocations:        newRevocationList(),
+		loginThrottle:      newLoginThrottle(config.LoginIPMaxAttempts, config.LoginLockoutDuration),
+		resetEmailThrottle: newLoginThrottle(config.PasswordResetMaxRequests, config.PasswordResetDuration),
+		resetIPThrottle:    newLoginThrottle(config.PasswordResetMaxRequests, config.PasswordResetDuration),
+		fxProvider:         fxProvider,
+		mailer:             mailer,
 	}
 
 	// Register custom validation functions
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrencies)
+		v.RegisterValidation("role", validRoles)
+		v.RegisterValidation("frequency", validFrequencies)
 	}
 
 	server.setupRouter()
+	server.httpServer = &http.Server{Handler: server.router}
 
 	return server, nil
 }
@@ -47,13 +84,49 @@ func (server *Server) setupRouter() {
 	// Define routes
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
+	router.POST("/users/login/mfa", server.loginMFA)
+	router.POST("/users/password/forgot", server.forgotPassword)
+	router.POST("/users/password/reset", server.resetPassword)
+	router.GET("/users/verify_email", server.verifyEmail)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
+	if len(server.jwks.Keys) > 0 {
+		router.GET("/.well-known/jwks.json", server.getJWKS)
+	}
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.revocations))
+	// with REQUIRE_VERIFIED_EMAIL set, every route that moves money needs a verified email
+	verifiedEmail := requireVerifiedEmail(server.store, server.config.RequireVerifiedEmail)
 
-	authRoutes.POST("/accounts", server.createAccount)
-	authRoutes.GET("/accounts/:id", server.getAccount)
-	authRoutes.GET("/accounts", server.listAccounts)
-	authRoutes.POST("/transfers", server.createTransfer)
+	authRoutes.POST("/users/logout", server.logoutUser)
+	authRoutes.POST("/users/logout_all", server.logoutAllUser)
+	authRoutes.PATCH("/users/password", server.changePassword)
+	authRoutes.POST("/users/verify_email/resend", server.resendVerificationEmail)
+	authRoutes.POST("/users/mfa/totp", server.enrollTOTP)
+	authRoutes.POST("/users/mfa/totp/confirm", server.confirmTOTP)
+	authRoutes.PATCH("/users/:username/role", requireRole(util.AdminRole), server.updateUserRole)
+	authRoutes.POST("/users/:username/unlock", requireRole(util.AdminRole), server.unlockUser)
+	authRoutes.POST("/accounts", requireScope(scopeAccountsWrite), verifiedEmail, server.createAccount)
+	authRoutes.GET("/accounts/:id", requireScope(scopeAccountsRead), server.getAccount)
+	authRoutes.GET("/accounts", requireScope(scopeAccountsRead), server.listAccounts)
+	authRoutes.GET("/accounts/:id/entries", requireScope(scopeAccountsRead), server.listAccountEntries)
+	authRoutes.GET("/accounts/:id/transfers", requireScope(scopeAccountsRead), server.listAccountTransfers)
+	authRoutes.GET("/accounts/:id/limits", requireScope(scopeAccountsRead), server.getAccountLimits)
+	authRoutes.PUT("/accounts/:id/limits", requireScope(scopeLimitsWrite), server.setAccountLimits)
+	authRoutes.POST("/transfers", requireScope(scopeTransfersWrite), verifiedEmail, server.createTransfer)
+	authRoutes.POST("/transfers/batch", requireScope(scopeTransfersWrite), verifiedEmail, server.createBatchTransfer)
+	authRoutes.GET("/transfers/:id", requireScope(scopeAccountsRead), server.getTransfer)
+	authRoutes.POST("/transfers/:id/reverse", requireScope(scopeTransfersWrite), verifiedEmail, server.reverseTransfer)
+	authRoutes.POST("/holds", requireScope(scopeTransfersWrite), verifiedEmail, server.createHold)
+	authRoutes.GET("/holds/:id", requireScope(scopeAccountsRead), server.getHold)
+	authRoutes.POST("/holds/:id/capture", requireScope(scopeTransfersWrite), verifiedEmail, server.captureHold)
+	authRoutes.POST("/holds/:id/void", requireScope(scopeTransfersWrite), server.voidHold)
+	authRoutes.POST("/fx/quotes", requireScope(scopeTransfersWrite), server.createFXQuote)
+	authRoutes.POST("/scheduled_transfers", requireScope(scopeTransfersWrite), verifiedEmail, server.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers", requireScope(scopeAccountsRead), server.listScheduledTransfers)
+	authRoutes.GET("/scheduled_transfers/:id", requireScope(scopeAccountsRead), server.getScheduledTransfer)
+	authRoutes.PATCH("/scheduled_transfers/:id", requireScope(scopeTransfersWrite), verifiedEmail, server.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled_transfers/:id", requireScope(scopeTransfersWrite), server.cancelScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id/runs", requireScope(scopeAccountsRead), server.listScheduledTransferRuns)
 
 	server.router = router
 }
@@ -62,7 +135,70 @@ func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
 
-// Start runs the HTTP server on a specific address.
+// Machine-readable error codes for failures clients are expected to handle
+const (
+	errCodeInsufficientFunds       = "insufficient_funds"
+	errCodeIdempotencyKeyReused    = "idempotency_key_reused"
+	errCodeFXRateUnavailable       = "fx_rate_unavailable"
+	errCodeFXQuoteExpired          = "fx_quote_expired"
+	errCodeFXQuoteUsed             = "fx_quote_used"
+	errCodeFXQuoteMismatch         = "fx_quote_mismatch"
+	errCodeAmountOutOfRange        = "amount_out_of_range"
+	errCodeScheduledTransferClosed = "scheduled_transfer_closed"
+	errCodeInvalidReversalAmount   = "invalid_reversal_amount"
+	errCodeReversalOfReversal      = "reversal_of_reversal"
+	errCodeHoldClosed              = "hold_closed"
+	errCodeHoldExpired             = "hold_expired"
+	errCodeInvalidCaptureAmount    = "invalid_capture_amount"
+	errCodeRecipientNotFound       = "recipient_not_found"
+	errCodeTransferLimitExceeded   = "transfer_limit_exceeded"
+	errCodeInvalidResetCode        = "invalid_reset_code"
+	errCodeResetCodeExpired        = "reset_code_expired"
+	errCodeTooManyResets           = "too_many_password_resets"
+	errCodeInvalidVerificationCode = "invalid_verification_code"
+	errCodeVerificationCodeExpired = "verification_code_expired"
+	errCodeEmailNotVerified        = "email_not_verified"
+	errCodeEmailAlreadyVerified    = "email_already_verified"
+	errCodeMFAAlreadyEnabled       = "mfa_already_enabled"
+	errCodeMFANotPending           = "mfa_not_pending"
+	errCodeInvalidMFACode          = "invalid_mfa_code"
+	errCodeMFARequired             = "mfa_required"
+	errCodeIncorrectCredentials    = "incorrect_credentials"
+	errCodeLoginLocked             = "login_locked"
+)
+
+// errorCodeResponse adds a machine-readable code to the error body
+func errorCodeResponse(code string, err error) gin.H {
+	return gin.H{"error": err.Error(), "code": code}
+}
+
+// Start runs the HTTP server on a specific address until Shutdown is called.
 func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+	server.httpServer.Addr = address
+	err := server.httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops the HTTP server, then waits for the requests in flight and the tasks started
+// by runInBackground to finish, so no email is lost on a restart. It gives up once ctx is done.
+func (server *Server) Shutdown(ctx context.Context) error {
+	if err := server.httpServer.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildBody     func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string)
+		buildStubs    func(store *mockdb.MockStore, payload *token.Payload, refreshToken string)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		{
+			name: "OK",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(randomSession(payload, refreshToken), nil)
+				// the user was promoted after the refresh token was issued
+				promoted := user
+				promoted.Role = util.BankerRole
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(promoted, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+				var resp renewAccessTokenResponse
+				if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				payload, err := tokenMaker.VerifyToken(resp.AccessToken)
+				if err != nil {
+					t.Fatalf("expected a valid access token: %v", err)
+				}
+				if payload.Role != util.BankerRole {
+					t.Errorf("expected the current role %s, got %s", util.BankerRole, payload.Role)
+				}
+			},
+		},
+		{
+			name: "GetUserError",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(randomSession(payload, refreshToken), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, fmt.Errorf("internal error"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "MissingRefreshToken",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return map[string]any{}, nil, ""
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			// an access token cannot renew itself
+			name: "AccessToken",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				accessToken, payload, err := tokenMaker.CreateToken(user.Username, util.DepositorRole, token.AccessToken, time.Hour)
+				if err != nil {
+					t.Fatalf("cannot create token: %v", err)
+				}
+				return map[string]any{"refresh_token": accessToken}, payload, accessToken
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "InvalidRefreshToken",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return map[string]any{"refresh_token": gofakeit.LetterN(50)}, nil, ""
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "ExpiredRefreshToken",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return createRefreshToken(t, tokenMaker, user.Username, -time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "InternalError",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{}, fmt.Errorf("internal error"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				session := randomSession(payload, refreshToken)
+				session.IsBlocked = true
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "UsernameMismatch",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				session := randomSession(payload, refreshToken)
+				session.Username = gofakeit.LetterN(10)
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "RefreshTokenMismatch",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(randomSession(payload, gofakeit.LetterN(50)), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (map[string]any, *token.Payload, string) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, refreshToken string) {
+				session := randomSession(payload, refreshToken)
+				session.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, payload, refreshToken := tc.buildBody(t, server.tokenMaker)
+			tc.buildStubs(store, payload, refreshToken)
+
+			data, _ := json.Marshal(body)
+			request := httptest.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server.tokenMaker)
+		})
+	}
+}
+
+func createRefreshToken(t *testing.T, tokenMaker token.Maker, username string, duration time.Duration) (map[string]any, *token.Payload, string) {
+	refreshToken, payload, err := tokenMaker.CreateToken(username, util.DepositorRole, token.RefreshToken, duration)
+	if err != nil {
+		t.Fatalf("cannot create refresh token: %v", err)
+	}
+
+	return map[string]any{"refresh_token": refreshToken}, payload, refreshToken
+}
+
+func randomSession(payload *token.Payload, refreshToken string) db.Session {
+	return db.Session{
+		ID:           payload.ID,
+		Username:     payload.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    gofakeit.UserAgent(),
+		ClientIp:     gofakeit.IPv4Address(),
+		IsBlocked:    false,
+		ExpiresAt:    pgtype.Timestamptz{Time: payload.ExpiredAt, Valid: true},
+	}
+}
@@ -1,20 +1,98 @@
 package api
 
 import (
-	"database/sql"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	db "github.com/WilliamOdinson/simplebank/db/sqlc"
 	"github.com/WilliamOdinson/simplebank/token"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// transferRequest moves Amount in Currency between two accounts in that currency.
+// With a QuoteID from POST /fx/quotes, Currency is the source account's currency and the
+// destination account is credited the quoted amount in the quote's target currency.
+// Instead of ToAccountID, the recipient can be named by ToUsername or ToEmail, and is paid
+// into their account in the destination currency.
 type transferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required,currency"`
+	FromAccountID int64     `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64     `json:"to_account_id" binding:"required_without_all=ToUsername ToEmail,omitempty,min=1,nefield=FromAccountID"`
+	ToUsername    string    `json:"to_username,omitempty" binding:"omitempty,alphanum,excluded_with=ToAccountID ToEmail"`
+	ToEmail       string    `json:"to_email,omitempty" binding:"omitempty,email,excluded_with=ToAccountID ToUsername"`
+	Amount        int64     `json:"amount" binding:"required,gt=0"`
+	Currency      string    `json:"currency" binding:"required,currency"`
+	QuoteID       uuid.UUID `json:"quote_id"`
+	// MFACode is the TOTP code transfers above the MFA_STEP_UP_AMOUNT of their currency need
+	MFACode string `json:"mfa_code,omitempty" binding:"omitempty,numeric,len=6"`
+	transferDetails
+}
+
+// transferDetails optionally describe what a transfer is for. They are stored on the
+// transfer and both of its entries, and the reference can be searched for in the history.
+type transferDetails struct {
+	Description string            `json:"description,omitempty" binding:"omitempty,max=255"`
+	Reference   string            `json:"reference,omitempty" binding:"omitempty,max=64"`
+	Metadata    map[string]string `json:"metadata,omitempty" binding:"omitempty,max=20,dive,keys,min=1,max=40,endkeys,max=500"`
+}
+
+// transferTxParams returns the parameters of a transfer carrying these details
+func (details transferDetails) transferTxParams(fromAccountID, toAccountID, amount int64) db.TransferTxParams {
+	arg := db.TransferTxParams{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+		Description:   pgtype.Text{String: details.Description, Valid: details.Description != ""},
+		Reference:     pgtype.Text{String: details.Reference, Valid: details.Reference != ""},
+	}
+	if len(details.Metadata) > 0 {
+		// a map of strings always encodes
+		arg.Metadata, _ = json.Marshal(details.Metadata)
+	}
+	return arg
+}
+
+// recipientTransferResponse is the result of a transfer to a user named by username or email.
+// It leaves out the recipient's account and entry, since the payer has no business knowing their balance.
+type recipientTransferResponse struct {
+	Transfer    db.Transfer `json:"transfer"`
+	FromAccount db.Account  `json:"from_account"`
+	FromEntry   db.Entry    `json:"from_entry"`
+}
+
+// transferResponse returns what a transfer made for req responds with
+func transferResponse(req transferRequest, result db.TransferTxResult) any {
+	if req.ToAccountID != 0 {
+		return result
+	}
+	return recipientTransferResponse{
+		Transfer:    result.Transfer,
+		FromAccount: result.FromAccount,
+		FromEntry:   result.FromEntry,
+	}
+}
+
+// to get transfer by id from the URI
+type getTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// reverseTransferRequest refunds Amount of a transfer in its source account's currency.
+// Without an amount, whatever has not been reversed yet is refunded.
+type reverseTransferRequest struct {
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
@@ -24,6 +102,13 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
+	idempotencyKey := ctx.GetHeader(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		err := fmt.Errorf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
 	// Check if from and to account is valid
@@ -40,31 +125,360 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
-	if _, valid := server.validAccount(ctx, req.ToAccountID, req.Currency); !valid {
-		err := fmt.Errorf("to account %d is not valid", req.ToAccountID)
+	// a retry of a transfer that went through is answered before step-up, so it needs no new TOTP code
+	if idempotencyKey != "" && server.replayedTransfer(ctx, authPayload.Username, idempotencyKey, req) {
+		return
+	}
+
+	if !server.stepUpVerified(ctx, authPayload.Username, map[string]int64{req.Currency: req.Amount}, req.MFACode) {
+		return
+	}
+
+	toCurrency := req.Currency
+	if req.QuoteID != uuid.Nil {
+		quote, valid := server.validQuote(ctx, req.QuoteID, authPayload.Username, req.Currency)
+		if !valid {
+			return
+		}
+		toCurrency = quote.ToCurrency
+	}
+
+	toAccountID := req.ToAccountID
+	if toAccountID == 0 {
+		toAccount, valid := server.recipientAccount(ctx, req.ToUsername, req.ToEmail, toCurrency)
+		if !valid {
+			return
+		}
+		if toAccount.ID == req.FromAccountID {
+			err := errors.New("cannot transfer to the source account")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		toAccountID = toAccount.ID
+	} else if _, valid := server.validAccount(ctx, toAccountID, toCurrency); !valid {
+		err := fmt.Errorf("to account %d is not valid", toAccountID)
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
+	arg := req.transferTxParams(req.FromAccountID, toAccountID, req.Amount)
+
+	if idempotencyKey != "" {
+		result, err := server.store.IdempotentTransferTx(ctx, db.IdempotentTransferTxParams{
+			TransferTxParams: arg,
+			Username:         authPayload.Username,
+			IdempotencyKey:   idempotencyKey,
+			RequestHash:      hashTransferRequest(req),
+			QuoteID:          req.QuoteID,
+		})
+		if err != nil {
+			transferErrorResponse(ctx, err)
+			return
+		}
+
+		if result.Replayed {
+			ctx.Header(idempotentReplayedHeader, "true")
+		}
+		ctx.JSON(http.StatusOK, transferResponse(req, result.TransferTxResult))
+		return
 	}
 
-	result, err := server.store.TransferTx(ctx, arg)
+	var result db.TransferTxResult
+	var err error
+	if req.QuoteID != uuid.Nil {
+		result, err = server.store.FXTransferTx(ctx, db.FXTransferTxParams{
+			TransferTxParams: arg,
+			Username:         authPayload.Username,
+			QuoteID:          req.QuoteID,
+		})
+	} else {
+		result, err = server.store.TransferTx(ctx, arg)
+	}
 	if err != nil {
+		transferErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transferResponse(req, result))
+}
+
+func (server *Server) getTransfer(ctx *gin.Context) {
+	var req getTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, req.ID)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Bankers and admins may read any transfer, everyone else only those touching their own accounts
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !hasScope(authPayload, scopeAccountsReadAny) {
+		owner, err := server.ownsAnyAccount(ctx, authPayload.Username, transfer.FromAccountID, transfer.ToAccountID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !owner {
+			err := errors.New("transfer does not belong to the authenticated user")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// reverseTransfer refunds all or part of a transfer to its source account. Only the owner of the
+// destination account, who gives the money back, or an admin may reverse a transfer.
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var uri getTransferRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// the body is optional
+	var req reverseTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, uri.ID)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	} else if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !hasScope(authPayload, scopeTransfersReverseAny) {
+		owner, err := server.ownsAnyAccount(ctx, authPayload.Username, transfer.ToAccountID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !owner {
+			err := errors.New("transfer was not made to an account of the authenticated user")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+	}
+
+	result, err := server.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		Amount:     req.Amount,
+	})
+	if err != nil {
+		transferErrorResponse(ctx, err)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, result)
 }
 
+// ownsAnyAccount reports whether the user owns one of the accounts with given IDs.
+func (server *Server) ownsAnyAccount(ctx *gin.Context, username string, accountIDs ...int64) (bool, error) {
+	for _, accountID := range accountIDs {
+		account, err := server.store.GetAccount(ctx, accountID)
+		if err != nil {
+			return false, err
+		}
+		if account.Owner == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// listAccountTransfers lists the transfers into and out of an account, newest first.
+func (server *Server) listAccountTransfers(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listHistoryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	cursor, err := decodeCursor(req.Cursor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, valid := server.readableAccount(ctx, uri.ID); !valid {
+		return
+	}
+
+	createdAt, id := cursor.position(true)
+	arg := db.ListAccountTransfersBeforeParams{
+		AccountID:       uri.ID,
+		Direction:       req.direction(),
+		CursorCreatedAt: createdAt,
+		CursorID:        id,
+		StartTime:       req.startTime(),
+		EndTime:         req.endTime(),
+		MinAmount:       req.minAmount(),
+		MaxAmount:       req.maxAmount(),
+		Reference:       req.reference(),
+		PageLimit:       req.PageSize + 1,
+	}
+
+	var transfers []db.Transfer
+	if cursor.backward() {
+		transfers, err = server.store.ListAccountTransfersAfter(ctx, db.ListAccountTransfersAfterParams(arg))
+	} else {
+		transfers, err = server.store.ListAccountTransfersBefore(ctx, arg)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(transfers, req.PageSize, cursor, func(transfer db.Transfer) (pgtype.Timestamptz, int64) {
+		return transfer.CreatedAt, transfer.ID
+	}))
+}
+
+// transferErrorResponse writes the response for an error returned by a transfer transaction
+func transferErrorResponse(ctx *gin.Context, err error) {
+	ctx.JSON(transferError(err))
+}
+
+// transferError returns the status code and body of the response for an error returned by a transfer transaction
+func transferError(err error) (int, gin.H) {
+	var fundsErr *db.InsufficientFundsError
+	var limitErr *db.LimitExceededError
+	switch {
+	case errors.As(err, &fundsErr):
+		resp := errorCodeResponse(errCodeInsufficientFunds, err)
+		resp["available_balance"] = fundsErr.Available
+		resp["requested_amount"] = fundsErr.Requested
+		return http.StatusUnprocessableEntity, resp
+	case errors.As(err, &limitErr):
+		resp := errorCodeResponse(errCodeTransferLimitExceeded, err)
+		resp["limit"] = limitErr.Limit
+		resp["limit_amount"] = limitErr.Max
+		resp["used_amount"] = limitErr.Used
+		resp["requested_amount"] = limitErr.Requested
+		return http.StatusUnprocessableEntity, resp
+	case errors.Is(err, db.ErrIdempotencyKeyReused):
+		return http.StatusConflict, errorCodeResponse(errCodeIdempotencyKeyReused, err)
+	case errors.Is(err, db.ErrQuoteExpired):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeFXQuoteExpired, err)
+	case errors.Is(err, db.ErrQuoteUsed):
+		return http.StatusConflict, errorCodeResponse(errCodeFXQuoteUsed, err)
+	case errors.Is(err, db.ErrQuoteMismatch):
+		return http.StatusBadRequest, errorCodeResponse(errCodeFXQuoteMismatch, err)
+	case errors.Is(err, db.ErrAmountOutOfRange):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeAmountOutOfRange, err)
+	case errors.Is(err, db.ErrInvalidReversalAmount):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeInvalidReversalAmount, err)
+	case errors.Is(err, db.ErrReversalOfReversal):
+		return http.StatusConflict, errorCodeResponse(errCodeReversalOfReversal, err)
+	case errors.Is(err, db.ErrHoldClosed):
+		return http.StatusConflict, errorCodeResponse(errCodeHoldClosed, err)
+	case errors.Is(err, db.ErrHoldExpired):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeHoldExpired, err)
+	case errors.Is(err, db.ErrInvalidCaptureAmount):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeInvalidCaptureAmount, err)
+	case errors.Is(err, db.ErrRecordNotFound):
+		return http.StatusNotFound, errorResponse(err)
+	default:
+		return http.StatusInternalServerError, errorResponse(err)
+	}
+}
+
+// replayedTransfer answers a request with the result stored for its idempotency key, if a transfer
+// with that key was already made, and returns whether it did. A key stored for another request is
+// answered with 409. Transfers still in flight are not stored yet, so IdempotentTransferTx replays those.
+func (server *Server) replayedTransfer(ctx *gin.Context, username string, idempotencyKey string, req transferRequest) bool {
+	stored, err := server.store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username: username,
+		Key:      idempotencyKey,
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return false
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	if stored.RequestHash != hashTransferRequest(req) {
+		transferErrorResponse(ctx, db.ErrIdempotencyKeyReused)
+		return true
+	}
+
+	var result db.TransferTxResult
+	if err := json.Unmarshal(stored.Response, &result); err != nil {
+		err = fmt.Errorf("failed to decode stored transfer result: %w", err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	ctx.Header(idempotentReplayedHeader, "true")
+	ctx.JSON(http.StatusOK, transferResponse(req, result))
+	return true
+}
+
+// hashTransferRequest identifies a transfer request so a replayed idempotency key can be
+// checked against the body it was first used with
+func hashTransferRequest(req transferRequest) string {
+	// a retry carries a new TOTP code for the same transfer
+	req.MFACode = ""
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// recipientAccount looks up the account in currency of the user with given username, or else email.
+// A user who does not exist and one without such an account get the same response, so that
+// transfers cannot be used to find out whether an email address banks with us.
+func (server *Server) recipientAccount(ctx *gin.Context, username string, email string, currency string) (db.Account, bool) {
+	var account db.Account
+	var err error
+	if username != "" {
+		account, err = server.store.GetAccountByOwner(ctx, db.GetAccountByOwnerParams{
+			Owner:    username,
+			Currency: currency,
+		})
+	} else {
+		account, err = server.store.GetAccountByOwnerEmail(ctx, db.GetAccountByOwnerEmailParams{
+			Email:    email,
+			Currency: currency,
+		})
+	}
+	if errors.Is(err, db.ErrRecordNotFound) {
+		err := fmt.Errorf("recipient cannot receive %s transfers", currency)
+		ctx.JSON(http.StatusNotFound, errorCodeResponse(errCodeRecipientNotFound, err))
+		return account, false
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return account, false
+	}
+
+	return account, true
+}
+
 // validAccount checks if the account with given ID exists and if its currency matches the provided one.
 func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountID)
-	if err == sql.ErrNoRows {
+	if errors.Is(err, db.ErrRecordNotFound) {
 		ctx.JSON(http.StatusNotFound, errorResponse(err))
 		return account, false
 	} else if err != nil {
@@ -2,17 +2,22 @@ package api
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
 	db "github.com/WilliamOdinson/simplebank/db/sqlc"
 	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/mock/gomock"
 )
 
@@ -42,6 +47,11 @@ func TestCreateTransferAPI(t *testing.T) {
 		Currency: "EUR",
 	}
 
+	tooManyMetadataKeys := make(map[string]string)
+	for i := range 21 {
+		tooManyMetadataKeys[fmt.Sprintf("key%d", i)] = "value"
+	}
+
 	testCases := []struct {
 		name          string
 		body          map[string]any
@@ -85,55 +95,90 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "NoAuthorization",
+			name: "WithDetails",
 			body: map[string]any{
 				"from_account_id": account1.ID,
 				"to_account_id":   account2.ID,
 				"amount":          amount,
 				"currency":        "USD",
+				"description":     "March rent",
+				"reference":       "INV-2026-03",
+				"metadata":        map[string]string{"flat": "4B", "tenant": "Ana"},
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				// Don't add authorization
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Any()).
-					Times(0)
+					TransferTx(gomock.Any(), db.TransferTxParams{
+						FromAccountID: account1.ID,
+						ToAccountID:   account2.ID,
+						Amount:        amount,
+						Description:   pgtype.Text{String: "March rent", Valid: true},
+						Reference:     pgtype.Text{String: "INV-2026-03", Valid: true},
+						Metadata:      json.RawMessage(`{"flat":"4B","tenant":"Ana"}`),
+					}).
+					Times(1).
+					Return(db.TransferTxResult{}, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusUnauthorized {
-					t.Errorf("expected status code 401, got %d", recorder.Code)
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "UnauthorizedUser",
+			name: "ToUsername",
 			body: map[string]any{
 				"from_account_id": account1.ID,
-				"to_account_id":   account2.ID,
+				"to_username":     user2.Username,
 				"amount":          amount,
 				"currency":        "USD",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					GetAccountByOwner(gomock.Any(), gomock.Eq(db.GetAccountByOwnerParams{Owner: user2.Username, Currency: "USD"})).
 					Times(1).
-					Return(account1, nil)
+					Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), db.TransferTxParams{
+						FromAccountID: account1.ID,
+						ToAccountID:   account2.ID,
+						Amount:        amount,
+					}).
+					Times(1).
+					Return(db.TransferTxResult{FromAccount: account1, ToAccount: account2}, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusUnauthorized {
-					t.Errorf("expected status code 401, got %d", recorder.Code)
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+				// the payer does not get to see the recipient's account
+				var resp map[string]json.RawMessage
+				if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				for _, field := range []string{"to_account", "to_entry"} {
+					if _, ok := resp[field]; ok {
+						t.Errorf("expected no %s in the response", field)
+					}
+				}
+				if _, ok := resp["from_account"]; !ok {
+					t.Error("expected from_account in the response")
 				}
 			},
 		},
 		{
-			name: "FromAccountNotFound",
+			name: "ToEmail",
 			body: map[string]any{
 				"from_account_id": account1.ID,
-				"to_account_id":   account2.ID,
+				"to_email":        user2.Email,
 				"amount":          amount,
 				"currency":        "USD",
 			},
@@ -141,22 +186,31 @@ func TestCreateTransferAPI(t *testing.T) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					GetAccountByOwnerEmail(gomock.Any(), gomock.Eq(db.GetAccountByOwnerEmailParams{Email: user2.Email, Currency: "USD"})).
+					Times(1).
+					Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), db.TransferTxParams{
+						FromAccountID: account1.ID,
+						ToAccountID:   account2.ID,
+						Amount:        amount,
+					}).
 					Times(1).
-					Return(db.Account{}, sql.ErrNoRows)
+					Return(db.TransferTxResult{}, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusNotFound {
-					t.Errorf("expected status code 404, got %d", recorder.Code)
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "ToAccountNotFound",
+			name: "RecipientNotFound",
 			body: map[string]any{
 				"from_account_id": account1.ID,
-				"to_account_id":   account2.ID,
+				"to_email":        "nobody@example.com",
 				"amount":          amount,
 				"currency":        "USD",
 			},
@@ -164,26 +218,25 @@ func TestCreateTransferAPI(t *testing.T) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
-					Times(1).
-					Return(account1, nil)
-				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					GetAccountByOwnerEmail(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.Account{}, sql.ErrNoRows)
+					Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusNotFound {
 					t.Errorf("expected status code 404, got %d", recorder.Code)
 				}
+				checkErrorCode(t, recorder, errCodeRecipientNotFound)
 			},
 		},
 		{
-			name: "FromAccountInternalError",
+			name: "ToUsernameIsSender",
 			body: map[string]any{
 				"from_account_id": account1.ID,
-				"to_account_id":   account2.ID,
+				"to_username":     user1.Username,
 				"amount":          amount,
 				"currency":        "USD",
 			},
@@ -191,22 +244,25 @@ func TestCreateTransferAPI(t *testing.T) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					GetAccountByOwner(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.Account{}, fmt.Errorf("internal error"))
+					Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusInternalServerError {
-					t.Errorf("expected status code 500, got %d", recorder.Code)
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "ToAccountInternalError",
+			name: "AmbiguousRecipient",
 			body: map[string]any{
 				"from_account_id": account1.ID,
 				"to_account_id":   account2.ID,
+				"to_username":     user2.Username,
 				"amount":          amount,
 				"currency":        "USD",
 			},
@@ -214,37 +270,28 @@ func TestCreateTransferAPI(t *testing.T) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
-					Times(1).
-					Return(account1, nil)
-				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
-					Times(1).
-					Return(db.Account{}, fmt.Errorf("internal error"))
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusInternalServerError {
-					t.Errorf("expected status code 500, got %d", recorder.Code)
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "FromAccountCurrencyMismatch",
+			name: "NoRecipient",
 			body: map[string]any{
-				"from_account_id": account3.ID,
-				"to_account_id":   account2.ID,
+				"from_account_id": account1.ID,
 				"amount":          amount,
 				"currency":        "USD",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user3.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account3.ID)).
-					Times(1).
-					Return(account3, nil) // account3 has EUR currency
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusBadRequest {
@@ -253,10 +300,10 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "ToAccountCurrencyMismatch",
+			name: "InvalidToEmail",
 			body: map[string]any{
 				"from_account_id": account1.ID,
-				"to_account_id":   account3.ID,
+				"to_email":        "not-an-email",
 				"amount":          amount,
 				"currency":        "USD",
 			},
@@ -264,14 +311,8 @@ func TestCreateTransferAPI(t *testing.T) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
-					Times(1).
-					Return(account1, nil)
-				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account3.ID)).
-					Times(1).
-					Return(account3, nil) // account3 has EUR currency
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusBadRequest {
@@ -280,49 +321,42 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "TransferTxInternalError",
+			name: "DescriptionTooLong",
 			body: map[string]any{
 				"from_account_id": account1.ID,
 				"to_account_id":   account2.ID,
 				"amount":          amount,
 				"currency":        "USD",
+				"description":     strings.Repeat("a", 256),
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
-					Times(1).
-					Return(account1, nil)
-				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
-					Times(1).
-					Return(account2, nil)
-				store.EXPECT().
-					TransferTx(gomock.Any(), gomock.Any()).
-					Times(1).
-					Return(db.TransferTxResult{}, fmt.Errorf("transfer failed"))
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusInternalServerError {
-					t.Errorf("expected status code 500, got %d", recorder.Code)
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "InvalidFromAccountID",
+			name: "MetadataValueTooLong",
 			body: map[string]any{
-				"from_account_id": 0,
+				"from_account_id": account1.ID,
 				"to_account_id":   account2.ID,
 				"amount":          amount,
 				"currency":        "USD",
+				"metadata":        map[string]string{"note": strings.Repeat("a", 501)},
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to binding validation failure
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusBadRequest {
@@ -331,18 +365,20 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "InvalidToAccountID",
+			name: "TooManyMetadataKeys",
 			body: map[string]any{
 				"from_account_id": account1.ID,
-				"to_account_id":   0,
+				"to_account_id":   account2.ID,
 				"amount":          amount,
 				"currency":        "USD",
+				"metadata":        tooManyMetadataKeys,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to binding validation failure
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusBadRequest {
@@ -351,70 +387,78 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "NegativeAmount",
+			name: "NoAuthorization",
 			body: map[string]any{
 				"from_account_id": account1.ID,
 				"to_account_id":   account2.ID,
-				"amount":          -100,
+				"amount":          amount,
 				"currency":        "USD",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+				// Don't add authorization
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to binding validation failure
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusBadRequest {
-					t.Errorf("expected status code 400, got %d", recorder.Code)
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "ZeroAmount",
+			name: "UnauthorizedUser",
 			body: map[string]any{
 				"from_account_id": account1.ID,
 				"to_account_id":   account2.ID,
-				"amount":          0,
+				"amount":          amount,
 				"currency":        "USD",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to binding validation failure
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(account1, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusBadRequest {
-					t.Errorf("expected status code 400, got %d", recorder.Code)
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "InvalidCurrency",
+			name: "FromAccountNotFound",
 			body: map[string]any{
 				"from_account_id": account1.ID,
 				"to_account_id":   account2.ID,
 				"amount":          amount,
-				"currency":        "INVALID",
+				"currency":        "USD",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to binding validation failure
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrRecordNotFound)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusBadRequest {
-					t.Errorf("expected status code 400, got %d", recorder.Code)
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "SameFromAndToAccount",
+			name: "ToAccountNotFound",
 			body: map[string]any{
 				"from_account_id": account1.ID,
-				"to_account_id":   account1.ID,
+				"to_account_id":   account2.ID,
 				"amount":          amount,
 				"currency":        "USD",
 			},
@@ -422,37 +466,49 @@ func TestCreateTransferAPI(t *testing.T) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to binding validation failure
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(account1, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrRecordNotFound)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusBadRequest {
-					t.Errorf("expected status code 400, got %d", recorder.Code)
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "MissingFromAccountID",
+			name: "FromAccountInternalError",
 			body: map[string]any{
-				"to_account_id": account2.ID,
-				"amount":        amount,
-				"currency":      "USD",
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to binding validation failure
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(db.Account{}, fmt.Errorf("internal error"))
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusBadRequest {
-					t.Errorf("expected status code 400, got %d", recorder.Code)
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "MissingToAccountID",
+			name: "ToAccountInternalError",
 			body: map[string]any{
 				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
 				"amount":          amount,
 				"currency":        "USD",
 			},
@@ -460,26 +516,37 @@ func TestCreateTransferAPI(t *testing.T) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to binding validation failure
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(account1, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).
+					Return(db.Account{}, fmt.Errorf("internal error"))
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				if recorder.Code != http.StatusBadRequest {
-					t.Errorf("expected status code 400, got %d", recorder.Code)
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
 				}
 			},
 		},
 		{
-			name: "MissingAmount",
+			name: "FromAccountCurrencyMismatch",
 			body: map[string]any{
-				"from_account_id": account1.ID,
+				"from_account_id": account3.ID,
 				"to_account_id":   account2.ID,
+				"amount":          amount,
 				"currency":        "USD",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user3.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to binding validation failure
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account3.ID)).
+					Times(1).
+					Return(account3, nil) // account3 has EUR currency
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusBadRequest {
@@ -488,17 +555,25 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "MissingCurrency",
+			name: "ToAccountCurrencyMismatch",
 			body: map[string]any{
 				"from_account_id": account1.ID,
-				"to_account_id":   account2.ID,
+				"to_account_id":   account3.ID,
 				"amount":          amount,
+				"currency":        "USD",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to binding validation failure
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(account1, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account3.ID)).
+					Times(1).
+					Return(account3, nil) // account3 has EUR currency
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusBadRequest {
@@ -507,13 +582,1294 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "InvalidJSON",
-			body: nil,
+			name: "TransferTxInternalError",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				// No calls expected due to JSON parsing failure
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(account1, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).
+					Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("transfer failed"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          account1.Balance + 1,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(account1, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).
+					Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, &db.InsufficientFundsError{
+						AccountID: account1.ID,
+						Available: account1.Balance,
+						Requested: account1.Balance + 1,
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				var resp struct {
+					Code             string `json:"code"`
+					AvailableBalance int64  `json:"available_balance"`
+					RequestedAmount  int64  `json:"requested_amount"`
+				}
+				if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if resp.Code != errCodeInsufficientFunds {
+					t.Errorf("expected code %s, got %s", errCodeInsufficientFunds, resp.Code)
+				}
+				if resp.AvailableBalance != account1.Balance || resp.RequestedAmount != account1.Balance+1 {
+					t.Errorf("unexpected balances in response: %+v", resp)
+				}
+			},
+		},
+		{
+			name: "DailyLimitExceeded",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, &db.LimitExceededError{
+						AccountID: account1.ID,
+						Limit:     db.LimitDaily,
+						Max:       500,
+						Used:      450,
+						Requested: amount,
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				var resp struct {
+					Code            string `json:"code"`
+					Limit           string `json:"limit"`
+					LimitAmount     int64  `json:"limit_amount"`
+					UsedAmount      int64  `json:"used_amount"`
+					RequestedAmount int64  `json:"requested_amount"`
+				}
+				if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if resp.Code != errCodeTransferLimitExceeded || resp.Limit != db.LimitDaily {
+					t.Errorf("expected code %s for the daily limit, got %+v", errCodeTransferLimitExceeded, resp)
+				}
+				if resp.LimitAmount != 500 || resp.UsedAmount != 450 || resp.RequestedAmount != amount {
+					t.Errorf("unexpected amounts in response: %+v", resp)
+				}
+			},
+		},
+		{
+			name: "InvalidFromAccountID",
+			body: map[string]any{
+				"from_account_id": 0,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to binding validation failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "InvalidToAccountID",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   0,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to binding validation failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "NegativeAmount",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          -100,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to binding validation failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "ZeroAmount",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          0,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to binding validation failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "InvalidCurrency",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "INVALID",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to binding validation failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "SameFromAndToAccount",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account1.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to binding validation failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "MissingFromAccountID",
+			body: map[string]any{
+				"to_account_id": account2.ID,
+				"amount":        amount,
+				"currency":      "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to binding validation failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "MissingToAccountID",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to binding validation failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "MissingAmount",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to binding validation failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "MissingCurrency",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to binding validation failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "InvalidJSON",
+			body: nil,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// No calls expected due to JSON parsing failure
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "EUR_Currency",
+			body: map[string]any{
+				"from_account_id": account3.ID,
+				"to_account_id":   int64(4),
+				"amount":          amount,
+				"currency":        "EUR",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user3.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				account4 := db.Account{ID: 4, Owner: "user4", Balance: 500, Currency: "EUR"}
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account3.ID)).
+					Times(1).
+					Return(account3, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(int64(4))).
+					Times(1).
+					Return(account4, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "CAD_Currency",
+			body: map[string]any{
+				"from_account_id": int64(5),
+				"to_account_id":   int64(6),
+				"amount":          amount,
+				"currency":        "CAD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user5", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				account5 := db.Account{ID: 5, Owner: "user5", Balance: 500, Currency: "CAD"}
+				account6 := db.Account{ID: 6, Owner: "user6", Balance: 500, Currency: "CAD"}
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(int64(5))).
+					Times(1).
+					Return(account5, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(int64(6))).
+					Times(1).
+					Return(account6, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body []byte
+			if tc.body != nil {
+				body, _ = json.Marshal(tc.body)
+			} else {
+				body = []byte("invalid json")
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(body))
+			request.Header.Set("Content-Type", "application/json")
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateTransferIdempotencyKeyAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := db.Account{ID: 1, Owner: user1.Username, Balance: 1000, Currency: "USD"}
+	account2 := db.Account{ID: 2, Owner: user2.Username, Balance: 500, Currency: "USD"}
+
+	body := map[string]any{
+		"from_account_id": account1.ID,
+		"to_account_id":   account2.ID,
+		"amount":          int64(100),
+		"currency":        "USD",
+	}
+	key := "c0ffee00-0000-4000-8000-000000000001"
+	transferResult := db.TransferTxResult{
+		Transfer: db.Transfer{ID: 7, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 100},
+	}
+
+	expectAccounts := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+			Times(1).
+			Return(account1, nil)
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+			Times(1).
+			Return(account2, nil)
+	}
+	// expectNewKey stubs the lookup of a key no transfer was stored for yet
+	expectNewKey := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: user1.Username, Key: key})).
+			Times(1).
+			Return(db.IdempotencyKey{}, db.ErrRecordNotFound)
+	}
+
+	testCases := []struct {
+		name          string
+		key           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "FirstRequest",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store)
+				expectNewKey(store)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.IdempotentTransferTxParams) (db.IdempotentTransferTxResult, error) {
+						if arg.Username != user1.Username || arg.IdempotencyKey != key || arg.RequestHash == "" {
+							t.Errorf("unexpected idempotency params: %+v", arg)
+						}
+						if arg.Amount != 100 || arg.FromAccountID != account1.ID || arg.ToAccountID != account2.ID {
+							t.Errorf("unexpected transfer params: %+v", arg.TransferTxParams)
+						}
+						return db.IdempotentTransferTxResult{TransferTxResult: transferResult}, nil
+					})
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+				if got := recorder.Header().Get(idempotentReplayedHeader); got != "" {
+					t.Errorf("expected no %s header, got %q", idempotentReplayedHeader, got)
+				}
+				requireBodyMatchTransferResult(t, recorder, transferResult)
+			},
+		},
+		{
+			// a request with the same key that was still in flight when this one was looked up
+			name: "ReplayedInFlight",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store)
+				expectNewKey(store)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotentTransferTxResult{TransferTxResult: transferResult, Replayed: true}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+				if got := recorder.Header().Get(idempotentReplayedHeader); got != "true" {
+					t.Errorf("expected %s header true, got %q", idempotentReplayedHeader, got)
+				}
+				requireBodyMatchTransferResult(t, recorder, transferResult)
+			},
+		},
+		{
+			name: "KeyReused",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store)
+				expectNewKey(store)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotentTransferTxResult{}, db.ErrIdempotencyKeyReused)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				var resp struct {
+					Code string `json:"code"`
+				}
+				if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if resp.Code != errCodeIdempotencyKeyReused {
+					t.Errorf("expected code %s, got %s", errCodeIdempotencyKeyReused, resp.Code)
+				}
+			},
+		},
+		{
+			name: "KeyTooLong",
+			key:  strings.Repeat("k", maxIdempotencyKeyLength+1),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().IdempotentTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, _ := json.Marshal(body)
+			request := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set(idempotencyKeyHeader, tc.key)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateTransferReplayBeforeStepUp(t *testing.T) {
+	user1, _ := randomMFAUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := db.Account{ID: 1, Owner: user1.Username, Balance: 1000, Currency: "USD"}
+	account2 := db.Account{ID: 2, Owner: user2.Username, Balance: 500, Currency: "USD"}
+
+	// the amount is above the step-up threshold and the retry carries no TOTP code
+	req := transferRequest{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 600, Currency: "USD"}
+	key := "c0ffee00-0000-4000-8000-000000000002"
+	transferResult := db.TransferTxResult{
+		Transfer: db.Transfer{ID: 7, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 600},
+	}
+	response, err := json.Marshal(transferResult)
+	if err != nil {
+		t.Fatal("Cannot encode transfer result:", err)
+	}
+
+	testCases := []struct {
+		name          string
+		stored        db.IdempotencyKey
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "SameRequest",
+			stored: db.IdempotencyKey{Username: user1.Username, Key: key, RequestHash: hashTransferRequest(req), Response: response},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+				if got := recorder.Header().Get(idempotentReplayedHeader); got != "true" {
+					t.Errorf("expected %s header true, got %q", idempotentReplayedHeader, got)
+				}
+				requireBodyMatchTransferResult(t, recorder, transferResult)
+			},
+		},
+		{
+			name:   "AnotherRequest",
+			stored: db.IdempotencyKey{Username: user1.Username, Key: key, RequestHash: "another request", Response: response},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeIdempotencyKeyReused)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+			store.EXPECT().
+				GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: user1.Username, Key: key})).
+				Times(1).
+				Return(tc.stored, nil)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			store.EXPECT().IdempotentTransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+			server := newTestServer(t, store)
+			server.config.MFAStepUpAmounts = map[string]int64{"USD": 500}
+			recorder := httptest.NewRecorder()
+
+			data, _ := json.Marshal(req)
+			request := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set(idempotencyKeyHeader, key)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestHashTransferRequest(t *testing.T) {
+	req := transferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 100, Currency: "USD"}
+
+	if hashTransferRequest(req) != hashTransferRequest(req) {
+		t.Errorf("expected the same request to hash the same")
+	}
+
+	other := req
+	other.Amount = 101
+	if hashTransferRequest(req) == hashTransferRequest(other) {
+		t.Errorf("expected different requests to hash differently")
+	}
+}
+
+func requireBodyMatchTransferResult(t *testing.T, recorder *httptest.ResponseRecorder, want db.TransferTxResult) {
+	t.Helper()
+	var got db.TransferTxResult
+	if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if got.Transfer.ID != want.Transfer.ID || got.Transfer.Amount != want.Transfer.Amount {
+		t.Errorf("expected transfer %+v, got %+v", want.Transfer, got.Transfer)
+	}
+}
+
+func TestCreateFXTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := db.Account{ID: 1, Owner: user1.Username, Balance: 10000, Currency: "USD"}
+	account2 := db.Account{ID: 2, Owner: user2.Username, Balance: 500, Currency: "EUR"}
+	account3 := db.Account{ID: 3, Owner: user2.Username, Balance: 500, Currency: "CAD"}
+
+	quote := db.FxQuote{
+		ID:           uuid.New(),
+		Username:     user1.Username,
+		FromCurrency: "USD",
+		ToCurrency:   "EUR",
+		Rate:         0.9,
+		SpreadBps:    100,
+		FromAmount:   1000,
+		ToAmount:     891,
+	}
+	body := func(toAccountID int64) map[string]any {
+		return map[string]any{
+			"from_account_id": account1.ID,
+			"to_account_id":   toAccountID,
+			"amount":          quote.FromAmount,
+			"currency":        "USD",
+			"quote_id":        quote.ID,
+		}
+	}
+	expectFromAccount := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+			Times(1).
+			Return(account1, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          map[string]any
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: body(account2.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectFromAccount(store)
+				store.EXPECT().
+					GetFXQuote(gomock.Any(), gomock.Eq(quote.ID)).
+					Times(1).
+					Return(quote, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).
+					Return(account2, nil)
+				arg := db.FXTransferTxParams{
+					TransferTxParams: db.TransferTxParams{
+						FromAccountID: account1.ID,
+						ToAccountID:   account2.ID,
+						Amount:        quote.FromAmount,
+					},
+					Username: user1.Username,
+					QuoteID:  quote.ID,
+				}
+				store.EXPECT().
+					FXTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.TransferTxResult{Transfer: db.Transfer{ID: 9, Amount: quote.FromAmount, ToAmount: quote.ToAmount}}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+				requireBodyMatchTransferResult(t, recorder, db.TransferTxResult{Transfer: db.Transfer{ID: 9, Amount: quote.FromAmount}})
+			},
+		},
+		{
+			name: "QuoteNotFound",
+			body: body(account2.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectFromAccount(store)
+				store.EXPECT().
+					GetFXQuote(gomock.Any(), gomock.Eq(quote.ID)).
+					Times(1).
+					Return(db.FxQuote{}, db.ErrRecordNotFound)
+				store.EXPECT().FXTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "QuoteOfAnotherUser",
+			body: body(account2.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectFromAccount(store)
+				other := quote
+				other.Username = user2.Username
+				store.EXPECT().
+					GetFXQuote(gomock.Any(), gomock.Eq(quote.ID)).
+					Times(1).
+					Return(other, nil)
+				store.EXPECT().FXTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "ToAccountCurrencyMismatch",
+			body: body(account3.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectFromAccount(store)
+				store.EXPECT().
+					GetFXQuote(gomock.Any(), gomock.Eq(quote.ID)).
+					Times(1).
+					Return(quote, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account3.ID)).
+					Times(1).
+					Return(account3, nil)
+				store.EXPECT().FXTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name: "QuoteExpired",
+			body: body(account2.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectFromAccount(store)
+				store.EXPECT().GetFXQuote(gomock.Any(), gomock.Any()).Times(1).Return(quote, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					FXTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrQuoteExpired)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeFXQuoteExpired)
+			},
+		},
+		{
+			name: "QuoteUsed",
+			body: body(account2.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectFromAccount(store)
+				store.EXPECT().GetFXQuote(gomock.Any(), gomock.Any()).Times(1).Return(quote, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					FXTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrQuoteUsed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeFXQuoteUsed)
+			},
+		},
+		{
+			name: "AmountMismatch",
+			body: body(account2.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectFromAccount(store)
+				store.EXPECT().GetFXQuote(gomock.Any(), gomock.Any()).Times(1).Return(quote, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					FXTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: amount 1, quoted 1000", db.ErrQuoteMismatch))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeFXQuoteMismatch)
+			},
+		},
+		{
+			name: "AmountOutOfRange",
+			body: body(account2.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectFromAccount(store)
+				store.EXPECT().GetFXQuote(gomock.Any(), gomock.Any()).Times(1).Return(quote, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					FXTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: 900 EUR", db.ErrAmountOutOfRange))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeAmountOutOfRange)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, _ := json.Marshal(tc.body)
+			request := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := db.Account{ID: 1, Owner: user1.Username, Balance: 1000, Currency: "USD"}
+	account2 := db.Account{ID: 2, Owner: user2.Username, Balance: 500, Currency: "USD"}
+	transfer := db.Transfer{
+		ID:            42,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		ToAmount:      100,
+		ExchangeRate:  1,
+		Description:   pgtype.Text{String: "March rent", Valid: true},
+		Reference:     pgtype.Text{String: "INV-2026-03", Valid: true},
+		Metadata:      json.RawMessage(`{"flat":"4B"}`),
+	}
+
+	testCases := []struct {
+		name          string
+		transferID    int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "Sender",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got db.Transfer
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if !reflect.DeepEqual(got, transfer) {
+					t.Errorf("expected transfer %+v, got %+v", transfer, got)
+				}
+			},
+		},
+		{
+			name:       "Recipient",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:       "UnauthorizedUser",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "other_user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:       "BankerReadsAnyTransfer",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, "banker_user", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:       "NotFound",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(db.Transfer{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:       "InternalError",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, fmt.Errorf("internal error"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:       "InvalidID",
+			transferID: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/transfers/%d", tc.transferID), nil)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReverseTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := db.Account{ID: 1, Owner: user1.Username, Balance: 900, Currency: "USD"}
+	account2 := db.Account{ID: 2, Owner: user2.Username, Balance: 600, Currency: "USD"}
+	transfer := db.Transfer{ID: 42, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 100, ToAmount: 100, ExchangeRate: 1, Status: db.TransferCompleted}
+
+	result := db.ReverseTransferTxResult{
+		TransferTxResult: db.TransferTxResult{
+			Transfer: db.Transfer{
+				ID:            43,
+				FromAccountID: account2.ID,
+				ToAccountID:   account1.ID,
+				Amount:        40,
+				ToAmount:      40,
+				ExchangeRate:  1,
+				Status:        db.TransferCompleted,
+				ReversalOf:    pgtype.Int8{Int64: transfer.ID, Valid: true},
+			},
+		},
+		OriginalTransfer: transfer,
+	}
+	result.OriginalTransfer.Status = db.TransferPartiallyReversed
+	result.OriginalTransfer.ReversedAmount = 40
+
+	testCases := []struct {
+		name          string
+		transferID    int64
+		body          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "PartialByRecipient",
+			transferID: transfer.ID,
+			body:       `{"amount": 40}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				arg := db.ReverseTransferTxParams{TransferID: transfer.ID, Amount: 40}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got db.ReverseTransferTxResult
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if got.Transfer.ID != result.Transfer.ID || got.Transfer.Amount != result.Transfer.Amount ||
+					got.OriginalTransfer.Status != result.OriginalTransfer.Status ||
+					got.OriginalTransfer.ReversedAmount != result.OriginalTransfer.ReversedAmount {
+					t.Errorf("expected result %+v, got %+v", result, got)
+				}
+			},
+		},
+		{
+			name:       "FullWithoutBody",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				arg := db.ReverseTransferTxParams{TransferID: transfer.ID}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:       "AdminReversesAnyTransfer",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, "admin_user", util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:       "SenderCannotReverse",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:       "ExceedsAmount",
+			transferID: transfer.ID,
+			body:       `{"amount": 150}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				err := fmt.Errorf("%w: requested 150, unreversed 100", db.ErrInvalidReversalAmount)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReverseTransferTxResult{}, err)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeInvalidReversalAmount)
+			},
+		},
+		{
+			name:       "ReversalOfReversal",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReverseTransferTxResult{}, db.ErrReversalOfReversal)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeReversalOfReversal)
+			},
+		},
+		{
+			name:       "InsufficientFunds",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				err := &db.InsufficientFundsError{AccountID: account2.ID, Available: 10, Requested: 100}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReverseTransferTxResult{}, err)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeInsufficientFunds)
+			},
+		},
+		{
+			name:       "NotFound",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(db.Transfer{}, db.ErrRecordNotFound)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusNotFound {
+					t.Errorf("expected status code 404, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:       "InvalidAmount",
+			transferID: transfer.ID,
+			body:       `{"amount": -5}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusBadRequest {
@@ -522,30 +1878,143 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "EUR_Currency",
-			body: map[string]any{
-				"from_account_id": account3.ID,
-				"to_account_id":   int64(4),
-				"amount":          amount,
-				"currency":        "EUR",
+			name:       "NoTransferScope",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationWithRole(t, request, tokenMaker, authorizationTypeBearer, "banker_user", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusForbidden {
+					t.Errorf("expected status code 403, got %d", recorder.Code)
+				}
 			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/%d/reverse", tc.transferID)
+			request := httptest.NewRequest(http.MethodPost, url, strings.NewReader(tc.body))
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAccountTransfersAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := db.Account{ID: 1, Owner: user.Username, Balance: 1000, Currency: "USD"}
+	transfers := []db.Transfer{
+		{ID: 2, FromAccountID: account.ID, ToAccountID: 3, Amount: 50, ToAmount: 50, ExchangeRate: 1},
+		{ID: 1, FromAccountID: 3, ToAccountID: account.ID, Amount: 80, ToAmount: 80, ExchangeRate: 1},
+	}
+	createdAt, id := pageCursor{}.position(true)
+	cursorTime := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: url.Values{"page_size": {"5"}},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user3.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				account4 := db.Account{ID: 4, Owner: "user4", Balance: 500, Currency: "EUR"}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.ListAccountTransfersBeforeParams{AccountID: account.ID, CursorCreatedAt: createdAt, CursorID: id, PageLimit: 6}
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account3.ID)).
+					ListAccountTransfersBefore(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
-					Return(account3, nil)
+					Return(transfers, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got pageResponse[db.Transfer]
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(got.Items) != len(transfers) {
+					t.Errorf("expected %d transfers, got %d", len(transfers), len(got.Items))
+				}
+			},
+		},
+		{
+			name:  "NextPage",
+			query: url.Values{"page_size": {"1"}, "cursor": {pageCursor{CreatedAt: cursorTime, ID: 3, Direction: cursorNext}.encode()}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.ListAccountTransfersBeforeParams{
+					AccountID:       account.ID,
+					CursorCreatedAt: pgtype.Timestamptz{Time: cursorTime, Valid: true},
+					CursorID:        3,
+					PageLimit:       2,
+				}
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(int64(4))).
+					ListAccountTransfersBefore(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
-					Return(account4, nil)
+					Return(transfers, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var got pageResponse[db.Transfer]
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if len(got.Items) != 1 || got.Items[0].ID != transfers[0].ID {
+					t.Errorf("expected the first transfer only, got %+v", got.Items)
+				}
+				if !got.HasMore || got.NextCursor == "" || got.PrevCursor == "" {
+					t.Errorf("expected more pages in both directions, got %+v", got)
+				}
+			},
+		},
+		{
+			name:  "IncomingWithAmountRange",
+			query: url.Values{"page_size": {"5"}, "direction": {"incoming"}, "min_amount": {"60"}, "max_amount": {"100"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.ListAccountTransfersBeforeParams{
+					AccountID:       account.ID,
+					Direction:       pgtype.Text{String: "incoming", Valid: true},
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+					MinAmount:       pgtype.Int8{Int64: 60, Valid: true},
+					MaxAmount:       pgtype.Int8{Int64: 100, Valid: true},
+					PageLimit:       6,
+				}
 				store.EXPECT().
-					TransferTx(gomock.Any(), gomock.Any()).
+					ListAccountTransfersBefore(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
-					Return(db.TransferTxResult{}, nil)
+					Return(transfers[1:], nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusOK {
@@ -554,31 +2023,24 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "CAD_Currency",
-			body: map[string]any{
-				"from_account_id": int64(5),
-				"to_account_id":   int64(6),
-				"amount":          amount,
-				"currency":        "CAD",
-			},
+			name:  "ByReference",
+			query: url.Values{"page_size": {"5"}, "reference": {"INV-2026-03"}},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user5", time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				account5 := db.Account{ID: 5, Owner: "user5", Balance: 500, Currency: "CAD"}
-				account6 := db.Account{ID: 6, Owner: "user6", Balance: 500, Currency: "CAD"}
-				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(int64(5))).
-					Times(1).
-					Return(account5, nil)
-				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(int64(6))).
-					Times(1).
-					Return(account6, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.ListAccountTransfersBeforeParams{
+					AccountID:       account.ID,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+					Reference:       pgtype.Text{String: "INV-2026-03", Valid: true},
+					PageLimit:       6,
+				}
 				store.EXPECT().
-					TransferTx(gomock.Any(), gomock.Any()).
+					ListAccountTransfersBefore(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
-					Return(db.TransferTxResult{}, nil)
+					Return(transfers[:1], nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				if recorder.Code != http.StatusOK {
@@ -586,6 +2048,72 @@ func TestCreateTransferAPI(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:  "ReferenceTooLong",
+			query: url.Values{"page_size": {"5"}, "reference": {strings.Repeat("r", 65)}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountTransfersBefore(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:  "UnauthorizedUser",
+			query: url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "other_user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountTransfersBefore(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:  "PageSizeTooLarge",
+			query: url.Values{"page_size": {"101"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:  "InternalError",
+			query: url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountTransfersBefore(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, fmt.Errorf("internal error"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
+				}
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -599,15 +2127,8 @@ func TestCreateTransferAPI(t *testing.T) {
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
-			var body []byte
-			if tc.body != nil {
-				body, _ = json.Marshal(tc.body)
-			} else {
-				body = []byte("invalid json")
-			}
-
-			request := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(body))
-			request.Header.Set("Content-Type", "application/json")
+			path := fmt.Sprintf("/accounts/%d/transfers?%s", account.ID, tc.query.Encode())
+			request := httptest.NewRequest(http.MethodGet, path, nil)
 
 			tc.setupAuth(t, request, server.tokenMaker)
 			server.router.ServeHTTP(recorder, request)
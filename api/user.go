@@ -1,14 +1,18 @@
 package api
 
 import (
-	"database/sql"
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"time"
 
 	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/token"
 	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type createUserRequest struct {
@@ -20,8 +24,11 @@ type createUserRequest struct {
 
 type userResponse struct {
 	Username          string `json:"username"`
+	Role              string `json:"role"`
 	FullName          string `json:"full_name"`
 	Email             string `json:"email"`
+	IsEmailVerified   bool   `json:"is_email_verified"`
+	MFAEnabled        bool   `json:"mfa_enabled"`
 	PasswordChangedAt string `json:"password_changed_at"`
 	CreatedAt         string `json:"created_at"`
 }
@@ -32,16 +39,40 @@ type loginUserRequest struct {
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
+}
+
+type updateUserRoleURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,role"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,nefield=CurrentPassword"`
+}
+
+type logoutUserRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 // parseUserResponse converts a db.User to a userResponse.
 func parseUserResponse(user db.User) userResponse {
 	return userResponse{
 		Username:          user.Username,
+		Role:              user.Role,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		IsEmailVerified:   user.IsEmailVerified,
+		MFAEnabled:        user.MfaEnabled,
 		PasswordChangedAt: user.PasswordChangedAt.Time.Format(time.RFC3339),
 		CreatedAt:         user.CreatedAt.Time.Format(time.RFC3339),
 	}
@@ -60,26 +91,38 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	user, err := server.store.CreateUser(ctx, db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	code, err := util.RandomOneTimeCode()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hashedPassword,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
+		VerifyCodeHash:  util.HashOneTimeCode(code),
+		VerifyExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(server.config.EmailVerificationDuration), Valid: true},
 	})
 
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
+		if errors.Is(err, db.ErrUniqueViolation) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, parseUserResponse(user))
+	// the email is only sent once the user is committed, and a slow mail server does not hold up the sign-up
+	server.runInBackground("send verification email", func(ctx context.Context) error {
+		return server.sendVerificationEmail(ctx, result.User, code)
+	})
+
+	ctx.JSON(http.StatusOK, parseUserResponse(result.User))
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
@@ -89,23 +132,67 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	now := time.Now()
+	if !server.loginAllowed(ctx, now) {
+		return
+	}
+
 	user, err := server.store.GetUser(ctx, req.Username)
-	if err == sql.ErrNoRows {
-		ctx.JSON(http.StatusNotFound, errorResponse(err))
+	if errors.Is(err, db.ErrRecordNotFound) {
+		server.rejectLogin(ctx, req.Password, now)
 		return
 	} else if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	user, claimed, err := server.claimLoginAttempt(ctx, user, now)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// unknown usernames cannot be locked out, so locked users get no hint that they are
+	if !claimed {
+		server.rejectLogin(ctx, req.Password, now)
+		return
+	}
+
 	err = util.CheckPassword(req.Password, user.HashedPassword)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		// the claim already counted the failure against the user
+		server.loginThrottle.recordFailure(ctx.ClientIP(), now)
+		ctx.JSON(http.StatusUnauthorized, errorCodeResponse(errCodeIncorrectCredentials, errIncorrectCredentials))
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(
+	if user.MfaEnabled {
+		// the failures in a row are only forgotten once the second factor is right as well
+		user, err = server.store.ReleaseLoginAttempt(ctx, user.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		server.mfaChallenge(ctx, user)
+		return
+	}
+
+	server.startSession(ctx, user)
+}
+
+// startSession creates a session for a user who logged in and responds with its tokens
+func (server *Server) startSession(ctx *gin.Context, user db.User) {
+	if user.FailedLoginAttempts > 0 {
+		_, err := server.store.UnlockUser(ctx, user.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
 		user.Username,
+		user.Role,
+		token.AccessToken,
 		server.config.AccessTokenDuration,
 	)
 	if err != nil {
@@ -113,10 +200,210 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(
+		user.Username,
+		user.Role,
+		token.RefreshToken,
+		server.config.RefreshTokenDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The session shares its ID with the refresh token so it can be looked up on renewal
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIp:     ctx.ClientIP(),
+		IsBlocked:    false,
+		ExpiresAt:    pgtype.Timestamptz{Time: refreshPayload.ExpiredAt, Valid: true},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	response := loginUserResponse{
-		AccessToken: accessToken,
-		User:        parseUserResponse(user),
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+		User:                  parseUserResponse(user),
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
+
+// logoutUser revokes the access token used for the request and, when a refresh token is given,
+// blocks the session behind it so it can no longer be renewed.
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req logoutUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if req.RefreshToken != "" {
+		refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		if refreshPayload.Type != token.RefreshToken {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrWrongTokenType))
+			return
+		}
+
+		if refreshPayload.Username != authPayload.Username {
+			err := errors.New("refresh token does not belong to the authenticated user")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		err = server.store.BlockSession(ctx, db.BlockSessionParams{
+			ID:       refreshPayload.ID,
+			Username: refreshPayload.Username,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if err := server.revokeToken(ctx, refreshPayload); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	if err := server.revokeToken(ctx, authPayload); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// logoutAllUser blocks every session of the authenticated user and revokes all tokens issued so far.
+func (server *Server) logoutAllUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.LogoutAllTx(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.revocations.revokeUser(user.Username, user.TokensRevokedAt.Time)
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// changePassword replaces the authenticated user's password after checking the current one.
+// Every session is blocked and every token issued before the change is rejected from then on,
+// including the one used for this request, so the user has to log in again.
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = util.CheckPassword(req.CurrentPassword, user.HashedPassword)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err = server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.revocations.revokeUser(user.Username, user.PasswordChangedAt.Time)
+
+	ctx.JSON(http.StatusOK, parseUserResponse(user))
+}
+
+// updateUserRole lets an admin promote or demote a user.
+// A change of role logs the user out of every device, so no token keeps the old role's permissions.
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri updateUserRoleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.UpdateUserRoleTx(ctx, db.UpdateUserRoleParams{
+		Username: uri.Username,
+		Role:     req.Role,
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.revocations.revokeUser(user.Username, user.TokensRevokedAt.Time)
+
+	ctx.JSON(http.StatusOK, parseUserResponse(user))
+}
+
+type unlockUserURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// unlockUser lets an admin lift the lockout of a user after too many failed logins
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var uri unlockUserURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.UnlockUser(ctx, uri.Username)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, parseUserResponse(user))
+}
@@ -11,3 +11,17 @@ var validCurrencies validator.Func = func(fl validator.FieldLevel) bool {
 	}
 	return false
 }
+
+var validRoles validator.Func = func(fl validator.FieldLevel) bool {
+	if role, ok := fl.Field().Interface().(string); ok {
+		return util.IsSupportedRole(role)
+	}
+	return false
+}
+
+var validFrequencies validator.Func = func(fl validator.FieldLevel) bool {
+	if frequency, ok := fl.Field().Interface().(string); ok {
+		return util.IsSupportedFrequency(frequency)
+	}
+	return false
+}
@@ -50,3 +50,35 @@ func TestValidCurrencies_NonStringField(t *testing.T) {
 	err := v.Struct(nonStringStruct{Currency: 123})
 	require.Error(t, err)
 }
+
+func TestValidRoles(t *testing.T) {
+	v := validator.New()
+	v.RegisterValidation("role", validRoles)
+
+	type testStruct struct {
+		Role string `validate:"role"`
+	}
+
+	testCases := []struct {
+		name  string
+		role  string
+		valid bool
+	}{
+		{"Depositor", "depositor", true},
+		{"Banker", "banker", true},
+		{"Admin", "admin", true},
+		{"Invalid", "teller", false},
+		{"Empty", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.Struct(testStruct{Role: tc.role})
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
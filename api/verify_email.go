@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/mail"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrEmailNotVerified is returned when an unverified user calls a route that requires a verified email
+var ErrEmailNotVerified = errors.New("email address is not verified")
+
+// sendVerificationEmail mails the user a link to EMAIL_VERIFICATION_URL that verifies their email with the code
+func (server *Server) sendVerificationEmail(ctx context.Context, user db.User, code string) error {
+	link, err := url.Parse(server.config.EmailVerificationURL)
+	if err != nil {
+		return fmt.Errorf("invalid email verification url: %w", err)
+	}
+	query := link.Query()
+	query.Set("code", code)
+	link.RawQuery = query.Encode()
+
+	return server.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your SimpleBank email",
+		Body: fmt.Sprintf("Hi %s,\n\nWelcome to SimpleBank! Please verify your email by opening this link:\n\n%s\n\n"+
+			"The link expires in %s.\n",
+			user.FullName, link, server.config.EmailVerificationDuration),
+	})
+}
+
+type verifyEmailRequest struct {
+	Code string `form:"code" binding:"required"`
+}
+
+// verifyEmail is the target of the link in the verification email
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.VerifyEmailTx(ctx, util.HashOneTimeCode(req.Code))
+	switch {
+	case errors.Is(err, db.ErrVerificationCodeInvalid):
+		ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeInvalidVerificationCode, err))
+		return
+	case errors.Is(err, db.ErrVerificationCodeExpired):
+		ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeVerificationCodeExpired, err))
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, parseUserResponse(user))
+}
+
+// resendVerificationEmail mails the logged-in user a new verification link, for when the first one
+// expired or never arrived. Links sent before stay valid until they expire.
+func (server *Server) resendVerificationEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.IsEmailVerified {
+		err := errors.New("email address is already verified")
+		ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeEmailAlreadyVerified, err))
+		return
+	}
+
+	code, err := util.RandomOneTimeCode()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:  user.Username,
+		Email:     user.Email,
+		CodeHash:  util.HashOneTimeCode(code),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(server.config.EmailVerificationDuration), Valid: true},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.runInBackground("send verification email", func(ctx context.Context) error {
+		return server.sendVerificationEmail(ctx, user, code)
+	})
+
+	ctx.Status(http.StatusAccepted)
+}
@@ -0,0 +1,385 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/mail"
+	"github.com/WilliamOdinson/simplebank/token"
+	"github.com/WilliamOdinson/simplebank/util"
+	"go.uber.org/mock/gomock"
+)
+
+// verificationLinkPattern finds the link in a verification email
+var verificationLinkPattern = regexp.MustCompile(`http://\S+`)
+
+// failingMailer fails to send every message
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg mail.Message) error {
+	return fmt.Errorf("smtp server unavailable")
+}
+
+func TestCreateUserSendsVerificationEmail(t *testing.T) {
+	user, password := randomUser(t)
+	body := map[string]any{
+		"username":  user.Username,
+		"password":  password,
+		"full_name": user.FullName,
+		"email":     user.Email,
+	}
+
+	testCases := []struct {
+		name          string
+		mailer        mail.Mailer
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mailer mail.Mailer, arg db.CreateUserTxParams)
+	}{
+		{
+			name: "OK",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer mail.Mailer, arg db.CreateUserTxParams) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				if d := time.Until(arg.VerifyExpiresAt.Time); d < 23*time.Hour || d > 24*time.Hour {
+					t.Errorf("expected the code to expire in 24 hours, got %v", d)
+				}
+
+				messages := mailer.(*mail.Outbox).Messages()
+				if len(messages) != 1 {
+					t.Fatalf("expected 1 email, got %d", len(messages))
+				}
+				if messages[0].To != user.Email {
+					t.Errorf("expected email to %s, got %s", user.Email, messages[0].To)
+				}
+				link, err := url.Parse(verificationLinkPattern.FindString(messages[0].Body))
+				if err != nil {
+					t.Fatalf("expected a verification link in the email, got %q", messages[0].Body)
+				}
+				if link.Path != "/users/verify_email" {
+					t.Errorf("expected a link to /users/verify_email, got %s", link)
+				}
+				if util.HashOneTimeCode(link.Query().Get("code")) != arg.VerifyCodeHash {
+					t.Errorf("expected the stored hash to match the code in the link")
+				}
+			},
+		},
+		{
+			// the email is sent after the user is committed, so a mail failure does not fail the sign-up
+			name:   "MailError",
+			mailer: failingMailer{},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer mail.Mailer, arg db.CreateUserTxParams) {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("expected status code 200, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var got db.CreateUserTxParams
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				CreateUserTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ any, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+					got = arg
+					return db.CreateUserTxResult{User: user}, nil
+				})
+
+			server := newTestServer(t, store)
+			if tc.mailer != nil {
+				server.mailer = tc.mailer
+			}
+			recorder := httptest.NewRecorder()
+
+			data, _ := json.Marshal(body)
+			request := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			server.router.ServeHTTP(recorder, request)
+			server.background.Wait()
+			tc.checkResponse(t, recorder, server.mailer, got)
+		})
+	}
+}
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	code := "ABCDEFGH234567QR"
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?code=" + code,
+			buildStubs: func(store *mockdb.MockStore) {
+				verified := user
+				verified.IsEmailVerified = true
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(util.HashOneTimeCode(code))).
+					Times(1).
+					Return(verified, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("expected status code 200, got %d", recorder.Code)
+				}
+				var resp userResponse
+				if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if !resp.IsEmailVerified {
+					t.Error("expected the email to be verified")
+				}
+			},
+		},
+		{
+			name:  "InvalidCode",
+			query: "?code=" + code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrVerificationCodeInvalid)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeInvalidVerificationCode)
+			},
+		},
+		{
+			name:  "ExpiredCode",
+			query: "?code=" + code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrVerificationCodeExpired)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					t.Errorf("expected status code 422, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeVerificationCodeExpired)
+			},
+		},
+		{
+			name:  "MissingCode",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("expected status code 400, got %d", recorder.Code)
+				}
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?code=" + code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, fmt.Errorf("internal error"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				if recorder.Code != http.StatusInternalServerError {
+					t.Errorf("expected status code 500, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(http.MethodGet, "/users/verify_email"+tc.query, nil)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUnverifiedEmailRoutes(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name string
+		path string
+		body map[string]any
+	}{
+		{
+			name: "CreateAccount",
+			path: "/accounts",
+			body: map[string]any{"currency": util.USD},
+		},
+		{
+			name: "CreateTransfer",
+			path: "/transfers",
+			body: map[string]any{"from_account_id": 1, "to_account_id": 2, "amount": 10, "currency": util.USD},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+			server := newTestServer(t, store)
+			server.config.RequireVerifiedEmail = true
+			server.setupRouter()
+			recorder := httptest.NewRecorder()
+
+			data, _ := json.Marshal(tc.body)
+			request := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewReader(data))
+			request.Header.Set("Content-Type", "application/json")
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			if recorder.Code != http.StatusForbidden {
+				t.Errorf("expected status code 403, got %d", recorder.Code)
+			}
+			checkErrorCode(t, recorder, errCodeEmailNotVerified)
+		})
+	}
+}
+
+func TestResendVerificationEmailAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	verified := user
+	verified.IsEmailVerified = true
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore, got *db.CreateVerifyEmailParams)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, outbox *mail.Outbox, arg db.CreateVerifyEmailParams)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, got *db.CreateVerifyEmailParams) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+						*got = arg
+						return db.VerifyEmail{Username: arg.Username, Email: arg.Email, CodeHash: arg.CodeHash}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, outbox *mail.Outbox, arg db.CreateVerifyEmailParams) {
+				if recorder.Code != http.StatusAccepted {
+					t.Fatalf("expected status code 202, got %d", recorder.Code)
+				}
+				if arg.Username != user.Username || arg.Email != user.Email {
+					t.Errorf("expected a code for %s <%s>, got %s <%s>", user.Username, user.Email, arg.Username, arg.Email)
+				}
+
+				messages := outbox.Messages()
+				if len(messages) != 1 {
+					t.Fatalf("expected 1 email, got %d", len(messages))
+				}
+				link, err := url.Parse(verificationLinkPattern.FindString(messages[0].Body))
+				if err != nil {
+					t.Fatalf("expected a verification link in the email, got %q", messages[0].Body)
+				}
+				if util.HashOneTimeCode(link.Query().Get("code")) != arg.CodeHash {
+					t.Errorf("expected the stored hash to match the code in the link")
+				}
+			},
+		},
+		{
+			name: "AlreadyVerified",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, got *db.CreateVerifyEmailParams) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(verified, nil)
+				store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, outbox *mail.Outbox, arg db.CreateVerifyEmailParams) {
+				if recorder.Code != http.StatusConflict {
+					t.Errorf("expected status code 409, got %d", recorder.Code)
+				}
+				checkErrorCode(t, recorder, errCodeEmailAlreadyVerified)
+				if n := len(outbox.Messages()); n != 0 {
+					t.Errorf("expected no email, got %d", n)
+				}
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore, got *db.CreateVerifyEmailParams) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, outbox *mail.Outbox, arg db.CreateVerifyEmailParams) {
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("expected status code 401, got %d", recorder.Code)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var got db.CreateVerifyEmailParams
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, &got)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(http.MethodPost, "/users/verify_email/resend", nil)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			server.background.Wait()
+			tc.checkResponse(t, recorder, server.mailer.(*mail.Outbox), got)
+		})
+	}
+}
@@ -0,0 +1,10 @@
+ALTER TABLE "transfers" DROP CONSTRAINT IF EXISTS "to_amount_positive";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "spread_bps";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "exchange_rate";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "to_amount";
+
+COMMENT ON COLUMN "transfers"."amount" IS 'must be positive';
+
+DROP TABLE IF EXISTS "fx_quotes";
+
+DROP TABLE IF EXISTS "fx_rates";
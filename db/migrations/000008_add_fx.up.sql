@@ -0,0 +1,43 @@
+CREATE TABLE "fx_rates" (
+  "from_currency" varchar NOT NULL,
+  "to_currency" varchar NOT NULL,
+  "rate" double precision NOT NULL,
+  "spread_bps" integer NOT NULL DEFAULT 0,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("from_currency", "to_currency")
+);
+
+ALTER TABLE "fx_rates" ADD CONSTRAINT "rate_positive" CHECK (rate > 0);
+ALTER TABLE "fx_rates" ADD CONSTRAINT "spread_bps_range" CHECK (spread_bps >= 0 AND spread_bps < 10000);
+
+CREATE TABLE "fx_quotes" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "from_currency" varchar NOT NULL,
+  "to_currency" varchar NOT NULL,
+  "rate" double precision NOT NULL,
+  "spread_bps" integer NOT NULL,
+  "from_amount" bigint NOT NULL,
+  "to_amount" bigint NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "fx_quotes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "fx_quotes" ("username");
+
+CREATE INDEX ON "fx_quotes" ("expires_at");
+
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+UPDATE "transfers" SET "to_amount" = "amount";
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" double precision NOT NULL DEFAULT 1;
+ALTER TABLE "transfers" ADD COLUMN "spread_bps" integer NOT NULL DEFAULT 0;
+
+ALTER TABLE "transfers" ADD CONSTRAINT "to_amount_positive" CHECK (to_amount > 0);
+
+COMMENT ON COLUMN "transfers"."amount" IS 'debited from the source account, in its currency';
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'credited to the destination account, in its currency';
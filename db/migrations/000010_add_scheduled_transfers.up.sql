@@ -0,0 +1,57 @@
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "frequency" varchar NOT NULL,
+  "interval_count" integer NOT NULL DEFAULT 1,
+  "start_at" timestamptz NOT NULL,
+  "end_at" timestamptz,
+  "max_runs" integer,
+  "run_count" integer NOT NULL DEFAULT 0,
+  "next_run_at" timestamptz NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'active',
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_amount_positive" CHECK (amount > 0);
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_different_accounts" CHECK (from_account_id != to_account_id);
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_frequency_valid" CHECK (frequency IN ('once', 'daily', 'weekly', 'monthly'));
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_interval_positive" CHECK (interval_count > 0);
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_max_runs_positive" CHECK (max_runs > 0);
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_status_valid" CHECK (status IN ('active', 'paused', 'completed', 'cancelled'));
+
+CREATE INDEX ON "scheduled_transfers" ("owner", "created_at", "id");
+
+CREATE INDEX ON "scheduled_transfers" ("next_run_at") WHERE status = 'active';
+
+COMMENT ON COLUMN "scheduled_transfers"."frequency" IS 'once, daily, weekly or monthly';
+
+COMMENT ON COLUMN "scheduled_transfers"."run_count" IS 'occurrences claimed by the scheduler so far';
+
+CREATE TABLE "scheduled_transfer_runs" (
+  "id" bigserial PRIMARY KEY,
+  "scheduled_transfer_id" bigint NOT NULL,
+  "scheduled_for" timestamptz NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "transfer_id" bigint,
+  "error" varchar,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "finished_at" timestamptz
+);
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("scheduled_transfer_id") REFERENCES "scheduled_transfers" ("id");
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "scheduled_transfer_runs" ADD CONSTRAINT "scheduled_run_status_valid" CHECK (status IN ('pending', 'succeeded', 'failed'));
+
+CREATE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id", "created_at", "id");
@@ -0,0 +1,5 @@
+ALTER TABLE "transfers" DROP CONSTRAINT IF EXISTS "reversed_amount_range";
+ALTER TABLE "transfers" DROP CONSTRAINT IF EXISTS "transfer_status_valid";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reversal_of";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reversed_amount";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "status";
@@ -0,0 +1,12 @@
+ALTER TABLE "transfers" ADD COLUMN "status" varchar NOT NULL DEFAULT 'completed';
+ALTER TABLE "transfers" ADD COLUMN "reversed_amount" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "transfers" ADD COLUMN "reversal_of" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversal_of") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfer_status_valid" CHECK (status IN ('completed', 'partially_reversed', 'reversed'));
+ALTER TABLE "transfers" ADD CONSTRAINT "reversed_amount_range" CHECK (reversed_amount >= 0 AND reversed_amount <= amount);
+
+CREATE INDEX ON "transfers" ("reversal_of");
+
+COMMENT ON COLUMN "transfers"."reversed_amount" IS 'refunded by reversals so far, in the source account''s currency';
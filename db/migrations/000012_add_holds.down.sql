@@ -0,0 +1,7 @@
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "hold_id";
+
+DROP TABLE IF EXISTS "holds";
+
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "held_amount_range";
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "available_balance";
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "held_amount";
@@ -0,0 +1,39 @@
+ALTER TABLE "accounts" ADD COLUMN "held_amount" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "accounts" ADD COLUMN "available_balance" bigint GENERATED ALWAYS AS (balance - held_amount) STORED;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "held_amount_range" CHECK (held_amount >= 0 AND held_amount <= balance);
+
+COMMENT ON COLUMN "accounts"."held_amount" IS 'reserved by pending holds, still part of the balance';
+
+CREATE TABLE "holds" (
+  "id" bigserial PRIMARY KEY,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "captured_amount" bigint NOT NULL DEFAULT 0,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD CONSTRAINT "hold_amount_positive" CHECK (amount > 0);
+ALTER TABLE "holds" ADD CONSTRAINT "hold_different_accounts" CHECK (from_account_id != to_account_id);
+ALTER TABLE "holds" ADD CONSTRAINT "hold_captured_amount_range" CHECK (captured_amount >= 0 AND captured_amount <= amount);
+ALTER TABLE "holds" ADD CONSTRAINT "hold_status_valid" CHECK (status IN ('pending', 'captured', 'voided', 'expired'));
+
+CREATE INDEX ON "holds" ("from_account_id");
+
+CREATE INDEX ON "holds" ("to_account_id");
+
+CREATE INDEX ON "holds" ("expires_at") WHERE status = 'pending';
+
+ALTER TABLE "transfers" ADD COLUMN "hold_id" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("hold_id") REFERENCES "holds" ("id");
+
+CREATE UNIQUE INDEX ON "transfers" ("hold_id");
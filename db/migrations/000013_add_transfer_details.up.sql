@@ -0,0 +1,16 @@
+ALTER TABLE "transfers" ADD COLUMN "description" varchar;
+ALTER TABLE "transfers" ADD COLUMN "reference" varchar;
+ALTER TABLE "transfers" ADD COLUMN "metadata" jsonb;
+
+ALTER TABLE "entries" ADD COLUMN "description" varchar;
+ALTER TABLE "entries" ADD COLUMN "reference" varchar;
+ALTER TABLE "entries" ADD COLUMN "metadata" jsonb;
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfer_metadata_object" CHECK (jsonb_typeof(metadata) = 'object');
+ALTER TABLE "entries" ADD CONSTRAINT "entry_metadata_object" CHECK (jsonb_typeof(metadata) = 'object');
+
+CREATE INDEX ON "transfers" ("reference") WHERE reference IS NOT NULL;
+
+CREATE INDEX ON "entries" ("account_id", "reference") WHERE reference IS NOT NULL;
+
+COMMENT ON COLUMN "transfers"."reference" IS 'external reference supplied by the client, e.g. an invoice number';
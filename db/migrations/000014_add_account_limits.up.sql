@@ -0,0 +1,15 @@
+CREATE TABLE "account_limits" (
+  "account_id" bigint PRIMARY KEY,
+  "per_transfer" bigint,
+  "daily" bigint,
+  "monthly" bigint,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "account_limits" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_limits" ADD CONSTRAINT "per_transfer_limit_positive" CHECK (per_transfer > 0);
+ALTER TABLE "account_limits" ADD CONSTRAINT "daily_limit_positive" CHECK (daily > 0);
+ALTER TABLE "account_limits" ADD CONSTRAINT "monthly_limit_positive" CHECK (monthly > 0);
+
+COMMENT ON TABLE "account_limits" IS 'overrides the configured default transfer limits of an account''s currency, NULL keeps the default';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "mfa_recovery_codes";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "totp_last_step";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "mfa_enabled";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "totp_secret";
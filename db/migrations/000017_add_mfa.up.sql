@@ -0,0 +1,21 @@
+ALTER TABLE "users" ADD COLUMN "totp_secret" varchar;
+ALTER TABLE "users" ADD COLUMN "mfa_enabled" boolean NOT NULL DEFAULT false;
+ALTER TABLE "users" ADD COLUMN "totp_last_step" bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "users"."totp_secret" IS 'base32 TOTP secret, set when enrollment starts and only used for login once mfa_enabled';
+
+COMMENT ON COLUMN "users"."totp_last_step" IS 'period of the last TOTP code accepted, so no code is accepted twice';
+
+CREATE TABLE "mfa_recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "mfa_recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE UNIQUE INDEX ON "mfa_recovery_codes" ("username", "code_hash");
+
+COMMENT ON COLUMN "mfa_recovery_codes"."code_hash" IS 'SHA-256 of the recovery code, the code itself is only shown to the user once';
@@ -0,0 +1,19 @@
+-- name: GetAccountLimits :one
+SELECT * FROM account_limits
+WHERE account_id = $1 LIMIT 1;
+
+-- name: SetAccountLimits :one
+INSERT INTO account_limits (
+  account_id,
+  per_transfer,
+  daily,
+  monthly
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (account_id) DO UPDATE SET
+  per_transfer = EXCLUDED.per_transfer,
+  daily = EXCLUDED.daily,
+  monthly = EXCLUDED.monthly,
+  updated_at = now()
+RETURNING *;
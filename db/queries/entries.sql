@@ -1,9 +1,12 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  description,
+  reference,
+  metadata
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4, $5
 )
 RETURNING *;
 
@@ -16,4 +19,44 @@ SELECT * FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
-OFFSET $3;
\ No newline at end of file
+OFFSET $3;
+
+-- name: ListAccountEntriesBefore :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+  AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+  AND (sqlc.narg(min_amount)::bigint IS NULL OR abs(amount) >= sqlc.narg(min_amount))
+  AND (sqlc.narg(max_amount)::bigint IS NULL OR abs(amount) <= sqlc.narg(max_amount))
+  AND (
+    sqlc.narg(direction)::varchar IS NULL OR
+    (sqlc.narg(direction) = 'incoming' AND amount > 0) OR
+    (sqlc.narg(direction) = 'outgoing' AND amount < 0)
+  )
+  AND (sqlc.narg(reference)::varchar IS NULL OR reference = sqlc.narg(reference))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListAccountEntriesAfter :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+  AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+  AND (sqlc.narg(min_amount)::bigint IS NULL OR abs(amount) >= sqlc.narg(min_amount))
+  AND (sqlc.narg(max_amount)::bigint IS NULL OR abs(amount) <= sqlc.narg(max_amount))
+  AND (
+    sqlc.narg(direction)::varchar IS NULL OR
+    (sqlc.narg(direction) = 'incoming' AND amount > 0) OR
+    (sqlc.narg(direction) = 'outgoing' AND amount < 0)
+  )
+  AND (sqlc.narg(reference)::varchar IS NULL OR reference = sqlc.narg(reference))
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: SumAccountOutgoing :one
+SELECT COALESCE(-SUM(amount), 0)::bigint AS total FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND amount < 0
+  AND created_at >= sqlc.arg(since);
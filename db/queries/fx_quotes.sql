@@ -0,0 +1,30 @@
+-- name: CreateFXQuote :one
+INSERT INTO fx_quotes (
+  id,
+  username,
+  from_currency,
+  to_currency,
+  rate,
+  spread_bps,
+  from_amount,
+  to_amount,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: GetFXQuote :one
+SELECT * FROM fx_quotes
+WHERE id = $1 LIMIT 1;
+
+-- name: GetFXQuoteForUpdate :one
+SELECT * FROM fx_quotes
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: MarkFXQuoteUsed :one
+UPDATE fx_quotes
+  set used_at = now()
+WHERE id = $1
+RETURNING *;
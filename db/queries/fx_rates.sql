@@ -0,0 +1,18 @@
+-- name: GetFXRate :one
+SELECT * FROM fx_rates
+WHERE from_currency = $1 AND to_currency = $2 LIMIT 1;
+
+-- name: UpsertFXRate :one
+INSERT INTO fx_rates (
+  from_currency,
+  to_currency,
+  rate,
+  spread_bps
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (from_currency, to_currency) DO UPDATE
+  SET rate = EXCLUDED.rate,
+      spread_bps = EXCLUDED.spread_bps,
+      updated_at = now()
+RETURNING *;
@@ -0,0 +1,41 @@
+-- name: CreateHold :one
+INSERT INTO holds (
+  from_account_id,
+  to_account_id,
+  amount,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetHold :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHoldForUpdate :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: CloseHold :one
+UPDATE holds
+SET
+  status = sqlc.arg(status),
+  captured_amount = sqlc.arg(captured_amount),
+  updated_at = now()
+WHERE id = sqlc.arg(id) AND status = 'pending'
+RETURNING *;
+
+-- name: ClaimExpiredHolds :many
+SELECT * FROM holds
+WHERE status = 'pending' AND expires_at <= now()
+ORDER BY expires_at
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: SumAccountPendingHolds :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total FROM holds
+WHERE from_account_id = sqlc.arg(account_id)
+  AND status = 'pending'
+  AND created_at >= sqlc.arg(since);
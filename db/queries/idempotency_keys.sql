@@ -0,0 +1,18 @@
+-- name: CreateIdempotencyKey :execrows
+INSERT INTO idempotency_keys (
+  username,
+  key,
+  request_hash
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (username, key) DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND key = $2 LIMIT 1;
+
+-- name: SetIdempotencyKeyResponse :exec
+UPDATE idempotency_keys
+  set response = $3
+WHERE username = $1 AND key = $2;
@@ -0,0 +1,18 @@
+-- name: CreateMFARecoveryCode :one
+INSERT INTO mfa_recovery_codes (
+  username,
+  code_hash
+) VALUES (
+  $1, $2
+)
+RETURNING *;
+
+-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE username = $1;
+
+-- name: UseMFARecoveryCode :one
+UPDATE mfa_recovery_codes
+  set used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL
+RETURNING *;
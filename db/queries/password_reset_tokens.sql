@@ -0,0 +1,20 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+  username,
+  code_hash,
+  expires_at
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetPasswordResetTokenForUpdate :one
+SELECT * FROM password_reset_tokens
+WHERE code_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: InvalidatePasswordResetTokens :exec
+-- marks every unused code of the user as used, so a reset leaves no other code working
+UPDATE password_reset_tokens
+  set used_at = now()
+WHERE username = $1 AND used_at IS NULL;
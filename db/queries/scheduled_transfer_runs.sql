@@ -0,0 +1,32 @@
+-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+  scheduled_transfer_id,
+  scheduled_for
+) VALUES (
+  $1, $2
+)
+RETURNING *;
+
+-- name: FinishScheduledTransferRun :one
+UPDATE scheduled_transfer_runs
+SET
+  status = $2,
+  transfer_id = $3,
+  error = $4,
+  finished_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListScheduledTransferRunsBefore :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = sqlc.arg(scheduled_transfer_id)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListScheduledTransferRunsAfter :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = sqlc.arg(scheduled_transfer_id)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,70 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  frequency,
+  interval_count,
+  start_at,
+  end_at,
+  max_runs,
+  next_run_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $7
+)
+RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: ListScheduledTransfersAfter :many
+SELECT * FROM scheduled_transfers
+WHERE owner = sqlc.arg(owner)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListScheduledTransfersBefore :many
+SELECT * FROM scheduled_transfers
+WHERE owner = sqlc.arg(owner)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  amount = COALESCE(sqlc.narg(amount), amount),
+  end_at = COALESCE(sqlc.narg(end_at), end_at),
+  max_runs = COALESCE(sqlc.narg(max_runs), max_runs),
+  status = COALESCE(sqlc.narg(status), status),
+  updated_at = now()
+WHERE id = sqlc.arg(id) AND status IN ('active', 'paused')
+RETURNING *;
+
+-- name: CancelScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  status = 'cancelled',
+  updated_at = now()
+WHERE id = $1 AND status IN ('active', 'paused')
+RETURNING *;
+
+-- name: ClaimDueScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE status = 'active' AND next_run_at <= now()
+ORDER BY next_run_at
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: AdvanceScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  run_count = run_count + 1,
+  next_run_at = $2,
+  status = $3,
+  updated_at = now()
+WHERE id = $1
+RETURNING *;
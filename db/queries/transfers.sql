@@ -2,16 +2,82 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  description,
+  reference,
+  metadata
 ) VALUES (
-  $1, $2, $3
-) 
+  $1, $2, $3, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: CreateFXTransfer :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  to_amount,
+  exchange_rate,
+  spread_bps,
+  description,
+  reference,
+  metadata
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: CreateCaptureTransfer :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  to_amount,
+  hold_id
+) VALUES (
+  $1, $2, $3, $3, $4
+)
 RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: CreateReversalTransfer :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  to_amount,
+  exchange_rate,
+  reversal_of
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: AddTransferReversedAmount :one
+UPDATE transfers
+SET
+  reversed_amount = reversed_amount + sqlc.arg(amount),
+  status = CASE
+    WHEN reversed_amount + sqlc.arg(amount) = amount THEN 'reversed'
+    ELSE 'partially_reversed'
+  END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ListTransferReversals :many
+SELECT * FROM transfers
+WHERE reversal_of = $1
+ORDER BY created_at, id;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 WHERE 
@@ -19,4 +85,46 @@ WHERE
     to_account_id = $2
 ORDER BY id
 LIMIT $3
-OFFSET $4;
\ No newline at end of file
+OFFSET $4;
+
+-- name: ListAccountTransfersBefore :many
+SELECT * FROM transfers
+WHERE (
+    (from_account_id = sqlc.arg(account_id) AND sqlc.narg(direction)::varchar IS DISTINCT FROM 'incoming') OR
+    (to_account_id = sqlc.arg(account_id) AND sqlc.narg(direction) IS DISTINCT FROM 'outgoing')
+  )
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+  AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+  AND (
+    sqlc.narg(min_amount)::bigint IS NULL OR
+    CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END >= sqlc.narg(min_amount)
+  )
+  AND (
+    sqlc.narg(max_amount)::bigint IS NULL OR
+    CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END <= sqlc.narg(max_amount)
+  )
+  AND (sqlc.narg(reference)::varchar IS NULL OR reference = sqlc.narg(reference))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListAccountTransfersAfter :many
+SELECT * FROM transfers
+WHERE (
+    (from_account_id = sqlc.arg(account_id) AND sqlc.narg(direction)::varchar IS DISTINCT FROM 'incoming') OR
+    (to_account_id = sqlc.arg(account_id) AND sqlc.narg(direction) IS DISTINCT FROM 'outgoing')
+  )
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+  AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+  AND (
+    sqlc.narg(min_amount)::bigint IS NULL OR
+    CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END >= sqlc.narg(min_amount)
+  )
+  AND (
+    sqlc.narg(max_amount)::bigint IS NULL OR
+    CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END <= sqlc.narg(max_amount)
+  )
+  AND (sqlc.narg(reference)::varchar IS NULL OR reference = sqlc.narg(reference))
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
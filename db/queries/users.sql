@@ -12,3 +12,87 @@ RETURNING *;
 -- name: GetUser :one
 SELECT * FROM users
 WHERE username = $1 LIMIT 1;
+
+-- name: RevokeUserTokens :one
+UPDATE users
+  set tokens_revoked_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: ListUserTokenRevocations :many
+-- changing the password revokes older tokens just like logging out from all devices
+SELECT username, GREATEST(tokens_revoked_at, password_changed_at)::timestamptz AS tokens_revoked_at FROM users
+WHERE tokens_revoked_at > sqlc.arg(since) OR password_changed_at > sqlc.arg(since);
+
+-- name: UpdateUserRole :one
+UPDATE users
+  set role = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+  set hashed_password = $2,
+  password_changed_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: VerifyUserEmail :one
+-- only verifies the address the code was sent to, in case the user's email changed since
+UPDATE users
+  set is_email_verified = true
+WHERE username = $1 AND email = $2
+RETURNING *;
+
+-- name: SetUserTOTPSecret :one
+-- starts TOTP enrollment, which cannot be restarted once MFA is enabled
+UPDATE users
+  set totp_secret = $2
+WHERE username = $1 AND NOT mfa_enabled
+RETURNING *;
+
+-- name: EnableUserMFA :one
+UPDATE users
+  set mfa_enabled = true,
+  totp_last_step = $2
+WHERE username = $1 AND totp_secret IS NOT NULL AND NOT mfa_enabled
+RETURNING *;
+
+-- name: UseUserTOTPStep :one
+-- records the period of an accepted TOTP code, finding no row when a code of that period was already used
+UPDATE users
+  set totp_last_step = $2
+WHERE username = $1 AND totp_last_step < $2
+RETURNING *;
+
+-- name: ClaimLoginAttempt :one
+-- counts a login attempt as failed before it is checked and locks the user until locked_until.
+-- Nothing is claimed while the user is locked out or once another attempt changed failed_login_attempts,
+-- so concurrent attempts cannot all get past the lockout.
+UPDATE users
+  set failed_login_attempts = failed_login_attempts + 1,
+  locked_until = sqlc.arg(locked_until)
+WHERE username = sqlc.arg(username)
+  AND failed_login_attempts = sqlc.arg(failed_login_attempts)
+  AND locked_until <= now()
+RETURNING *;
+
+-- name: ReleaseLoginAttempt :one
+-- takes back an attempt claimed by ClaimLoginAttempt that turned out not to fail
+UPDATE users
+  set failed_login_attempts = failed_login_attempts - 1,
+  locked_until = '0001-01-01 00:00:00Z'
+WHERE username = $1
+RETURNING *;
+
+-- name: UnlockUser :one
+-- forgets failed logins, after a successful one or when an admin unlocks the user
+UPDATE users
+  set failed_login_attempts = 0,
+  locked_until = '0001-01-01 00:00:00Z'
+WHERE username = $1
+RETURNING *;
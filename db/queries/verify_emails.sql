@@ -0,0 +1,21 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username,
+  email,
+  code_hash,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetVerifyEmailForUpdate :one
+SELECT * FROM verify_emails
+WHERE code_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: MarkVerifyEmailUsed :one
+UPDATE verify_emails
+  set used_at = now()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Kinds of transfer limit, as reported by LimitExceededError
+const (
+	LimitPerTransfer = "per_transfer"
+	LimitDaily       = "daily"
+	LimitMonthly     = "monthly"
+)
+
+// StoreOption configures a SQLStore created by NewStore
+type StoreOption func(*SQLStore)
+
+// WithTransferLimits sets the default transfer limits of accounts by currency
+func WithTransferLimits(limits map[string]util.TransferLimits) StoreOption {
+	return func(store *SQLStore) {
+		store.transferLimits = limits
+	}
+}
+
+// EffectiveTransferLimits applies the limits an account overrides to the defaults of its currency
+func EffectiveTransferLimits(defaults util.TransferLimits, override AccountLimit) util.TransferLimits {
+	limits := defaults
+	if override.PerTransfer.Valid {
+		limits.PerTransfer = override.PerTransfer.Int64
+	}
+	if override.Daily.Valid {
+		limits.Daily = override.Daily.Int64
+	}
+	if override.Monthly.Valid {
+		limits.Monthly = override.Monthly.Int64
+	}
+	return limits
+}
+
+// checkLimits returns a *LimitExceededError if sending amount would take the account past one of its
+// transfer limits. Daily and monthly limits count the outgoing entries since the start of the UTC day
+// or month plus the funds reserved by the holds placed since then that are still pending. The account
+// must be locked, so that concurrent transfers from it wait and are counted.
+func (store *SQLStore) checkLimits(ctx context.Context, q *Queries, account Account, amount int64) error {
+	override, err := q.GetAccountLimits(ctx, account.ID)
+	if err != nil && !errors.Is(err, ErrRecordNotFound) {
+		return err
+	}
+	limits := EffectiveTransferLimits(store.transferLimits[account.Currency], override)
+
+	if limits.PerTransfer > 0 && amount > limits.PerTransfer {
+		return &LimitExceededError{
+			AccountID: account.ID,
+			Limit:     LimitPerTransfer,
+			Max:       limits.PerTransfer,
+			Requested: amount,
+		}
+	}
+
+	now := time.Now().UTC()
+	periods := []struct {
+		limit string
+		max   int64
+		since time.Time
+	}{
+		{LimitDaily, limits.Daily, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)},
+		{LimitMonthly, limits.Monthly, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, period := range periods {
+		if period.max == 0 {
+			continue
+		}
+
+		since := pgtype.Timestamptz{Time: period.since, Valid: true}
+		sent, err := q.SumAccountOutgoing(ctx, SumAccountOutgoingParams{
+			AccountID: account.ID,
+			Since:     since,
+		})
+		if err != nil {
+			return err
+		}
+
+		// a hold counts in the period it was placed, like the transfer it captures into counts in its own
+		held, err := q.SumAccountPendingHolds(ctx, SumAccountPendingHoldsParams{
+			AccountID: account.ID,
+			Since:     since,
+		})
+		if err != nil {
+			return err
+		}
+
+		used := sent + held
+		if used+amount > period.max {
+			return &LimitExceededError{
+				AccountID: account.ID,
+				Limit:     period.limit,
+				Max:       period.max,
+				Used:      used,
+				Requested: amount,
+			}
+		}
+	}
+
+	return nil
+}
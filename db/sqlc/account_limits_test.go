@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAccountLimits(t *testing.T) {
+	ctx := context.Background()
+	from, _ := createFundedAccounts(t, 1000)
+
+	_, err := testQueries.GetAccountLimits(ctx, from.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	limits, err := testQueries.SetAccountLimits(ctx, SetAccountLimitsParams{
+		AccountID: from.ID,
+		Daily:     pgtype.Int8{Int64: 500, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(500), limits.Daily.Int64)
+	require.False(t, limits.PerTransfer.Valid)
+
+	// setting the limits again replaces them
+	limits, err = testQueries.SetAccountLimits(ctx, SetAccountLimitsParams{
+		AccountID:   from.ID,
+		PerTransfer: pgtype.Int8{Int64: 100, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), limits.PerTransfer.Int64)
+	require.False(t, limits.Daily.Valid)
+
+	got, err := testQueries.GetAccountLimits(ctx, from.ID)
+	require.NoError(t, err)
+	require.Equal(t, limits.PerTransfer, got.PerTransfer)
+	require.Equal(t, limits.Daily, got.Daily)
+}
+
+func TestTransferTxLimits(t *testing.T) {
+	ctx := context.Background()
+	from, to := createFundedAccounts(t, 1000)
+	store := NewStore(testPool, WithTransferLimits(map[string]util.TransferLimits{
+		from.Currency: {PerTransfer: 300, Daily: 500},
+	}))
+
+	transferAmount := func(amount int64) error {
+		_, err := store.TransferTx(ctx, TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount})
+		return err
+	}
+
+	var limitErr *LimitExceededError
+	err := transferAmount(301)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, LimitPerTransfer, limitErr.Limit)
+	require.Equal(t, int64(300), limitErr.Max)
+
+	require.NoError(t, transferAmount(300))
+	require.NoError(t, transferAmount(150))
+
+	// 450 of the daily 500 are used
+	err = transferAmount(100)
+	require.ErrorIs(t, err, ErrLimitExceeded)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, LimitDaily, limitErr.Limit)
+	require.Equal(t, int64(450), limitErr.Used)
+	require.Equal(t, int64(100), limitErr.Requested)
+
+	// funds reserved by a hold count as sent
+	_, err = testQueries.SetAccountLimits(ctx, SetAccountLimitsParams{
+		AccountID: from.ID,
+		Daily:     pgtype.Int8{Int64: 600, Valid: true},
+	})
+	require.NoError(t, err)
+	_, err = store.AuthorizeTx(ctx, AuthorizeTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 100, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	err = transferAmount(100)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, int64(550), limitErr.Used)
+	require.NoError(t, transferAmount(50))
+
+	// each leg of a batch counts the legs before it
+	_, err = testQueries.SetAccountLimits(ctx, SetAccountLimitsParams{
+		AccountID: from.ID,
+		Daily:     pgtype.Int8{Int64: 700, Valid: true},
+	})
+	require.NoError(t, err)
+	_, err = store.BatchTransferTx(ctx, BatchTransferTxParams{Legs: []TransferTxParams{
+		{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 60},
+		{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 60},
+	}})
+	var legErr *BatchLegError
+	require.ErrorAs(t, err, &legErr)
+	require.Equal(t, 1, legErr.Leg)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, int64(660), limitErr.Used)
+}
+
+func TestTransferTxLimitsCountHoldsInTheirPeriod(t *testing.T) {
+	ctx := context.Background()
+	from, to := createFundedAccounts(t, 1000)
+	store := NewStore(testPool, WithTransferLimits(map[string]util.TransferLimits{
+		from.Currency: {Daily: 500},
+	}))
+
+	hold, err := store.AuthorizeTx(ctx, AuthorizeTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 400, ExpiresAt: time.Now().Add(48 * time.Hour)})
+	require.NoError(t, err)
+
+	// while the hold was placed today it counts towards today's limit
+	_, err = store.TransferTx(ctx, TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 200})
+	var limitErr *LimitExceededError
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, int64(400), limitErr.Used)
+
+	// a hold placed yesterday and still pending was counted yesterday, so it leaves today's limit alone
+	_, err = testQueries.db.Exec(ctx, "UPDATE holds SET created_at = now() - interval '1 day' WHERE id = $1", hold.Hold.ID)
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 500})
+	require.NoError(t, err)
+}
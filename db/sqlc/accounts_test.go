@@ -8,6 +8,7 @@ import (
 	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -125,6 +126,58 @@ func TestListAccounts(t *testing.T) {
 	}
 }
 
+func TestListAccountsKeyset(t *testing.T) {
+	ctx := context.Background()
+	user, _ := createRandomUser(t)
+
+	var created []Account
+	for _, currency := range []string{util.USD, util.EUR, util.CAD} {
+		acc, _ := createRandomAccountForUser(t, user.Username, currency)
+		created = append(created, acc)
+	}
+
+	t.Cleanup(func() {
+		for _, acc := range created {
+			_ = testQueries.DeleteAccount(ctx, acc.ID)
+		}
+		deleteUser(t, user.Username)
+	})
+
+	// oldest first
+	accounts, err := testQueries.ListAccountsAfter(ctx, ListAccountsAfterParams{
+		Owner:           user.Username,
+		CursorCreatedAt: pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Valid: true},
+		CursorID:        0,
+		PageLimit:       2,
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.Equal(t, created[0].ID, accounts[0].ID)
+	require.Equal(t, created[1].ID, accounts[1].ID)
+
+	accounts, err = testQueries.ListAccountsAfter(ctx, ListAccountsAfterParams{
+		Owner:           user.Username,
+		CursorCreatedAt: accounts[1].CreatedAt,
+		CursorID:        accounts[1].ID,
+		PageLimit:       2,
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, created[2].ID, accounts[0].ID)
+
+	// back from the last one, nearest first
+	accounts, err = testQueries.ListAccountsBefore(ctx, ListAccountsBeforeParams{
+		Owner:           user.Username,
+		CursorCreatedAt: created[2].CreatedAt,
+		CursorID:        created[2].ID,
+		PageLimit:       2,
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.Equal(t, created[1].ID, accounts[0].ID)
+	require.Equal(t, created[0].ID, accounts[1].ID)
+}
+
 func TestChangeAccountBalance(t *testing.T) {
 	ctx := context.Background()
 	acc1, _ := createRandomAccount(t)
@@ -164,6 +217,37 @@ func TestGetAccountForUpdate(t *testing.T) {
 	require.Equal(t, acc1.Currency, acc2.Currency)
 }
 
+func TestGetAccountByOwner(t *testing.T) {
+	ctx := context.Background()
+	acc1, _ := createRandomAccount(t)
+
+	t.Cleanup(func() {
+		_ = testQueries.DeleteAccount(ctx, acc1.ID)
+		deleteUser(t, acc1.Owner)
+	})
+
+	user, err := testQueries.GetUser(ctx, acc1.Owner)
+	require.NoError(t, err)
+
+	acc2, err := testQueries.GetAccountByOwner(ctx, GetAccountByOwnerParams{Owner: acc1.Owner, Currency: acc1.Currency})
+	require.NoError(t, err)
+	require.Equal(t, acc1.ID, acc2.ID)
+
+	acc2, err = testQueries.GetAccountByOwnerEmail(ctx, GetAccountByOwnerEmailParams{Email: user.Email, Currency: acc1.Currency})
+	require.NoError(t, err)
+	require.Equal(t, acc1.ID, acc2.ID)
+
+	// an unknown email and a currency the user has no account in look the same
+	otherCurrency := util.USD
+	if acc1.Currency == util.USD {
+		otherCurrency = util.EUR
+	}
+	_, err = testQueries.GetAccountByOwnerEmail(ctx, GetAccountByOwnerEmailParams{Email: user.Email, Currency: otherCurrency})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = testQueries.GetAccountByOwnerEmail(ctx, GetAccountByOwnerEmailParams{Email: "unknown." + user.Email, Currency: acc1.Currency})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
 func TestCreateAccountNegativeBalanceConstraint(t *testing.T) {
 	ctx := context.Background()
 	user, _ := createRandomUser(t)
@@ -2,10 +2,12 @@ package db
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -107,3 +109,95 @@ func TestListEntries(t *testing.T) {
 		require.Equal(t, acc.ID, e.AccountID)
 	}
 }
+
+func TestListAccountEntries(t *testing.T) {
+	ctx := context.Background()
+	acc, _ := createRandomAccount(t)
+
+	amounts := []int64{100, -50, 300, -700, 20}
+	var created []Entry
+	for _, amount := range amounts {
+		entry, err := testQueries.CreateEntry(ctx, CreateEntryParams{AccountID: acc.ID, Amount: amount})
+		require.NoError(t, err)
+		created = append(created, entry)
+	}
+
+	t.Cleanup(func() {
+		for _, entry := range created {
+			deleteEntry(t, entry.ID)
+		}
+		_ = testQueries.DeleteAccount(ctx, acc.ID)
+		deleteUser(t, acc.Owner)
+	})
+
+	// newest first
+	end := pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}
+	entries, err := testQueries.ListAccountEntriesBefore(ctx, ListAccountEntriesBeforeParams{
+		AccountID:       acc.ID,
+		CursorCreatedAt: end,
+		CursorID:        math.MaxInt64,
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, len(amounts))
+	require.Equal(t, created[len(created)-1].ID, entries[0].ID)
+
+	entries, err = testQueries.ListAccountEntriesBefore(ctx, ListAccountEntriesBeforeParams{
+		AccountID:       acc.ID,
+		CursorCreatedAt: end,
+		CursorID:        math.MaxInt64,
+		Direction:       pgtype.Text{String: "outgoing", Valid: true},
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.Negative(t, entry.Amount)
+	}
+
+	entries, err = testQueries.ListAccountEntriesBefore(ctx, ListAccountEntriesBeforeParams{
+		AccountID:       acc.ID,
+		CursorCreatedAt: end,
+		CursorID:        math.MaxInt64,
+		MinAmount:       pgtype.Int8{Int64: 50, Valid: true},
+		MaxAmount:       pgtype.Int8{Int64: 300, Valid: true},
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	entries, err = testQueries.ListAccountEntriesBefore(ctx, ListAccountEntriesBeforeParams{
+		AccountID:       acc.ID,
+		CursorCreatedAt: end,
+		CursorID:        math.MaxInt64,
+		StartTime:       pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// the page after the fourth entry holds only the oldest one
+	fourth := created[1]
+	entries, err = testQueries.ListAccountEntriesBefore(ctx, ListAccountEntriesBeforeParams{
+		AccountID:       acc.ID,
+		CursorCreatedAt: fourth.CreatedAt,
+		CursorID:        fourth.ID,
+		EndTime:         pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+		PageLimit:       2,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, created[0].ID, entries[0].ID)
+
+	// and paging back from it returns the two entries before, oldest first
+	entries, err = testQueries.ListAccountEntriesAfter(ctx, ListAccountEntriesAfterParams{
+		AccountID:       acc.ID,
+		CursorCreatedAt: fourth.CreatedAt,
+		CursorID:        fourth.ID,
+		PageLimit:       2,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, created[2].ID, entries[0].ID)
+	require.Equal(t, created[3].ID, entries[1].ID)
+}
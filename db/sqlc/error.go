@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	ForeignKeyViolation = "23503"
+	UniqueViolation     = "23505"
+	CheckViolation      = "23514"
+
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+)
+
+var (
+	// ErrRecordNotFound is returned when a query that expects a row finds none
+	ErrRecordNotFound = pgx.ErrNoRows
+
+	// ErrUniqueViolation is matched by errors that break a unique or primary key constraint
+	ErrUniqueViolation = errors.New("unique constraint violation")
+
+	// ErrForeignKeyViolation is matched by errors that reference a missing row
+	ErrForeignKeyViolation = errors.New("foreign key constraint violation")
+
+	// ErrCheckViolation is matched by errors that break a CHECK constraint
+	ErrCheckViolation = errors.New("check constraint violation")
+
+	// ErrIdempotencyKeyReused is returned when an idempotency key is replayed with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+	// ErrQuoteExpired is returned when an FX quote is used after its rate lock ran out
+	ErrQuoteExpired = errors.New("fx quote has expired")
+
+	// ErrQuoteUsed is returned when an FX quote has already been used by another transfer
+	ErrQuoteUsed = errors.New("fx quote has already been used")
+
+	// ErrQuoteMismatch is matched by errors returned when a transfer does not match the FX quote it uses
+	ErrQuoteMismatch = errors.New("transfer does not match the fx quote")
+
+	// ErrAmountOutOfRange is matched by errors returned when the converted amount of an FX transfer
+	// does not fit in the balance of its destination account
+	ErrAmountOutOfRange = errors.New("converted amount is out of range")
+
+	// ErrInvalidReversalAmount is matched by errors returned when a reversal asks for more than is left
+	// to reverse, or for too little to refund anything in the destination account's currency
+	ErrInvalidReversalAmount = errors.New("invalid reversal amount")
+
+	// ErrReversalOfReversal is returned when a reversal transfer is itself reversed
+	ErrReversalOfReversal = errors.New("reversal transfers cannot be reversed")
+
+	// ErrScheduledTransferMismatch is matched by errors returned when a scheduled transfer no longer
+	// matches its accounts, because the source account changed owner or the currencies differ
+	ErrScheduledTransferMismatch = errors.New("scheduled transfer does not match its accounts")
+
+	// ErrHoldClosed is matched by errors returned when a hold that was already captured, voided or expired is settled
+	ErrHoldClosed = errors.New("hold is no longer pending")
+
+	// ErrHoldExpired is returned when a pending hold is captured after it expired
+	ErrHoldExpired = errors.New("hold has expired")
+
+	// ErrInvalidCaptureAmount is matched by errors returned when a capture asks for more than the held amount
+	ErrInvalidCaptureAmount = errors.New("capture exceeds the held amount")
+
+	// ErrResetCodeInvalid is returned when a password reset code is unknown or was already used
+	ErrResetCodeInvalid = errors.New("password reset code is invalid")
+
+	// ErrResetCodeExpired is returned when a password reset code is used after it expired
+	ErrResetCodeExpired = errors.New("password reset code has expired")
+
+	// ErrVerificationCodeInvalid is returned when an email verification code is unknown or was already used
+	ErrVerificationCodeInvalid = errors.New("email verification code is invalid")
+
+	// ErrVerificationCodeExpired is returned when an email verification code is used after it expired
+	ErrVerificationCodeExpired = errors.New("email verification code has expired")
+
+	// ErrInsufficientFunds is matched by errors returned when a transfer would overdraw its source account
+	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrLimitExceeded is matched by errors returned when a transfer would break a transfer limit of its source account
+	ErrLimitExceeded = errors.New("transfer limit exceeded")
+)
+
+// InsufficientFundsError reports how much the source account of a rejected transfer holds
+type InsufficientFundsError struct {
+	AccountID int64
+	Available int64
+	Requested int64
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient funds in account %d: available %d, requested %d", e.AccountID, e.Available, e.Requested)
+}
+
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == ErrInsufficientFunds
+}
+
+// LimitExceededError reports which transfer limit of its source account a rejected transfer would break
+type LimitExceededError struct {
+	AccountID int64
+	// Limit is LimitPerTransfer, LimitDaily or LimitMonthly
+	Limit string
+	Max   int64
+	// Used is how much the account already sent or holds in the limit's period
+	Used      int64
+	Requested int64
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s transfer limit of account %d exceeded: limit %d, used %d, requested %d", e.Limit, e.AccountID, e.Max, e.Used, e.Requested)
+}
+
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// Error is a classified Postgres error. errors.Is matches it against its class,
+// e.g. ErrUniqueViolation, and errors.As still reaches the *pgconn.PgError.
+type Error struct {
+	class error
+	pgErr *pgconn.PgError
+}
+
+func (e *Error) Error() string {
+	return e.pgErr.Error()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.class
+}
+
+func (e *Error) Unwrap() error {
+	return e.pgErr
+}
+
+// ConstraintName returns the name of the violated constraint
+func (e *Error) ConstraintName() string {
+	return e.pgErr.ConstraintName
+}
+
+// ClassifyError wraps Postgres constraint violations so they can be matched with errors.Is.
+// Other errors are returned unchanged.
+func ClassifyError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	var class error
+	switch pgErr.Code {
+	case UniqueViolation:
+		class = ErrUniqueViolation
+	case ForeignKeyViolation:
+		class = ErrForeignKeyViolation
+	case CheckViolation:
+		class = ErrCheckViolation
+	default:
+		return err
+	}
+
+	return &Error{class: class, pgErr: pgErr}
+}
+
+// classifyingDBTX classifies the errors of every query run through it
+type classifyingDBTX struct {
+	DBTX
+}
+
+func (db classifyingDBTX) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := db.DBTX.Exec(ctx, sql, args...)
+	return tag, ClassifyError(err)
+}
+
+func (db classifyingDBTX) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := db.DBTX.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, ClassifyError(err)
+	}
+	return classifyingRows{rows}, nil
+}
+
+func (db classifyingDBTX) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return classifyingRow{db.DBTX.QueryRow(ctx, sql, args...)}
+}
+
+type classifyingRows struct {
+	pgx.Rows
+}
+
+func (rows classifyingRows) Err() error {
+	return ClassifyError(rows.Rows.Err())
+}
+
+func (rows classifyingRows) Scan(dest ...any) error {
+	return ClassifyError(rows.Rows.Scan(dest...))
+}
+
+type classifyingRow struct {
+	pgx.Row
+}
+
+func (row classifyingRow) Scan(dest ...any) error {
+	return ClassifyError(row.Row.Scan(dest...))
+}
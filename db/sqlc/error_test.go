@@ -0,0 +1,48 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		name  string
+		err   error
+		class error
+	}{
+		{"UniqueViolation", &pgconn.PgError{Code: UniqueViolation}, ErrUniqueViolation},
+		{"ForeignKeyViolation", &pgconn.PgError{Code: ForeignKeyViolation}, ErrForeignKeyViolation},
+		{"CheckViolation", &pgconn.PgError{Code: CheckViolation}, ErrCheckViolation},
+		{"WrappedViolation", fmt.Errorf("tx err: %w", &pgconn.PgError{Code: UniqueViolation}), ErrUniqueViolation},
+		{"NoRows", pgx.ErrNoRows, ErrRecordNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ClassifyError(tc.err)
+			require.ErrorIs(t, err, tc.class)
+
+			var pgErr *pgconn.PgError
+			if errors.As(tc.err, &pgErr) {
+				require.ErrorAs(t, err, &pgErr)
+			}
+		})
+	}
+
+	t.Run("OtherPgError", func(t *testing.T) {
+		pgErr := &pgconn.PgError{Code: "40001"}
+		err := ClassifyError(pgErr)
+		require.Equal(t, pgErr, err)
+		require.NotErrorIs(t, err, ErrUniqueViolation)
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		require.NoError(t, ClassifyError(nil))
+	})
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// TxOptions configures how ExecTx runs a transaction
+type TxOptions struct {
+	IsoLevel pgx.TxIsoLevel
+	ReadOnly bool
+
+	// MaxRetries is how many times a transaction failing with a serialization
+	// failure or a deadlock is run again. Zero disables retries.
+	MaxRetries int
+
+	// The delay before retry n is a random duration up to min(MaxBackoff, BaseBackoff * 2^n)
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultTxOptions runs transactions at the database's default isolation level
+// and retries serialization failures and deadlocks a few times
+var DefaultTxOptions = TxOptions{
+	MaxRetries:  3,
+	BaseBackoff: 10 * time.Millisecond,
+	MaxBackoff:  200 * time.Millisecond,
+}
+
+// ExecTx executes a function within a database transaction. The whole function is
+// run again when the transaction fails with a retryable error, so it must not have
+// side effects outside the transaction other than overwriting its results.
+func (store *SQLStore) ExecTx(ctx context.Context, opts TxOptions, txFunc func(*Queries) error) error {
+	for attempt := 0; ; attempt++ {
+		err := store.runTx(ctx, opts, txFunc)
+		if err == nil || attempt >= opts.MaxRetries || !isRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(opts.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (store *SQLStore) runTx(ctx context.Context, opts TxOptions, txFunc func(*Queries) error) error {
+	accessMode := pgx.ReadWrite
+	if opts.ReadOnly {
+		accessMode = pgx.ReadOnly
+	}
+
+	tx, err := store.connPool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   opts.IsoLevel,
+		AccessMode: accessMode,
+	})
+	if err != nil {
+		return err
+	}
+
+	q := New(classifyingDBTX{tx})
+	err = txFunc(q)
+	if err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return ClassifyError(tx.Commit(ctx))
+}
+
+// backoff returns the delay before the given retry using exponential backoff with full jitter
+func (opts TxOptions) backoff(attempt int) time.Duration {
+	if opts.BaseBackoff <= 0 {
+		return 0
+	}
+
+	ceiling := opts.BaseBackoff << min(attempt, 30)
+	if ceiling <= 0 || (opts.MaxBackoff > 0 && ceiling > opts.MaxBackoff) {
+		ceiling = opts.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return rand.N(ceiling + 1)
+}
+
+// isRetryable reports whether running the transaction again may succeed
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == SerializationFailure || pgErr.Code == DeadlockDetected
+}
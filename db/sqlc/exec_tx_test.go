@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxOptionsBackoff(t *testing.T) {
+	opts := TxOptions{BaseBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+	for attempt := 0; attempt < 100; attempt++ {
+		ceiling := min(opts.BaseBackoff<<min(attempt, 30), opts.MaxBackoff)
+		if attempt > 30 {
+			ceiling = opts.MaxBackoff
+		}
+		delay := opts.backoff(attempt)
+		require.GreaterOrEqual(t, delay, time.Duration(0))
+		require.LessOrEqual(t, delay, ceiling)
+	}
+
+	require.Zero(t, TxOptions{}.backoff(3))
+}
+
+func TestIsRetryable(t *testing.T) {
+	require.True(t, isRetryable(&pgconn.PgError{Code: SerializationFailure}))
+	require.True(t, isRetryable(fmt.Errorf("tx err: %w", &pgconn.PgError{Code: DeadlockDetected})))
+	require.False(t, isRetryable(&pgconn.PgError{Code: UniqueViolation}))
+	require.False(t, isRetryable(ErrInsufficientFunds))
+	require.False(t, isRetryable(pgx.ErrNoRows))
+}
+
+func TestExecTxRetriesRetryableErrors(t *testing.T) {
+	store := NewStore(testPool)
+
+	testCases := []struct {
+		name         string
+		err          error
+		wantAttempts int
+	}{
+		{"SerializationFailure", &pgconn.PgError{Code: SerializationFailure}, 3},
+		{"Deadlock", &pgconn.PgError{Code: DeadlockDetected}, 3},
+		{"OtherError", errors.New("boom"), 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			err := store.ExecTx(context.Background(), TxOptions{MaxRetries: 2}, func(q *Queries) error {
+				attempts++
+				return tc.err
+			})
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.wantAttempts, attempts)
+		})
+	}
+}
+
+func TestExecTxSerializableConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	account, _ := createRandomAccount(t)
+	t.Cleanup(func() {
+		_ = testQueries.DeleteAccount(ctx, account.ID)
+		deleteUser(t, account.Owner)
+	})
+
+	opts := TxOptions{
+		IsoLevel:    pgx.Serializable,
+		MaxRetries:  20,
+		BaseBackoff: 5 * time.Millisecond,
+		MaxBackoff:  50 * time.Millisecond,
+	}
+
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			errs <- store.ExecTx(ctx, opts, func(q *Queries) error {
+				current, err := q.GetAccount(ctx, account.ID)
+				if err != nil {
+					return err
+				}
+				_, err = q.UpdateAccount(ctx, UpdateAccountParams{
+					ID:      account.ID,
+					Balance: current.Balance + 1,
+				})
+				return err
+			})
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	updated, err := store.GetAccount(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+int64(n), updated.Balance)
+}
+
+func TestExecTxReadOnly(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	account, _ := createRandomAccount(t)
+	t.Cleanup(func() {
+		_ = testQueries.DeleteAccount(ctx, account.ID)
+		deleteUser(t, account.Owner)
+	})
+
+	err := store.ExecTx(ctx, TxOptions{ReadOnly: true}, func(q *Queries) error {
+		_, err := q.UpdateAccount(ctx, UpdateAccountParams{ID: account.ID, Balance: account.Balance + 1})
+		return err
+	})
+
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, "25006", pgErr.Code) // read_only_sql_transaction
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/brianvoe/gofakeit/v7"
+	"github.com/google/uuid"
 )
 
 var supportedCurrencies = []string{util.USD, util.EUR, util.CAD}
@@ -112,3 +113,51 @@ func deleteTransfer(t *testing.T, transfer_id int64) {
 		t.Fatalf("expected to delete 1 row, deleted %d", tag.RowsAffected())
 	}
 }
+
+func deleteSession(t *testing.T, sessionID uuid.UUID) {
+	t.Helper()
+
+	tag, err := testQueries.db.Exec(
+		context.Background(),
+		"DELETE FROM sessions WHERE id = $1",
+		sessionID,
+	)
+
+	if err != nil {
+		t.Fatal("Cannot delete session:", err)
+	}
+	if tag.RowsAffected() != 1 {
+		t.Fatalf("expected to delete 1 row, deleted %d", tag.RowsAffected())
+	}
+}
+
+func deleteRevokedToken(t *testing.T, tokenID uuid.UUID) {
+	t.Helper()
+
+	tag, err := testQueries.db.Exec(
+		context.Background(),
+		"DELETE FROM revoked_tokens WHERE id = $1",
+		tokenID,
+	)
+
+	if err != nil {
+		t.Fatal("Cannot delete revoked token:", err)
+	}
+	if tag.RowsAffected() != 1 {
+		t.Fatalf("expected to delete 1 row, deleted %d", tag.RowsAffected())
+	}
+}
+
+func deleteIdempotencyKeys(t *testing.T, username string) {
+	t.Helper()
+
+	_, err := testQueries.db.Exec(
+		context.Background(),
+		"DELETE FROM idempotency_keys WHERE username = $1",
+		username,
+	)
+
+	if err != nil {
+		t.Fatal("Cannot delete idempotency keys:", err)
+	}
+}
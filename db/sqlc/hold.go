@@ -0,0 +1,9 @@
+package db
+
+// Statuses of a hold. Only pending holds reserve funds; the others are final.
+const (
+	HoldPending  = "pending"
+	HoldCaptured = "captured"
+	HoldVoided   = "voided"
+	HoldExpired  = "expired"
+)
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRevokedToken(t *testing.T) {
+	ctx := context.Background()
+	user, _ := createRandomUser(t)
+
+	arg := CreateRevokedTokenParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	t.Cleanup(func() {
+		deleteRevokedToken(t, arg.ID)
+		deleteUser(t, user.Username)
+	})
+
+	err := testQueries.CreateRevokedToken(ctx, arg)
+	require.NoError(t, err)
+
+	// revoking the same token twice is a no-op
+	err = testQueries.CreateRevokedToken(ctx, arg)
+	require.NoError(t, err)
+
+	tokens, err := testQueries.ListActiveRevokedTokens(ctx)
+	require.NoError(t, err)
+
+	var found bool
+	for _, token := range tokens {
+		if token.ID == arg.ID {
+			found = true
+			require.Equal(t, user.Username, token.Username)
+			require.WithinDuration(t, arg.ExpiresAt.Time, token.ExpiresAt.Time, time.Second)
+			require.NotZero(t, token.RevokedAt)
+		}
+	}
+	require.True(t, found)
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	ctx := context.Background()
+	user, _ := createRandomUser(t)
+	t.Cleanup(func() {
+		deleteUser(t, user.Username)
+	})
+
+	expiredID := uuid.New()
+	err := testQueries.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+		ID:        expiredID,
+		Username:  user.Username,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	tokens, err := testQueries.ListActiveRevokedTokens(ctx)
+	require.NoError(t, err)
+	for _, token := range tokens {
+		require.NotEqual(t, expiredID, token.ID)
+	}
+
+	err = testQueries.DeleteExpiredRevokedTokens(ctx)
+	require.NoError(t, err)
+
+	var count int
+	err = testPool.QueryRow(ctx, "SELECT count(*) FROM revoked_tokens WHERE id = $1", expiredID).Scan(&count)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
@@ -0,0 +1,17 @@
+package db
+
+// Statuses of a scheduled transfer. Only active ones are picked up by the scheduler,
+// and completed or cancelled ones can no longer be changed.
+const (
+	ScheduleActive    = "active"
+	SchedulePaused    = "paused"
+	ScheduleCompleted = "completed"
+	ScheduleCancelled = "cancelled"
+)
+
+// Outcomes of a single run of a scheduled transfer
+const (
+	RunPending   = "pending"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
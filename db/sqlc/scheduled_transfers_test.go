@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// createRandomScheduledTransfer creates a daily scheduled transfer between two funded accounts starting at startAt
+func createRandomScheduledTransfer(t *testing.T, startAt time.Time) (ScheduledTransfer, Account, Account) {
+	t.Helper()
+	ctx := context.Background()
+	from, to := createFundedAccounts(t, 1000)
+
+	schedule, err := testQueries.CreateScheduledTransfer(ctx, CreateScheduledTransferParams{
+		Owner:         from.Owner,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        100,
+		Frequency:     util.DailyFrequency,
+		IntervalCount: 1,
+		StartAt:       pgtype.Timestamptz{Time: startAt, Valid: true},
+		MaxRuns:       pgtype.Int4{Int32: 3, Valid: true},
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM scheduled_transfer_runs WHERE scheduled_transfer_id = $1", schedule.ID)
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM scheduled_transfers WHERE id = $1", schedule.ID)
+	})
+
+	return schedule, from, to
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	startAt := time.Now().Add(time.Hour)
+	schedule, from, to := createRandomScheduledTransfer(t, startAt)
+
+	require.NotZero(t, schedule.ID)
+	require.Equal(t, from.Owner, schedule.Owner)
+	require.Equal(t, from.ID, schedule.FromAccountID)
+	require.Equal(t, to.ID, schedule.ToAccountID)
+	require.Equal(t, ScheduleActive, schedule.Status)
+	require.Zero(t, schedule.RunCount)
+	require.False(t, schedule.EndAt.Valid)
+	require.WithinDuration(t, startAt, schedule.NextRunAt.Time, time.Millisecond)
+	require.WithinDuration(t, schedule.StartAt.Time, schedule.NextRunAt.Time, 0)
+}
+
+func TestUpdateScheduledTransfer(t *testing.T) {
+	ctx := context.Background()
+	schedule, _, _ := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	updated, err := testQueries.UpdateScheduledTransfer(ctx, UpdateScheduledTransferParams{
+		ID:     schedule.ID,
+		Amount: pgtype.Int8{Int64: 250, Valid: true},
+		Status: pgtype.Text{String: SchedulePaused, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(250), updated.Amount)
+	require.Equal(t, SchedulePaused, updated.Status)
+	require.Equal(t, schedule.MaxRuns, updated.MaxRuns)
+
+	cancelled, err := testQueries.CancelScheduledTransfer(ctx, schedule.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduleCancelled, cancelled.Status)
+
+	// cancelled schedules can no longer be changed
+	_, err = testQueries.UpdateScheduledTransfer(ctx, UpdateScheduledTransferParams{
+		ID:     schedule.ID,
+		Status: pgtype.Text{String: ScheduleActive, Valid: true},
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = testQueries.CancelScheduledTransfer(ctx, schedule.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestClaimDueScheduledTransfers(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool).(*SQLStore)
+	due, _, _ := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+	notDue, _, _ := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	claimedIDs := func(schedules []ScheduledTransfer) map[int64]bool {
+		ids := make(map[int64]bool)
+		for _, schedule := range schedules {
+			ids[schedule.ID] = true
+		}
+		return ids
+	}
+
+	// while one transaction holds the due schedule, another one skips it
+	tx, err := testPool.Begin(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback(ctx)
+
+	schedules, err := New(tx).ClaimDueScheduledTransfers(ctx, 1000)
+	require.NoError(t, err)
+	ids := claimedIDs(schedules)
+	require.True(t, ids[due.ID])
+	require.False(t, ids[notDue.ID])
+
+	err = store.ExecTx(ctx, DefaultTxOptions, func(q *Queries) error {
+		schedules, err := q.ClaimDueScheduledTransfers(ctx, 1000)
+		require.False(t, claimedIDs(schedules)[due.ID])
+		return err
+	})
+	require.NoError(t, err)
+
+	nextRunAt := due.NextRunAt.Time.AddDate(0, 0, 1)
+	advanced, err := New(tx).AdvanceScheduledTransfer(ctx, AdvanceScheduledTransferParams{
+		ID:        due.ID,
+		NextRunAt: pgtype.Timestamptz{Time: nextRunAt, Valid: true},
+		Status:    ScheduleActive,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), advanced.RunCount)
+	require.WithinDuration(t, nextRunAt, advanced.NextRunAt.Time, time.Millisecond)
+	require.NoError(t, tx.Commit(ctx))
+
+	// once advanced it is no longer due
+	err = store.ExecTx(ctx, DefaultTxOptions, func(q *Queries) error {
+		schedules, err := q.ClaimDueScheduledTransfers(ctx, 1000)
+		require.False(t, claimedIDs(schedules)[due.ID])
+		return err
+	})
+	require.NoError(t, err)
+}
+
+func TestScheduledTransferRuns(t *testing.T) {
+	ctx := context.Background()
+	schedule, from, to := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+
+	run, err := testQueries.CreateScheduledTransferRun(ctx, CreateScheduledTransferRunParams{
+		ScheduledTransferID: schedule.ID,
+		ScheduledFor:        schedule.NextRunAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, RunPending, run.Status)
+	require.False(t, run.FinishedAt.Valid)
+
+	transfer, err := testQueries.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        schedule.Amount,
+	})
+	require.NoError(t, err)
+
+	finished, err := testQueries.FinishScheduledTransferRun(ctx, FinishScheduledTransferRunParams{
+		ID:         run.ID,
+		Status:     RunSucceeded,
+		TransferID: pgtype.Int8{Int64: transfer.ID, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, RunSucceeded, finished.Status)
+	require.Equal(t, transfer.ID, finished.TransferID.Int64)
+	require.True(t, finished.FinishedAt.Valid)
+
+	failed, err := testQueries.CreateScheduledTransferRun(ctx, CreateScheduledTransferRunParams{
+		ScheduledTransferID: schedule.ID,
+		ScheduledFor:        schedule.NextRunAt,
+	})
+	require.NoError(t, err)
+	failed, err = testQueries.FinishScheduledTransferRun(ctx, FinishScheduledTransferRunParams{
+		ID:     failed.ID,
+		Status: RunFailed,
+		Error:  pgtype.Text{String: "insufficient funds", Valid: true},
+	})
+	require.NoError(t, err)
+
+	// newest first
+	runs, err := testQueries.ListScheduledTransferRunsBefore(ctx, ListScheduledTransferRunsBeforeParams{
+		ScheduledTransferID: schedule.ID,
+		CursorCreatedAt:     pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true},
+		CursorID:            failed.ID + 1,
+		PageLimit:           10,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.Equal(t, failed.ID, runs[0].ID)
+	require.Equal(t, "insufficient funds", runs[0].Error.String)
+	require.Equal(t, run.ID, runs[1].ID)
+}
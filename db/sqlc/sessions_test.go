@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T, username string) (Session, CreateSessionParams) {
+	t.Helper()
+	arg := CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     username,
+		RefreshToken: gofakeit.LetterN(64),
+		UserAgent:    gofakeit.UserAgent(),
+		ClientIp:     gofakeit.IPv4Address(),
+		IsBlocked:    false,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	if err != nil {
+		t.Fatal("Cannot create session:", err)
+	}
+	return session, arg
+}
+
+func TestCreateSession(t *testing.T) {
+	user, _ := createRandomUser(t)
+	session, arg := createRandomSession(t, user.Username)
+	t.Cleanup(func() {
+		deleteSession(t, session.ID)
+		deleteUser(t, user.Username)
+	})
+
+	require.NotEmpty(t, session)
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.RefreshToken, session.RefreshToken)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.False(t, session.IsBlocked)
+	require.WithinDuration(t, arg.ExpiresAt.Time, session.ExpiresAt.Time, time.Second)
+	require.NotZero(t, session.CreatedAt)
+}
+
+func TestGetSession(t *testing.T) {
+	ctx := context.Background()
+	user, _ := createRandomUser(t)
+	session1, _ := createRandomSession(t, user.Username)
+	t.Cleanup(func() {
+		deleteSession(t, session1.ID)
+		deleteUser(t, user.Username)
+	})
+
+	session2, err := testQueries.GetSession(ctx, session1.ID)
+	require.NoError(t, err)
+	require.Equal(t, session1.ID, session2.ID)
+	require.Equal(t, session1.Username, session2.Username)
+	require.Equal(t, session1.RefreshToken, session2.RefreshToken)
+	require.Equal(t, session1.IsBlocked, session2.IsBlocked)
+	require.WithinDuration(t, session1.ExpiresAt.Time, session2.ExpiresAt.Time, time.Second)
+	require.WithinDuration(t, session1.CreatedAt.Time, session2.CreatedAt.Time, time.Second)
+}
+
+func TestGetSessionNotFound(t *testing.T) {
+	ctx := context.Background()
+	_, err := testQueries.GetSession(ctx, uuid.New())
+	require.EqualError(t, err, pgx.ErrNoRows.Error())
+}
+
+func TestCreateSessionUnknownUser(t *testing.T) {
+	ctx := context.Background()
+	_, err := testQueries.CreateSession(ctx, CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     gofakeit.LetterN(12),
+		RefreshToken: gofakeit.LetterN(64),
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	})
+	require.Error(t, err)
+}
+
+func TestBlockSession(t *testing.T) {
+	ctx := context.Background()
+	user, _ := createRandomUser(t)
+	session, _ := createRandomSession(t, user.Username)
+	t.Cleanup(func() {
+		deleteSession(t, session.ID)
+		deleteUser(t, user.Username)
+	})
+
+	// a session cannot be blocked on behalf of another user
+	err := testQueries.BlockSession(ctx, BlockSessionParams{ID: session.ID, Username: gofakeit.LetterN(12)})
+	require.NoError(t, err)
+	got, err := testQueries.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.False(t, got.IsBlocked)
+
+	err = testQueries.BlockSession(ctx, BlockSessionParams{ID: session.ID, Username: user.Username})
+	require.NoError(t, err)
+	got, err = testQueries.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.True(t, got.IsBlocked)
+}
+
+func TestBlockUserSessions(t *testing.T) {
+	ctx := context.Background()
+	user, _ := createRandomUser(t)
+	session1, _ := createRandomSession(t, user.Username)
+	session2, _ := createRandomSession(t, user.Username)
+	t.Cleanup(func() {
+		deleteSession(t, session1.ID)
+		deleteSession(t, session2.ID)
+		deleteUser(t, user.Username)
+	})
+
+	err := testQueries.BlockUserSessions(ctx, user.Username)
+	require.NoError(t, err)
+
+	for _, id := range []uuid.UUID{session1.ID, session2.ID} {
+		got, err := testQueries.GetSession(ctx, id)
+		require.NoError(t, err)
+		require.True(t, got.IsBlocked)
+	}
+}
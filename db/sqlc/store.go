@@ -2,97 +2,171 @@ package db
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
 
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Store is an interface to expose all functions from SQL queries and transactions
 type Store interface {
 	Querier
+	ExecTx(ctx context.Context, opts TxOptions, txFunc func(*Queries) error) error
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ScheduledTransferTx(ctx context.Context, arg ScheduledTransferTxParams) (TransferTxResult, error)
+	FXTransferTx(ctx context.Context, arg FXTransferTxParams) (TransferTxResult, error)
+	BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error)
+	IdempotentTransferTx(ctx context.Context, arg IdempotentTransferTxParams) (IdempotentTransferTxResult, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	AuthorizeTx(ctx context.Context, arg AuthorizeTxParams) (HoldTxResult, error)
+	CaptureTx(ctx context.Context, arg CaptureTxParams) (CaptureTxResult, error)
+	VoidHoldTx(ctx context.Context, holdID int64) (HoldTxResult, error)
+	ExpireHoldsTx(ctx context.Context, limit int32) ([]Hold, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, codeHash string) (User, error)
+	EnableMFATx(ctx context.Context, arg EnableMFATxParams) (User, error)
+	LogoutAllTx(ctx context.Context, username string) (User, error)
+	UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
 }
 
 // SQLStore provides all functions to execute db queries and transactions
 type SQLStore struct {
 	*Queries
-	connPool *pgxpool.Pool
+	connPool       *pgxpool.Pool
+	transferLimits map[string]util.TransferLimits
 }
 
 // NewStore creates a new Store
-func NewStore(connPool *pgxpool.Pool) Store {
-	return &SQLStore{
+func NewStore(connPool *pgxpool.Pool, opts ...StoreOption) Store {
+	store := &SQLStore{
 		connPool: connPool,
-		Queries:  New(connPool),
+		Queries:  New(classifyingDBTX{connPool}),
 	}
+	for _, opt := range opts {
+		opt(store)
+	}
+	return store
 }
 
-// execTx executes a function within a database transaction
+// execTx executes a function within a database transaction using the default options
 func (store *SQLStore) execTx(ctx context.Context, txFunc func(*Queries) error) error {
-	tx, err := store.connPool.Begin(ctx)
-	if err != nil {
-		return err
-	}
-
-	q := New(tx)
-	err = txFunc(q)
-	if err != nil {
-		if rbErr := tx.Rollback(ctx); rbErr != nil {
-			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
-		}
-		return err
-	}
-
-	return tx.Commit(ctx)
+	return store.ExecTx(ctx, DefaultTxOptions, txFunc)
 }
 
 // TransferTx performs a money transfer from one account to another.
 // It creates a transfer record, add account entries, and update accounts' balance within a single db transaction.
+// It returns an *InsufficientFundsError if the source account cannot cover the amount, and a
+// *LimitExceededError if the amount would break one of the source account's transfer limits.
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
+		result, err = store.transfer(ctx, q, arg)
+		return err
+	})
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
-		if err != nil {
-			return err
-		}
+	return result, err
+}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+// transfer moves the money of a transfer using the queries of an open transaction
+func (store *SQLStore) transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	fromAccount, _, err := lockAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = execChangeBalance(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
+	err = checkFunds(fromAccount, arg.Amount)
+	if err != nil {
+		return result, err
+	}
 
-			if err != nil {
-				return err
-			}
-		} else {
-			result.ToAccount, result.FromAccount, err = execChangeBalance(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	err = store.checkLimits(ctx, q, fromAccount, arg.Amount)
+	if err != nil {
+		return result, err
+	}
 
-			if err != nil {
-				return err
-			}
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
+	if err != nil {
+		return result, err
+	}
+
+	err = postTransfer(ctx, q, &result)
+	return result, err
+}
+
+// checkFunds returns an *InsufficientFundsError if the account's available balance,
+// which leaves out the funds reserved by holds, cannot cover the amount
+func checkFunds(account Account, amount int64) error {
+	if amount > 0 && account.AvailableBalance < amount {
+		return &InsufficientFundsError{
+			AccountID: account.ID,
+			Available: account.AvailableBalance,
+			Requested: amount,
 		}
+	}
+	return nil
+}
 
-		return nil
+// postTransfer creates the entries of result.Transfer and moves its amounts between the account balances
+func postTransfer(ctx context.Context, q *Queries, result *TransferTxResult) error {
+	transfer := result.Transfer
+
+	var err error
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:   transfer.FromAccountID,
+		Amount:      -transfer.Amount,
+		Description: transfer.Description,
+		Reference:   transfer.Reference,
+		Metadata:    transfer.Metadata,
 	})
+	if err != nil {
+		return err
+	}
 
-	return result, err
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:   transfer.ToAccountID,
+		Amount:      transfer.ToAmount,
+		Description: transfer.Description,
+		Reference:   transfer.Reference,
+		Metadata:    transfer.Metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	if transfer.FromAccountID < transfer.ToAccountID {
+		result.FromAccount, result.ToAccount, err = execChangeBalance(ctx, q, transfer.FromAccountID, -transfer.Amount, transfer.ToAccountID, transfer.ToAmount)
+	} else {
+		result.ToAccount, result.FromAccount, err = execChangeBalance(ctx, q, transfer.ToAccountID, transfer.ToAmount, transfer.FromAccountID, -transfer.Amount)
+	}
+
+	return err
+}
+
+// lockAccounts locks both accounts of a transfer in ID order, so that concurrent transfers
+// in opposite directions cannot deadlock, and returns the source and destination accounts
+func lockAccounts(ctx context.Context, q *Queries, fromAccountID int64, toAccountID int64) (fromAccount Account, toAccount Account, err error) {
+	if fromAccountID < toAccountID {
+		fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+		if err != nil {
+			return
+		}
+		toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+		return
+	}
+
+	toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+	if err != nil {
+		return
+	}
+	fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+	return
 }
 
 func execChangeBalance(ctx context.Context,
@@ -124,9 +198,12 @@ func execChangeBalance(ctx context.Context,
 
 // TransferTxParams contains the input parameters of the transfer transaction
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64           `json:"from_account_id"`
+	ToAccountID   int64           `json:"to_account_id"`
+	Amount        int64           `json:"amount"`
+	Description   pgtype.Text     `json:"description"`
+	Reference     pgtype.Text     `json:"reference"`
+	Metadata      json.RawMessage `json:"metadata"`
 }
 
 // TransferTxResult is the result of the transfer transaction
@@ -2,10 +2,12 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -195,6 +197,7 @@ func TestTransferTxSameAccountConstraint(t *testing.T) {
 	var pgErr *pgconn.PgError
 	require.ErrorAs(t, err, &pgErr)
 	require.Equal(t, "23514", pgErr.Code) // check_violation
+	require.ErrorIs(t, err, ErrCheckViolation)
 }
 
 func TestTransferTxNegativeAmountConstraint(t *testing.T) {
@@ -222,6 +225,7 @@ func TestTransferTxNegativeAmountConstraint(t *testing.T) {
 	var pgErr *pgconn.PgError
 	require.ErrorAs(t, err, &pgErr)
 	require.Equal(t, "23514", pgErr.Code) // check_violation
+	require.ErrorIs(t, err, ErrCheckViolation)
 }
 
 func TestTransferTxZeroAmountConstraint(t *testing.T) {
@@ -249,9 +253,10 @@ func TestTransferTxZeroAmountConstraint(t *testing.T) {
 	var pgErr *pgconn.PgError
 	require.ErrorAs(t, err, &pgErr)
 	require.Equal(t, "23514", pgErr.Code) // check_violation
+	require.ErrorIs(t, err, ErrCheckViolation)
 }
 
-func TestTransferTxInsufficientBalanceConstraint(t *testing.T) {
+func TestTransferTxInsufficientFunds(t *testing.T) {
 	ctx := context.Background()
 	store := NewStore(testPool)
 
@@ -274,17 +279,28 @@ func TestTransferTxInsufficientBalanceConstraint(t *testing.T) {
 		deleteUser(t, user1.Username)
 	})
 
-	// transfer more than balance should fail due to balance_non_negative constraint
+	// transfer more than balance should be rejected before any row is written
 	_, err = store.TransferTx(ctx, TransferTxParams{
 		FromAccountID: acc1.ID,
 		ToAccountID:   acc2.ID,
 		Amount:        200, // more than acc1's balance
 	})
-	require.Error(t, err)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
 
-	var pgErr *pgconn.PgError
-	require.ErrorAs(t, err, &pgErr)
-	require.Equal(t, "23514", pgErr.Code) // check_violation
+	var fundsErr *InsufficientFundsError
+	require.ErrorAs(t, err, &fundsErr)
+	require.Equal(t, acc1.ID, fundsErr.AccountID)
+	require.Equal(t, acc1.Balance, fundsErr.Available)
+	require.Equal(t, int64(200), fundsErr.Requested)
+
+	transfers, err := store.ListTransfers(ctx, ListTransfersParams{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Limit:         5,
+		Offset:        0,
+	})
+	require.NoError(t, err)
+	require.Empty(t, transfers)
 
 	// verify acc1 balance is unchanged (transaction should have rolled back)
 	acc1After, err := store.GetAccount(ctx, acc1.ID)
@@ -393,3 +409,67 @@ func TestBilateralTransferTxDeadlock(t *testing.T) {
 		deleteUser(t, user1.Username)
 	})
 }
+
+func TestTransferTxDetails(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFundedAccounts(t, 1000)
+
+	reference := pgtype.Text{String: "INV-" + gofakeit.Numerify("######"), Valid: true}
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        100,
+		Description:   pgtype.Text{String: "March rent", Valid: true},
+		Reference:     reference,
+		Metadata:      json.RawMessage(`{"flat": "4B"}`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "March rent", result.Transfer.Description.String)
+	require.Equal(t, reference, result.Transfer.Reference)
+	require.JSONEq(t, `{"flat": "4B"}`, string(result.Transfer.Metadata))
+
+	// both entries carry the details of their transfer
+	for _, entry := range []Entry{result.FromEntry, result.ToEntry} {
+		require.Equal(t, result.Transfer.Description, entry.Description)
+		require.Equal(t, reference, entry.Reference)
+		require.JSONEq(t, `{"flat": "4B"}`, string(entry.Metadata))
+	}
+
+	// a transfer without details stores NULLs
+	plain, err := store.TransferTx(ctx, TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 10})
+	require.NoError(t, err)
+	require.False(t, plain.Transfer.Reference.Valid)
+	require.Nil(t, plain.Transfer.Metadata)
+
+	transfers, err := testQueries.ListAccountTransfersBefore(ctx, ListAccountTransfersBeforeParams{
+		AccountID:       to.ID,
+		CursorCreatedAt: pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true},
+		CursorID:        plain.Transfer.ID + 1,
+		Reference:       reference,
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, result.Transfer.ID, transfers[0].ID)
+
+	entries, err := testQueries.ListAccountEntriesBefore(ctx, ListAccountEntriesBeforeParams{
+		AccountID:       from.ID,
+		CursorCreatedAt: pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true},
+		CursorID:        plain.FromEntry.ID + 1,
+		Reference:       reference,
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, result.FromEntry.ID, entries[0].ID)
+
+	// metadata must be an object
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        10,
+		Metadata:      json.RawMessage(`["not", "an", "object"]`),
+	})
+	require.ErrorIs(t, err, ErrCheckViolation)
+}
@@ -0,0 +1,9 @@
+package db
+
+// Statuses of a transfer. A transfer is partially reversed until reversals have
+// refunded its whole amount.
+const (
+	TransferCompleted         = "completed"
+	TransferPartiallyReversed = "partially_reversed"
+	TransferReversed          = "reversed"
+)
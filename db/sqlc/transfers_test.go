@@ -2,10 +2,12 @@ package db
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,6 +30,9 @@ func TestCreateTransfer(t *testing.T) {
 	require.Equal(t, arg.FromAccountID, trs.FromAccountID)
 	require.Equal(t, arg.ToAccountID, trs.ToAccountID)
 	require.Equal(t, arg.Amount, trs.Amount)
+	require.Equal(t, arg.Amount, trs.ToAmount)
+	require.Equal(t, float64(1), trs.ExchangeRate)
+	require.Zero(t, trs.SpreadBps)
 
 	t.Cleanup(func() {
 		deleteTransfer(t, trs.ID)
@@ -186,3 +191,105 @@ func TestCreateTransferSameAccountConstraint(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "different_accounts")
 }
+
+func TestListAccountTransfers(t *testing.T) {
+	ctx := context.Background()
+	acc1, _ := createRandomAccount(t)
+	acc2, _ := createRandomAccount(t)
+
+	// acc1 sends 100 and 300 and receives 200 and 400
+	var createdIDs []int64
+	for i, amount := range []int64{100, 200, 300, 400} {
+		fromID, toID := acc1.ID, acc2.ID
+		if i%2 == 1 {
+			fromID, toID = acc2.ID, acc1.ID
+		}
+		trs, err := testQueries.CreateTransfer(ctx, CreateTransferParams{
+			FromAccountID: fromID,
+			ToAccountID:   toID,
+			Amount:        amount,
+		})
+		require.NoError(t, err)
+		createdIDs = append(createdIDs, trs.ID)
+	}
+
+	t.Cleanup(func() {
+		for _, id := range createdIDs {
+			deleteTransfer(t, id)
+		}
+		_ = testQueries.DeleteAccount(ctx, acc2.ID)
+		_ = testQueries.DeleteAccount(ctx, acc1.ID)
+		deleteUser(t, acc2.Owner)
+		deleteUser(t, acc1.Owner)
+	})
+
+	end := pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}
+	transfers, err := testQueries.ListAccountTransfersBefore(ctx, ListAccountTransfersBeforeParams{
+		AccountID:       acc1.ID,
+		CursorCreatedAt: end,
+		CursorID:        math.MaxInt64,
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 4)
+	require.Equal(t, createdIDs[3], transfers[0].ID)
+
+	transfers, err = testQueries.ListAccountTransfersBefore(ctx, ListAccountTransfersBeforeParams{
+		AccountID:       acc1.ID,
+		Direction:       pgtype.Text{String: "incoming", Valid: true},
+		CursorCreatedAt: end,
+		CursorID:        math.MaxInt64,
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	for _, tr := range transfers {
+		require.Equal(t, acc1.ID, tr.ToAccountID)
+	}
+
+	transfers, err = testQueries.ListAccountTransfersBefore(ctx, ListAccountTransfersBeforeParams{
+		AccountID:       acc1.ID,
+		Direction:       pgtype.Text{String: "outgoing", Valid: true},
+		CursorCreatedAt: end,
+		CursorID:        math.MaxInt64,
+		MinAmount:       pgtype.Int8{Int64: 200, Valid: true},
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, int64(300), transfers[0].Amount)
+
+	transfers, err = testQueries.ListAccountTransfersBefore(ctx, ListAccountTransfersBeforeParams{
+		AccountID:       acc1.ID,
+		CursorCreatedAt: end,
+		CursorID:        math.MaxInt64,
+		StartTime:       pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+		EndTime:         pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+		MaxAmount:       pgtype.Int8{Int64: 250, Valid: true},
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+
+	// paging from the oldest transfer
+	oldest, err := testQueries.GetTransfer(ctx, createdIDs[0])
+	require.NoError(t, err)
+	transfers, err = testQueries.ListAccountTransfersAfter(ctx, ListAccountTransfersAfterParams{
+		AccountID:       acc1.ID,
+		CursorCreatedAt: oldest.CreatedAt,
+		CursorID:        oldest.ID,
+		PageLimit:       2,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	require.Equal(t, createdIDs[1], transfers[0].ID)
+
+	transfers, err = testQueries.ListAccountTransfersBefore(ctx, ListAccountTransfersBeforeParams{
+		AccountID:       acc1.ID,
+		CursorCreatedAt: oldest.CreatedAt,
+		CursorID:        oldest.ID,
+		PageLimit:       2,
+	})
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+}
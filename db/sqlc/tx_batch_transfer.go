@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// BatchTransferTxParams contains the legs of a batch transfer, executed in order
+type BatchTransferTxParams struct {
+	Legs []TransferTxParams `json:"legs"`
+}
+
+// BatchTransferTxResult holds the result of every leg of a batch transfer, in the order of the legs
+type BatchTransferTxResult struct {
+	Legs []TransferTxResult `json:"legs"`
+}
+
+// BatchLegError reports the leg of a batch transfer that failed. It unwraps to the leg's
+// error, so an *InsufficientFundsError can still be matched with errors.As.
+type BatchLegError struct {
+	Leg int
+	Err error
+}
+
+func (e *BatchLegError) Error() string {
+	return fmt.Sprintf("leg %d: %v", e.Leg, e.Err)
+}
+
+func (e *BatchLegError) Unwrap() error {
+	return e.Err
+}
+
+// BatchTransferTx performs many transfers atomically: either every leg is applied or none is.
+// All accounts touched by the batch are locked up front in ID order, the same order TransferTx
+// uses, so batches cannot deadlock with each other or with single transfers. Legs are applied in
+// order, so a leg may spend money credited by an earlier one. A failing leg is reported as a
+// *BatchLegError and rolls back the whole batch.
+func (store *SQLStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = store.batchTransfer(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// batchTransfer moves the money of every leg of a batch using the queries of an open transaction
+func (store *SQLStore) batchTransfer(ctx context.Context, q *Queries, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	result := BatchTransferTxResult{Legs: make([]TransferTxResult, 0, len(arg.Legs))}
+
+	accountIDs := make([]int64, 0, 2*len(arg.Legs))
+	for _, leg := range arg.Legs {
+		accountIDs = append(accountIDs, leg.FromAccountID, leg.ToAccountID)
+	}
+	slices.Sort(accountIDs)
+	accountIDs = slices.Compact(accountIDs)
+
+	accounts := make(map[int64]Account, len(accountIDs))
+	for _, id := range accountIDs {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return result, err
+		}
+		accounts[id] = account
+	}
+
+	for i, leg := range arg.Legs {
+		legResult, err := store.transferLockedLeg(ctx, q, accounts[leg.FromAccountID], leg)
+		if err != nil {
+			return result, &BatchLegError{Leg: i, Err: err}
+		}
+
+		accounts[leg.FromAccountID] = legResult.FromAccount
+		accounts[leg.ToAccountID] = legResult.ToAccount
+		result.Legs = append(result.Legs, legResult)
+	}
+
+	return result, nil
+}
+
+// transferLockedLeg moves the money of one leg whose accounts are already locked
+func (store *SQLStore) transferLockedLeg(ctx context.Context, q *Queries, fromAccount Account, leg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := checkFunds(fromAccount, leg.Amount)
+	if err != nil {
+		return result, err
+	}
+
+	// entries of earlier legs in the batch already count towards the daily and monthly limits
+	err = store.checkLimits(ctx, q, fromAccount, leg.Amount)
+	if err != nil {
+		return result, err
+	}
+
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(leg))
+	if err != nil {
+		return result, err
+	}
+
+	err = postTransfer(ctx, q, &result)
+	return result, err
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchTransferTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	payroll, employee1 := createFundedAccounts(t, 1000)
+	relay, employee2 := createFundedAccounts(t, 0)
+
+	result, err := store.BatchTransferTx(ctx, BatchTransferTxParams{Legs: []TransferTxParams{
+		{FromAccountID: payroll.ID, ToAccountID: employee1.ID, Amount: 300},
+		{FromAccountID: payroll.ID, ToAccountID: relay.ID, Amount: 400},
+		// spends what the previous leg credited
+		{FromAccountID: relay.ID, ToAccountID: employee2.ID, Amount: 400},
+	}})
+	require.NoError(t, err)
+	require.Len(t, result.Legs, 3)
+
+	for i, leg := range result.Legs {
+		require.NotZero(t, leg.Transfer.ID, "leg %d", i)
+		require.Equal(t, -leg.Transfer.Amount, leg.FromEntry.Amount)
+		require.Equal(t, leg.Transfer.Amount, leg.ToEntry.Amount)
+	}
+	require.Equal(t, int64(700), result.Legs[0].FromAccount.Balance)
+	require.Equal(t, int64(300), result.Legs[1].FromAccount.Balance)
+	require.Equal(t, int64(0), result.Legs[2].FromAccount.Balance)
+
+	for account, want := range map[Account]int64{
+		payroll:   300,
+		employee1: employee1.Balance + 300,
+		relay:     0,
+		employee2: employee2.Balance + 400,
+	} {
+		updated, err := testQueries.GetAccount(ctx, account.ID)
+		require.NoError(t, err)
+		require.Equal(t, want, updated.Balance)
+	}
+}
+
+func TestBatchTransferTxRollback(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	payroll, employee1 := createFundedAccounts(t, 1000)
+	_, employee2 := createFundedAccounts(t, 0)
+
+	_, err := store.BatchTransferTx(ctx, BatchTransferTxParams{Legs: []TransferTxParams{
+		{FromAccountID: payroll.ID, ToAccountID: employee1.ID, Amount: 600},
+		{FromAccountID: payroll.ID, ToAccountID: employee2.ID, Amount: 600},
+	}})
+
+	var legErr *BatchLegError
+	require.ErrorAs(t, err, &legErr)
+	require.Equal(t, 1, legErr.Leg)
+
+	var fundsErr *InsufficientFundsError
+	require.ErrorAs(t, err, &fundsErr)
+	require.Equal(t, int64(400), fundsErr.Available)
+
+	// the first leg is rolled back too
+	for _, account := range []Account{payroll, employee1, employee2} {
+		updated, err := testQueries.GetAccount(ctx, account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updated.Balance)
+	}
+}
+
+func TestBatchTransferTxDeadlock(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	account1, account2 := createFundedAccounts(t, 10000)
+	account3, account4 := createFundedAccounts(t, 10000)
+	accounts := []Account{account1, account2, account3, account4}
+	for i, account := range accounts {
+		// the destination accounts of createFundedAccounts may be empty
+		var err error
+		accounts[i], err = testQueries.ChangeAccountBalance(ctx, ChangeAccountBalanceParams{ID: account.ID, Amount: 100})
+		require.NoError(t, err)
+	}
+
+	// batches move money around the accounts in opposite directions
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		legs := make([]TransferTxParams, len(accounts))
+		for j := range accounts {
+			from, to := accounts[j], accounts[(j+1)%len(accounts)]
+			if i%2 == 0 {
+				from, to = to, from
+			}
+			legs[j] = TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 10}
+		}
+
+		go func() {
+			_, err := store.BatchTransferTx(ctx, BatchTransferTxParams{Legs: legs})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	// every account paid and received the same amount
+	for _, account := range accounts {
+		updated, err := testQueries.GetAccount(ctx, account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updated.Balance)
+	}
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+)
+
+// ChangePasswordTxParams contains the input parameters of the change password transaction
+type ChangePasswordTxParams struct {
+	Username       string
+	HashedPassword string
+}
+
+// ChangePasswordTx stores the user's new password hash and blocks every session of the user.
+// The password change time it records revokes all tokens issued to the user before it.
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = changePassword(ctx, q, arg)
+		return err
+	})
+
+	return user, err
+}
+
+// changePassword stores the new password hash and blocks the user's sessions using the queries of an open transaction
+func changePassword(ctx context.Context, q *Queries, arg ChangePasswordTxParams) (User, error) {
+	user, err := q.UpdateUserPassword(ctx, UpdateUserPasswordParams(arg))
+	if err != nil {
+		return user, err
+	}
+
+	err = q.BlockUserSessions(ctx, arg.Username)
+	return user, err
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePasswordTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	user, _ := createRandomUser(t)
+	session, _ := createRandomSession(t, user.Username)
+	t.Cleanup(func() {
+		deleteSession(t, session.ID)
+		deleteUser(t, user.Username)
+	})
+
+	hashedPassword, err := util.HashPassword(gofakeit.Password(true, true, true, false, false, 8))
+	require.NoError(t, err)
+
+	got, err := store.ChangePasswordTx(ctx, ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, got.HashedPassword)
+	require.WithinDuration(t, time.Now(), got.PasswordChangedAt.Time, time.Second)
+
+	s, err := store.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.True(t, s.IsBlocked)
+
+	// the password change is reported as a revocation cutoff
+	rows, err := store.ListUserTokenRevocations(ctx, pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true})
+	require.NoError(t, err)
+
+	var found bool
+	for _, row := range rows {
+		if row.Username == user.Username {
+			found = true
+			require.WithinDuration(t, got.PasswordChangedAt.Time, row.TokensRevokedAt.Time, time.Millisecond)
+		}
+	}
+	require.True(t, found)
+}
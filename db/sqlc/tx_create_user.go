@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CreateUserTxParams contains the input parameters of the create user transaction
+type CreateUserTxParams struct {
+	CreateUserParams
+	// VerifyCodeHash is the hash of the code that verifies the user's email, valid until VerifyExpiresAt
+	VerifyCodeHash  string
+	VerifyExpiresAt pgtype.Timestamptz
+}
+
+// CreateUserTxResult is the result of the create user transaction
+type CreateUserTxResult struct {
+	User        User
+	VerifyEmail VerifyEmail
+}
+
+// CreateUserTx creates a user along with a code to verify their email within a single db transaction
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:  result.User.Username,
+			Email:     result.User.Email,
+			CodeHash:  arg.VerifyCodeHash,
+			ExpiresAt: arg.VerifyExpiresAt,
+		})
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// randomCreateUserTxParams returns the parameters to create a random user with the given verification code
+func randomCreateUserTxParams(t *testing.T, code string, expiresAt time.Time) CreateUserTxParams {
+	t.Helper()
+	hashedPassword, err := util.HashPassword(gofakeit.Password(true, true, true, false, false, 8))
+	require.NoError(t, err)
+
+	return CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       gofakeit.LetterN(10),
+			HashedPassword: hashedPassword,
+			FullName:       gofakeit.Name(),
+			Email:          gofakeit.Email(),
+		},
+		VerifyCodeHash:  util.HashOneTimeCode(code),
+		VerifyExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}
+}
+
+// deleteUserWithVerifyEmails deletes a user created by CreateUserTx
+func deleteUserWithVerifyEmails(t *testing.T, username string) {
+	t.Helper()
+	_, err := testQueries.db.Exec(context.Background(), "DELETE FROM verify_emails WHERE username = $1", username)
+	require.NoError(t, err)
+	deleteUser(t, username)
+}
+
+func TestCreateUserTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	code, err := util.RandomOneTimeCode()
+	require.NoError(t, err)
+	arg := randomCreateUserTxParams(t, code, time.Now().Add(time.Hour))
+
+	result, err := store.CreateUserTx(ctx, arg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		deleteUserWithVerifyEmails(t, result.User.Username)
+	})
+
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+
+	require.Equal(t, result.User.Username, result.VerifyEmail.Username)
+	require.Equal(t, result.User.Email, result.VerifyEmail.Email)
+	require.Equal(t, arg.VerifyCodeHash, result.VerifyEmail.CodeHash)
+	require.False(t, result.VerifyEmail.UsedAt.Valid)
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+)
+
+// EnableMFATxParams contains the input parameters of the enable MFA transaction
+type EnableMFATxParams struct {
+	Username string
+	// TOTPStep is the period of the code that confirmed the enrollment, so it cannot be used again to log in
+	TOTPStep int64
+	// RecoveryCodeHashes are the hashes of the recovery codes shown to the user
+	RecoveryCodeHashes []string
+}
+
+// EnableMFATx turns on MFA for a user whose TOTP enrollment is pending and replaces their recovery codes,
+// within a single db transaction. It returns ErrRecordNotFound if no enrollment is pending.
+func (store *SQLStore) EnableMFATx(ctx context.Context, arg EnableMFATxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		user, err = q.EnableUserMFA(ctx, EnableUserMFAParams{
+			Username:     arg.Username,
+			TotpLastStep: arg.TOTPStep,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteMFARecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		for _, codeHash := range arg.RecoveryCodeHashes {
+			_, err = q.CreateMFARecoveryCode(ctx, CreateMFARecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: codeHash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return user, err
+}
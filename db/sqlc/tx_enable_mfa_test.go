@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func deleteUserWithRecoveryCodes(t *testing.T, username string) {
+	t.Helper()
+	_, err := testQueries.db.Exec(context.Background(), "DELETE FROM mfa_recovery_codes WHERE username = $1", username)
+	require.NoError(t, err)
+	deleteUser(t, username)
+}
+
+func TestEnableMFATx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	user, _ := createRandomUser(t)
+	t.Cleanup(func() {
+		deleteUserWithRecoveryCodes(t, user.Username)
+	})
+
+	// MFA cannot be enabled before enrolling
+	_, err := store.EnableMFATx(ctx, EnableMFATxParams{Username: user.Username, TOTPStep: 1})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	secret, err := util.RandomTOTPSecret()
+	require.NoError(t, err)
+	enrolling, err := testQueries.SetUserTOTPSecret(ctx, SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: pgtype.Text{String: secret, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, secret, enrolling.TotpSecret.String)
+	require.False(t, enrolling.MfaEnabled)
+
+	codes := []string{"AAAAAAAAAAAAAAAA", "BBBBBBBBBBBBBBBB"}
+	enabled, err := store.EnableMFATx(ctx, EnableMFATxParams{
+		Username:           user.Username,
+		TOTPStep:           100,
+		RecoveryCodeHashes: []string{util.HashOneTimeCode(codes[0]), util.HashOneTimeCode(codes[1])},
+	})
+	require.NoError(t, err)
+	require.True(t, enabled.MfaEnabled)
+	require.Equal(t, int64(100), enabled.TotpLastStep)
+
+	// the secret can no longer be replaced
+	_, err = testQueries.SetUserTOTPSecret(ctx, SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: pgtype.Text{String: secret, Valid: true},
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// each recovery code works once
+	arg := UseMFARecoveryCodeParams{Username: user.Username, CodeHash: util.HashOneTimeCode(codes[0])}
+	used, err := testQueries.UseMFARecoveryCode(ctx, arg)
+	require.NoError(t, err)
+	require.True(t, used.UsedAt.Valid)
+
+	_, err = testQueries.UseMFARecoveryCode(ctx, arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseUserTOTPStep(t *testing.T) {
+	ctx := context.Background()
+
+	user, _ := createRandomUser(t)
+	t.Cleanup(func() {
+		deleteUser(t, user.Username)
+	})
+
+	updated, err := testQueries.UseUserTOTPStep(ctx, UseUserTOTPStepParams{Username: user.Username, TotpLastStep: 10})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), updated.TotpLastStep)
+
+	// steps at or before the last used one are replays
+	_, err = testQueries.UseUserTOTPStep(ctx, UseUserTOTPStepParams{Username: user.Username, TotpLastStep: 10})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = testQueries.UseUserTOTPStep(ctx, UseUserTOTPStepParams{Username: user.Username, TotpLastStep: 9})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
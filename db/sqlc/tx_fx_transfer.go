@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FXTransferTxParams contains the input parameters of the cross-currency transfer transaction.
+// Amount is in the source account's currency and must equal the quoted amount.
+type FXTransferTxParams struct {
+	TransferTxParams
+	// Username must own the quote
+	Username string
+	QuoteID  uuid.UUID
+}
+
+// FXTransferTx performs a transfer between accounts in different currencies at the rate locked by a quote.
+// It debits the quoted amount from the source account, credits the converted amount to the destination
+// account and records the rate and spread on the transfer, then marks the quote as used, all within a
+// single db transaction. A quote of another user is reported as ErrRecordNotFound.
+func (store *SQLStore) FXTransferTx(ctx context.Context, arg FXTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = store.fxTransfer(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// fxTransfer moves the money of a cross-currency transfer using the queries of an open transaction
+func (store *SQLStore) fxTransfer(ctx context.Context, q *Queries, arg FXTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	// locking the quote first makes a concurrent transfer with the same quote wait and then see it used
+	quote, err := q.GetFXQuoteForUpdate(ctx, arg.QuoteID)
+	if err != nil {
+		return result, err
+	}
+	if quote.Username != arg.Username {
+		return result, ErrRecordNotFound
+	}
+	if quote.UsedAt.Valid {
+		return result, ErrQuoteUsed
+	}
+	if !time.Now().Before(quote.ExpiresAt.Time) {
+		return result, ErrQuoteExpired
+	}
+	if arg.Amount != quote.FromAmount {
+		return result, fmt.Errorf("%w: amount %d, quoted %d", ErrQuoteMismatch, arg.Amount, quote.FromAmount)
+	}
+
+	fromAccount, toAccount, err := lockAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+	if fromAccount.Currency != quote.FromCurrency || toAccount.Currency != quote.ToCurrency {
+		return result, fmt.Errorf("%w: accounts are in %s and %s, quoted %s to %s",
+			ErrQuoteMismatch, fromAccount.Currency, toAccount.Currency, quote.FromCurrency, quote.ToCurrency)
+	}
+
+	// the credit must fit in the destination balance, and a quote stored with a wrapped amount is refused
+	if quote.ToAmount <= 0 || toAccount.Balance > math.MaxInt64-quote.ToAmount {
+		return result, fmt.Errorf("%w: %d %s", ErrAmountOutOfRange, quote.ToAmount, quote.ToCurrency)
+	}
+
+	err = checkFunds(fromAccount, quote.FromAmount)
+	if err != nil {
+		return result, err
+	}
+
+	err = store.checkLimits(ctx, q, fromAccount, quote.FromAmount)
+	if err != nil {
+		return result, err
+	}
+
+	result.Transfer, err = q.CreateFXTransfer(ctx, CreateFXTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        quote.FromAmount,
+		ToAmount:      quote.ToAmount,
+		ExchangeRate:  quote.Rate,
+		SpreadBps:     quote.SpreadBps,
+		Description:   arg.Description,
+		Reference:     arg.Reference,
+		Metadata:      arg.Metadata,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = postTransfer(ctx, q, &result)
+	if err != nil {
+		return result, err
+	}
+
+	_, err = q.MarkFXQuoteUsed(ctx, quote.ID)
+	return result, err
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// createFXAccounts creates a USD account holding the given balance and an EUR account of another user
+func createFXAccounts(t *testing.T, balance int64) (Account, Account) {
+	t.Helper()
+	ctx := context.Background()
+
+	fromUser, _ := createRandomUser(t)
+	toUser, _ := createRandomUser(t)
+	from, err := testQueries.CreateAccount(ctx, CreateAccountParams{
+		Owner:    fromUser.Username,
+		Balance:  balance,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	to, _ := createRandomAccountForUser(t, toUser.Username, util.EUR)
+
+	t.Cleanup(func() {
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM entries WHERE account_id = ANY($1)", []int64{from.ID, to.ID})
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM transfers WHERE from_account_id = ANY($1) OR to_account_id = ANY($1)", []int64{from.ID, to.ID})
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM fx_quotes WHERE username = $1", from.Owner)
+		_ = testQueries.DeleteAccount(ctx, to.ID)
+		_ = testQueries.DeleteAccount(ctx, from.ID)
+		deleteUser(t, to.Owner)
+		deleteUser(t, from.Owner)
+	})
+
+	return from, to
+}
+
+func createRandomFXQuote(t *testing.T, username string, fromAmount int64, expiresIn time.Duration) FxQuote {
+	t.Helper()
+	quote, err := testQueries.CreateFXQuote(context.Background(), CreateFXQuoteParams{
+		ID:           uuid.New(),
+		Username:     username,
+		FromCurrency: util.USD,
+		ToCurrency:   util.EUR,
+		Rate:         0.9,
+		SpreadBps:    100,
+		FromAmount:   fromAmount,
+		ToAmount:     fromAmount * 891 / 1000,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(expiresIn), Valid: true},
+	})
+	require.NoError(t, err)
+	return quote
+}
+
+func TestFXTransferTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFXAccounts(t, 5000)
+	quote := createRandomFXQuote(t, from.Owner, 1000, time.Minute)
+
+	arg := FXTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        quote.FromAmount,
+		},
+		Username: from.Owner,
+		QuoteID:  quote.ID,
+	}
+
+	result, err := store.FXTransferTx(ctx, arg)
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, quote.FromAmount, transfer.Amount)
+	require.Equal(t, quote.ToAmount, transfer.ToAmount)
+	require.Equal(t, quote.Rate, transfer.ExchangeRate)
+	require.Equal(t, quote.SpreadBps, transfer.SpreadBps)
+
+	require.Equal(t, -quote.FromAmount, result.FromEntry.Amount)
+	require.Equal(t, quote.ToAmount, result.ToEntry.Amount)
+	require.Equal(t, from.Balance-quote.FromAmount, result.FromAccount.Balance)
+	require.Equal(t, to.Balance+quote.ToAmount, result.ToAccount.Balance)
+
+	used, err := store.GetFXQuote(ctx, quote.ID)
+	require.NoError(t, err)
+	require.True(t, used.UsedAt.Valid)
+
+	// a quote locks its rate for a single transfer
+	_, err = store.FXTransferTx(ctx, arg)
+	require.ErrorIs(t, err, ErrQuoteUsed)
+}
+
+func TestFXTransferTxRejectsInvalidQuotes(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFXAccounts(t, 5000)
+	other, _ := createRandomAccountForUser(t, from.Owner, util.CAD)
+	t.Cleanup(func() {
+		_ = testQueries.DeleteAccount(ctx, other.ID)
+	})
+
+	testCases := []struct {
+		name        string
+		username    string
+		toAccountID int64
+		amount      int64
+		quoteAmount int64
+		expiresIn   time.Duration
+		wantErr     error
+	}{
+		{"Expired", from.Owner, to.ID, 1000, 1000, -time.Second, ErrQuoteExpired},
+		{"AnotherUser", to.Owner, to.ID, 1000, 1000, time.Minute, ErrRecordNotFound},
+		{"AmountMismatch", from.Owner, to.ID, 999, 1000, time.Minute, ErrQuoteMismatch},
+		{"CurrencyMismatch", from.Owner, other.ID, 1000, 1000, time.Minute, ErrQuoteMismatch},
+		{"InsufficientFunds", from.Owner, to.ID, 6000, 6000, time.Minute, ErrInsufficientFunds},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			quote := createRandomFXQuote(t, from.Owner, tc.quoteAmount, tc.expiresIn)
+			_, err := store.FXTransferTx(ctx, FXTransferTxParams{
+				TransferTxParams: TransferTxParams{
+					FromAccountID: from.ID,
+					ToAccountID:   tc.toAccountID,
+					Amount:        tc.amount,
+				},
+				Username: tc.username,
+				QuoteID:  quote.ID,
+			})
+			require.ErrorIs(t, err, tc.wantErr)
+
+			// a rejected transfer leaves the quote unused
+			quote, err = store.GetFXQuote(ctx, quote.ID)
+			require.NoError(t, err)
+			require.False(t, quote.UsedAt.Valid)
+		})
+	}
+
+	account, err := store.GetAccount(ctx, from.ID)
+	require.NoError(t, err)
+	require.Equal(t, from.Balance, account.Balance)
+}
+
+func TestFXTransferTxAmountOutOfRange(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, _ := createFXAccounts(t, 5000)
+	to, err := testQueries.CreateAccount(ctx, CreateAccountParams{
+		Owner:    from.Owner,
+		Balance:  1,
+		Currency: util.EUR,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = testQueries.DeleteAccount(ctx, to.ID)
+	})
+
+	// the converted amount fits in an int64, but not once added to the destination balance
+	quote, err := testQueries.CreateFXQuote(ctx, CreateFXQuoteParams{
+		ID:           uuid.New(),
+		Username:     from.Owner,
+		FromCurrency: util.USD,
+		ToCurrency:   util.EUR,
+		Rate:         math.MaxInt64 / 1000,
+		FromAmount:   1000,
+		ToAmount:     math.MaxInt64,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	_, err = store.FXTransferTx(ctx, FXTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        1000,
+		},
+		Username: from.Owner,
+		QuoteID:  quote.ID,
+	})
+	require.ErrorIs(t, err, ErrAmountOutOfRange)
+
+	account, err := store.GetAccount(ctx, to.ID)
+	require.NoError(t, err)
+	require.Equal(t, to.Balance, account.Balance)
+}
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// AuthorizeTxParams contains the input parameters of the authorization transaction
+type AuthorizeTxParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// HoldTxResult is the result of a transaction placing or releasing a hold
+type HoldTxResult struct {
+	Hold        Hold    `json:"hold"`
+	FromAccount Account `json:"from_account"`
+}
+
+// CaptureTxParams contains the input parameters of the capture transaction.
+// Zero Amount captures the whole hold.
+type CaptureTxParams struct {
+	HoldID int64 `json:"hold_id"`
+	Amount int64 `json:"amount"`
+}
+
+// CaptureTxResult is the result of the capture transaction
+type CaptureTxResult struct {
+	TransferTxResult
+	Hold Hold `json:"hold"`
+}
+
+// AuthorizeTx places a hold on the source account for a pending transfer. The held amount
+// is taken out of the account's available balance but stays in its balance until the hold is
+// captured, voided or expires. It returns an *InsufficientFundsError if the available balance
+// cannot cover the amount, and a *LimitExceededError if the hold would break one of the account's
+// transfer limits. Capturing the hold later is not checked again.
+func (store *SQLStore) AuthorizeTx(ctx context.Context, arg AuthorizeTxParams) (HoldTxResult, error) {
+	var result HoldTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.FromAccountID)
+		if err != nil {
+			return err
+		}
+
+		err = checkFunds(account, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		err = store.checkLimits(ctx, q, account, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.CreateHold(ctx, CreateHoldParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			ExpiresAt:     pgtype.Timestamptz{Time: arg.ExpiresAt, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		result.FromAccount, err = q.ChangeAccountHeldAmount(ctx, ChangeAccountHeldAmountParams{
+			ID:     arg.FromAccountID,
+			Amount: arg.Amount,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// CaptureTx settles all or part of a pending hold. It releases the whole hold and moves the
+// captured amount with a transfer linked to the hold, all within a single db transaction;
+// the rest of the hold goes back to the available balance. It returns ErrHoldClosed for a hold
+// that is no longer pending, ErrHoldExpired once it has expired and ErrInvalidCaptureAmount
+// for more than the held amount.
+func (store *SQLStore) CaptureTx(ctx context.Context, arg CaptureTxParams) (CaptureTxResult, error) {
+	var result CaptureTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		result = CaptureTxResult{}
+
+		hold, err := lockPendingHold(ctx, q, arg.HoldID)
+		if err != nil {
+			return err
+		}
+		if !time.Now().Before(hold.ExpiresAt.Time) {
+			return ErrHoldExpired
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount < 0 || amount > hold.Amount {
+			return fmt.Errorf("%w: requested %d, held %d", ErrInvalidCaptureAmount, amount, hold.Amount)
+		}
+
+		_, _, err = lockAccounts(ctx, q, hold.FromAccountID, hold.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.ChangeAccountHeldAmount(ctx, ChangeAccountHeldAmountParams{
+			ID:     hold.FromAccountID,
+			Amount: -hold.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.CreateCaptureTransfer(ctx, CreateCaptureTransferParams{
+			FromAccountID: hold.FromAccountID,
+			ToAccountID:   hold.ToAccountID,
+			Amount:        amount,
+			HoldID:        pgtype.Int8{Int64: hold.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = postTransfer(ctx, q, &result.TransferTxResult)
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.CloseHold(ctx, CloseHoldParams{
+			ID:             hold.ID,
+			Status:         HoldCaptured,
+			CapturedAmount: amount,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// VoidHoldTx cancels a pending hold and gives its amount back to the available balance.
+// It returns ErrHoldClosed for a hold that is no longer pending.
+func (store *SQLStore) VoidHoldTx(ctx context.Context, holdID int64) (HoldTxResult, error) {
+	var result HoldTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		hold, err := lockPendingHold(ctx, q, holdID)
+		if err != nil {
+			return err
+		}
+
+		result, err = releaseHold(ctx, q, hold, HoldVoided)
+		return err
+	})
+
+	return result, err
+}
+
+// ExpireHoldsTx releases up to limit pending holds whose expiry has passed and returns them.
+// Holds locked by a concurrent capture or void are skipped.
+func (store *SQLStore) ExpireHoldsTx(ctx context.Context, limit int32) ([]Hold, error) {
+	var expired []Hold
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		expired = nil
+
+		holds, err := q.ClaimExpiredHolds(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		// releasing in account order keeps the account locks in the order transfers take them
+		slices.SortFunc(holds, func(a, b Hold) int {
+			return cmp.Compare(a.FromAccountID, b.FromAccountID)
+		})
+
+		for _, hold := range holds {
+			result, err := releaseHold(ctx, q, hold, HoldExpired)
+			if err != nil {
+				return err
+			}
+			expired = append(expired, result.Hold)
+		}
+
+		return nil
+	})
+
+	return expired, err
+}
+
+// lockPendingHold locks the hold with the given ID and returns ErrHoldClosed unless it is pending
+func lockPendingHold(ctx context.Context, q *Queries, holdID int64) (Hold, error) {
+	hold, err := q.GetHoldForUpdate(ctx, holdID)
+	if err != nil {
+		return hold, err
+	}
+	if hold.Status != HoldPending {
+		return hold, fmt.Errorf("%w: hold %d is %s", ErrHoldClosed, hold.ID, hold.Status)
+	}
+	return hold, nil
+}
+
+// releaseHold closes a locked pending hold with the given status and returns its amount
+// to the available balance of the source account
+func releaseHold(ctx context.Context, q *Queries, hold Hold, status string) (HoldTxResult, error) {
+	var result HoldTxResult
+
+	var err error
+	result.FromAccount, err = q.ChangeAccountHeldAmount(ctx, ChangeAccountHeldAmountParams{
+		ID:     hold.FromAccountID,
+		Amount: -hold.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Hold, err = q.CloseHold(ctx, CloseHoldParams{
+		ID:     hold.ID,
+		Status: status,
+	})
+	return result, err
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizeTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFundedAccounts(t, 1000)
+
+	result, err := store.AuthorizeTx(ctx, AuthorizeTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        700,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, HoldPending, result.Hold.Status)
+	require.Equal(t, int64(700), result.Hold.Amount)
+
+	// the hold reduces the available balance only
+	require.Equal(t, int64(1000), result.FromAccount.Balance)
+	require.Equal(t, int64(700), result.FromAccount.HeldAmount)
+	require.Equal(t, int64(300), result.FromAccount.AvailableBalance)
+
+	// held funds can neither be held again nor transferred
+	_, err = store.AuthorizeTx(ctx, AuthorizeTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        301,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	var fundsErr *InsufficientFundsError
+	require.ErrorAs(t, err, &fundsErr)
+	require.Equal(t, int64(300), fundsErr.Available)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 301})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestCaptureTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFundedAccounts(t, 1000)
+
+	authorized, err := store.AuthorizeTx(ctx, AuthorizeTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        400,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = store.CaptureTx(ctx, CaptureTxParams{HoldID: authorized.Hold.ID, Amount: 401})
+	require.ErrorIs(t, err, ErrInvalidCaptureAmount)
+
+	result, err := store.CaptureTx(ctx, CaptureTxParams{HoldID: authorized.Hold.ID, Amount: 250})
+	require.NoError(t, err)
+	require.Equal(t, HoldCaptured, result.Hold.Status)
+	require.Equal(t, int64(250), result.Hold.CapturedAmount)
+	require.Equal(t, int64(250), result.Transfer.Amount)
+	require.Equal(t, authorized.Hold.ID, result.Transfer.HoldID.Int64)
+	require.Equal(t, int64(-250), result.FromEntry.Amount)
+	require.Equal(t, int64(250), result.ToEntry.Amount)
+
+	// the uncaptured rest of the hold is released
+	require.Equal(t, int64(750), result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldAmount)
+	require.Equal(t, int64(750), result.FromAccount.AvailableBalance)
+	require.Equal(t, to.Balance+250, result.ToAccount.Balance)
+
+	_, err = store.CaptureTx(ctx, CaptureTxParams{HoldID: authorized.Hold.ID})
+	require.ErrorIs(t, err, ErrHoldClosed)
+	_, err = store.VoidHoldTx(ctx, authorized.Hold.ID)
+	require.ErrorIs(t, err, ErrHoldClosed)
+}
+
+func TestVoidHoldTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFundedAccounts(t, 1000)
+
+	authorized, err := store.AuthorizeTx(ctx, AuthorizeTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        400,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	result, err := store.VoidHoldTx(ctx, authorized.Hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldVoided, result.Hold.Status)
+	require.Zero(t, result.Hold.CapturedAmount)
+	require.Equal(t, int64(1000), result.FromAccount.Balance)
+	require.Equal(t, int64(1000), result.FromAccount.AvailableBalance)
+
+	_, err = store.CaptureTx(ctx, CaptureTxParams{HoldID: authorized.Hold.ID})
+	require.ErrorIs(t, err, ErrHoldClosed)
+}
+
+func TestExpireHoldsTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFundedAccounts(t, 1000)
+
+	stale, err := store.AuthorizeTx(ctx, AuthorizeTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        300,
+		ExpiresAt:     time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+	fresh, err := store.AuthorizeTx(ctx, AuthorizeTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        200,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = store.CaptureTx(ctx, CaptureTxParams{HoldID: stale.Hold.ID})
+	require.ErrorIs(t, err, ErrHoldExpired)
+
+	expired, err := store.ExpireHoldsTx(ctx, 1000)
+	require.NoError(t, err)
+
+	expiredIDs := make(map[int64]bool)
+	for _, hold := range expired {
+		require.Equal(t, HoldExpired, hold.Status)
+		expiredIDs[hold.ID] = true
+	}
+	require.True(t, expiredIDs[stale.Hold.ID])
+	require.False(t, expiredIDs[fresh.Hold.ID])
+
+	account, err := testQueries.GetAccount(ctx, from.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(200), account.HeldAmount)
+	require.Equal(t, int64(800), account.AvailableBalance)
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// IdempotentTransferTxParams contains the input parameters of the idempotent transfer transaction
+type IdempotentTransferTxParams struct {
+	TransferTxParams
+	Username       string
+	IdempotencyKey string
+	// RequestHash identifies the request body the key was first used with
+	RequestHash string
+	// QuoteID makes this a cross-currency transfer at the quoted rate, see FXTransferTx
+	QuoteID uuid.UUID
+}
+
+// IdempotentTransferTxResult is the result of the idempotent transfer transaction
+type IdempotentTransferTxResult struct {
+	TransferTxResult
+	// Replayed is true when the result was stored by an earlier request with the same key
+	Replayed bool
+}
+
+// IdempotentTransferTx performs a transfer at most once per idempotency key.
+// The key is claimed and the serialized result stored in the same db transaction as the
+// transfer itself, so a failed transfer leaves the key free to be retried. A key reused
+// with a different request hash returns ErrIdempotencyKeyReused.
+func (store *SQLStore) IdempotentTransferTx(ctx context.Context, arg IdempotentTransferTxParams) (IdempotentTransferTxResult, error) {
+	var result IdempotentTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		result = IdempotentTransferTxResult{}
+
+		// a concurrent request holding the same key makes this wait until it commits or rolls back
+		claimed, err := q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+			Username:    arg.Username,
+			Key:         arg.IdempotencyKey,
+			RequestHash: arg.RequestHash,
+		})
+		if err != nil {
+			return err
+		}
+
+		if claimed == 0 {
+			return replayIdempotencyKey(ctx, q, arg, &result)
+		}
+
+		if arg.QuoteID == uuid.Nil {
+			result.TransferTxResult, err = store.transfer(ctx, q, arg.TransferTxParams)
+		} else {
+			result.TransferTxResult, err = store.fxTransfer(ctx, q, FXTransferTxParams{
+				TransferTxParams: arg.TransferTxParams,
+				Username:         arg.Username,
+				QuoteID:          arg.QuoteID,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		response, err := json.Marshal(result.TransferTxResult)
+		if err != nil {
+			return fmt.Errorf("failed to encode transfer result: %w", err)
+		}
+
+		return q.SetIdempotencyKeyResponse(ctx, SetIdempotencyKeyResponseParams{
+			Username: arg.Username,
+			Key:      arg.IdempotencyKey,
+			Response: response,
+		})
+	})
+
+	return result, err
+}
+
+func replayIdempotencyKey(ctx context.Context, q *Queries, arg IdempotentTransferTxParams, result *IdempotentTransferTxResult) error {
+	stored, err := q.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+		Username: arg.Username,
+		Key:      arg.IdempotencyKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	if stored.RequestHash != arg.RequestHash {
+		return ErrIdempotencyKeyReused
+	}
+
+	err = json.Unmarshal(stored.Response, &result.TransferTxResult)
+	if err != nil {
+		return fmt.Errorf("failed to decode stored transfer result: %w", err)
+	}
+
+	result.Replayed = true
+	return nil
+}
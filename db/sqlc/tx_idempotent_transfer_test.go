@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/stretchr/testify/require"
+)
+
+// createFundedAccounts creates two accounts where the first one holds the given balance
+func createFundedAccounts(t *testing.T, balance int64) (Account, Account) {
+	t.Helper()
+	ctx := context.Background()
+
+	user, _ := createRandomUser(t)
+	from, err := testQueries.CreateAccount(ctx, CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: randomCurrency(),
+	})
+	require.NoError(t, err)
+	to, _ := createRandomAccount(t)
+
+	t.Cleanup(func() {
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM entries WHERE account_id = ANY($1)", []int64{from.ID, to.ID})
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM transfers WHERE from_account_id = ANY($1) OR to_account_id = ANY($1)", []int64{from.ID, to.ID})
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM holds WHERE from_account_id = $1", from.ID)
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM account_limits WHERE account_id = $1", from.ID)
+		deleteIdempotencyKeys(t, from.Owner)
+		_ = testQueries.DeleteAccount(ctx, to.ID)
+		_ = testQueries.DeleteAccount(ctx, from.ID)
+		deleteUser(t, to.Owner)
+		deleteUser(t, from.Owner)
+	})
+
+	return from, to
+}
+
+func TestIdempotentTransferTxReplay(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFundedAccounts(t, 1000)
+
+	arg := IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        100,
+		},
+		Username:       from.Owner,
+		IdempotencyKey: gofakeit.UUID(),
+		RequestHash:    gofakeit.LetterN(64),
+	}
+
+	first, err := store.IdempotentTransferTx(ctx, arg)
+	require.NoError(t, err)
+	require.False(t, first.Replayed)
+	require.NotZero(t, first.Transfer.ID)
+	require.Equal(t, from.Balance-100, first.FromAccount.Balance)
+
+	second, err := store.IdempotentTransferTx(ctx, arg)
+	require.NoError(t, err)
+	require.True(t, second.Replayed)
+	require.Equal(t, first.Transfer.ID, second.Transfer.ID)
+	require.Equal(t, first.FromAccount.Balance, second.FromAccount.Balance)
+
+	// the money moved only once
+	account, err := store.GetAccount(ctx, from.ID)
+	require.NoError(t, err)
+	require.Equal(t, from.Balance-100, account.Balance)
+
+	// the same key with a different request is rejected
+	arg.RequestHash = gofakeit.LetterN(64)
+	_, err = store.IdempotentTransferTx(ctx, arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyReused)
+}
+
+func TestIdempotentTransferTxFailureReleasesKey(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFundedAccounts(t, 50)
+
+	arg := IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        100,
+		},
+		Username:       from.Owner,
+		IdempotencyKey: gofakeit.UUID(),
+		RequestHash:    gofakeit.LetterN(64),
+	}
+
+	_, err := store.IdempotentTransferTx(ctx, arg)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{Username: arg.Username, Key: arg.IdempotencyKey})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// after a deposit the same key can be used again
+	_, err = store.ChangeAccountBalance(ctx, ChangeAccountBalanceParams{ID: from.ID, Amount: 100})
+	require.NoError(t, err)
+
+	result, err := store.IdempotentTransferTx(ctx, arg)
+	require.NoError(t, err)
+	require.False(t, result.Replayed)
+}
+
+func TestIdempotentTransferTxConcurrent(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFundedAccounts(t, 1000)
+
+	arg := IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        10,
+		},
+		Username:       from.Owner,
+		IdempotencyKey: gofakeit.UUID(),
+		RequestHash:    gofakeit.LetterN(64),
+	}
+
+	n := 5
+	errs := make(chan error)
+	results := make(chan IdempotentTransferTxResult)
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := store.IdempotentTransferTx(ctx, arg)
+			errs <- err
+			results <- result
+		}()
+	}
+
+	transferIDs := make(map[int64]bool)
+	replayed := 0
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		result := <-results
+		transferIDs[result.Transfer.ID] = true
+		if result.Replayed {
+			replayed++
+		}
+	}
+
+	require.Len(t, transferIDs, 1)
+	require.Equal(t, n-1, replayed)
+
+	account, err := store.GetAccount(ctx, from.ID)
+	require.NoError(t, err)
+	require.Equal(t, from.Balance-10, account.Balance)
+}
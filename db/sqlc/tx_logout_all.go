@@ -0,0 +1,24 @@
+package db
+
+import (
+	"context"
+)
+
+// LogoutAllTx blocks every session of the user and revokes all tokens issued to them so far.
+// Both changes are applied in a single db transaction so a user is never left half logged out.
+func (store *SQLStore) LogoutAllTx(ctx context.Context, username string) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		user, err = q.RevokeUserTokens(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		return q.BlockUserSessions(ctx, username)
+	})
+
+	return user, err
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogoutAllTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	user, _ := createRandomUser(t)
+	session1, _ := createRandomSession(t, user.Username)
+	session2, _ := createRandomSession(t, user.Username)
+	t.Cleanup(func() {
+		deleteSession(t, session1.ID)
+		deleteSession(t, session2.ID)
+		deleteUser(t, user.Username)
+	})
+
+	got, err := store.LogoutAllTx(ctx, user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, got.Username)
+	require.WithinDuration(t, time.Now(), got.TokensRevokedAt.Time, time.Second)
+
+	for _, session := range []Session{session1, session2} {
+		s, err := store.GetSession(ctx, session.ID)
+		require.NoError(t, err)
+		require.True(t, s.IsBlocked)
+	}
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ResetPasswordTxParams contains the input parameters of the reset password transaction
+type ResetPasswordTxParams struct {
+	// CodeHash is the hash of the code the user received by mail
+	CodeHash       string
+	HashedPassword string
+}
+
+// ResetPasswordTx sets a new password for the user a password reset code was issued to.
+// It uses up the code along with every other unused code of the user, stores the new password hash
+// and blocks the user's sessions, all within a single db transaction. An unknown or used code is
+// reported as ErrResetCodeInvalid and an expired one as ErrResetCodeExpired.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// locking the code makes a concurrent reset with the same code wait and then see it used
+		resetToken, err := q.GetPasswordResetTokenForUpdate(ctx, arg.CodeHash)
+		if errors.Is(err, ErrRecordNotFound) {
+			return ErrResetCodeInvalid
+		} else if err != nil {
+			return err
+		}
+		if resetToken.UsedAt.Valid {
+			return ErrResetCodeInvalid
+		}
+		if !time.Now().Before(resetToken.ExpiresAt.Time) {
+			return ErrResetCodeExpired
+		}
+
+		err = q.InvalidatePasswordResetTokens(ctx, resetToken.Username)
+		if err != nil {
+			return err
+		}
+
+		user, err = changePassword(ctx, q, ChangePasswordTxParams{
+			Username:       resetToken.Username,
+			HashedPassword: arg.HashedPassword,
+		})
+		return err
+	})
+
+	return user, err
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// createRandomPasswordResetToken issues a reset code for the user that expires at expiresAt
+func createRandomPasswordResetToken(t *testing.T, username string, expiresAt time.Time) (PasswordResetToken, string) {
+	t.Helper()
+	code, err := util.RandomOneTimeCode()
+	require.NoError(t, err)
+
+	resetToken, err := testQueries.CreatePasswordResetToken(context.Background(), CreatePasswordResetTokenParams{
+		Username:  username,
+		CodeHash:  util.HashOneTimeCode(code),
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	require.NoError(t, err)
+	require.False(t, resetToken.UsedAt.Valid)
+
+	return resetToken, code
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	user, _ := createRandomUser(t)
+	session, _ := createRandomSession(t, user.Username)
+	t.Cleanup(func() {
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM password_reset_tokens WHERE username = $1", user.Username)
+		deleteSession(t, session.ID)
+		deleteUser(t, user.Username)
+	})
+
+	_, code := createRandomPasswordResetToken(t, user.Username, time.Now().Add(time.Minute))
+	other, _ := createRandomPasswordResetToken(t, user.Username, time.Now().Add(time.Minute))
+
+	hashedPassword, err := util.HashPassword(gofakeit.Password(true, true, true, false, false, 8))
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		CodeHash:       util.HashOneTimeCode(code),
+		HashedPassword: hashedPassword,
+	}
+	got, err := store.ResetPasswordTx(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, got.Username)
+	require.Equal(t, hashedPassword, got.HashedPassword)
+	require.WithinDuration(t, time.Now(), got.PasswordChangedAt.Time, time.Second)
+
+	s, err := store.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.True(t, s.IsBlocked)
+
+	// the code cannot be used twice, and the reset used up the user's other codes as well
+	_, err = store.ResetPasswordTx(ctx, arg)
+	require.ErrorIs(t, err, ErrResetCodeInvalid)
+
+	_, err = store.ResetPasswordTx(ctx, ResetPasswordTxParams{CodeHash: other.CodeHash, HashedPassword: hashedPassword})
+	require.ErrorIs(t, err, ErrResetCodeInvalid)
+}
+
+func TestResetPasswordTxExpiredCode(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	user, _ := createRandomUser(t)
+	t.Cleanup(func() {
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM password_reset_tokens WHERE username = $1", user.Username)
+		deleteUser(t, user.Username)
+	})
+
+	_, code := createRandomPasswordResetToken(t, user.Username, time.Now().Add(-time.Second))
+
+	_, err := store.ResetPasswordTx(ctx, ResetPasswordTxParams{
+		CodeHash:       util.HashOneTimeCode(code),
+		HashedPassword: user.HashedPassword,
+	})
+	require.ErrorIs(t, err, ErrResetCodeExpired)
+
+	_, err = store.ResetPasswordTx(ctx, ResetPasswordTxParams{
+		CodeHash:       util.HashOneTimeCode("unknown"),
+		HashedPassword: user.HashedPassword,
+	})
+	require.ErrorIs(t, err, ErrResetCodeInvalid)
+}
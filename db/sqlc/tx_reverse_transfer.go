@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ReverseTransferTxParams contains the input parameters of the reversal transaction.
+// Amount is in the original source account's currency; zero reverses whatever is left.
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	Amount     int64 `json:"amount"`
+}
+
+// ReverseTransferTxResult is the result of the reversal transaction. Transfer is the
+// reversal itself, moving money back from the original destination to the original source.
+type ReverseTransferTxResult struct {
+	TransferTxResult
+	OriginalTransfer Transfer `json:"original_transfer"`
+}
+
+// ReverseTransferTx refunds all or part of a transfer. It creates a reversal transfer linked
+// to the original one with its entries, updates both accounts' balances and adds the amount
+// to the original transfer's reversed amount, all within a single db transaction.
+// It returns ErrInvalidReversalAmount if more than the unreversed amount is requested,
+// ErrReversalOfReversal for a transfer that is itself a reversal, and an *InsufficientFundsError
+// if the original destination account cannot cover the refund.
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = reverseTransfer(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// reverseTransfer moves the money of a reversal using the queries of an open transaction
+func reverseTransfer(ctx context.Context, q *Queries, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	// locking the original transfer serializes concurrent reversals of it
+	original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+	if err != nil {
+		return result, err
+	}
+	if original.ReversalOf.Valid {
+		return result, ErrReversalOfReversal
+	}
+
+	remaining := original.Amount - original.ReversedAmount
+	amount := arg.Amount
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return result, fmt.Errorf("%w: requested %d, unreversed %d", ErrInvalidReversalAmount, amount, remaining)
+	}
+
+	// the refund is taken from the destination account in its own currency, at the rate of the
+	// original transfer; computing it from the running totals makes a full reversal exact
+	debit := reversedToAmount(original, original.ReversedAmount+amount) - reversedToAmount(original, original.ReversedAmount)
+	if debit == 0 {
+		return result, fmt.Errorf("%w: %d converts to nothing at the transfer's rate", ErrInvalidReversalAmount, amount)
+	}
+
+	fromAccount, _, err := lockAccounts(ctx, q, original.ToAccountID, original.FromAccountID)
+	if err != nil {
+		return result, err
+	}
+
+	err = checkFunds(fromAccount, debit)
+	if err != nil {
+		return result, err
+	}
+
+	result.Transfer, err = q.CreateReversalTransfer(ctx, CreateReversalTransferParams{
+		FromAccountID: original.ToAccountID,
+		ToAccountID:   original.FromAccountID,
+		Amount:        debit,
+		ToAmount:      amount,
+		ExchangeRate:  float64(amount) / float64(debit),
+		ReversalOf:    pgtype.Int8{Int64: original.ID, Valid: true},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = postTransfer(ctx, q, &result.TransferTxResult)
+	if err != nil {
+		return result, err
+	}
+
+	result.OriginalTransfer, err = q.AddTransferReversedAmount(ctx, AddTransferReversedAmountParams{
+		ID:     original.ID,
+		Amount: amount,
+	})
+	return result, err
+}
+
+// reversedToAmount returns the part of the transfer's credited amount that corresponds
+// to reversing the given amount of its debited amount
+func reversedToAmount(transfer Transfer, amount int64) int64 {
+	if amount == transfer.Amount {
+		return transfer.ToAmount
+	}
+	return transfer.ToAmount * amount / transfer.Amount
+}
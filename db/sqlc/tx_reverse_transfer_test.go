@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseTransferTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFundedAccounts(t, 1000)
+
+	original, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferCompleted, original.Transfer.Status)
+	require.Zero(t, original.Transfer.ReversedAmount)
+
+	partial, err := store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID, Amount: 30})
+	require.NoError(t, err)
+
+	reversal := partial.Transfer
+	require.Equal(t, to.ID, reversal.FromAccountID)
+	require.Equal(t, from.ID, reversal.ToAccountID)
+	require.Equal(t, int64(30), reversal.Amount)
+	require.Equal(t, int64(30), reversal.ToAmount)
+	require.Equal(t, original.Transfer.ID, reversal.ReversalOf.Int64)
+	require.Equal(t, int64(-30), partial.FromEntry.Amount)
+	require.Equal(t, int64(30), partial.ToEntry.Amount)
+	require.Equal(t, original.ToAccount.Balance-30, partial.FromAccount.Balance)
+	require.Equal(t, original.FromAccount.Balance+30, partial.ToAccount.Balance)
+	require.Equal(t, TransferPartiallyReversed, partial.OriginalTransfer.Status)
+	require.Equal(t, int64(30), partial.OriginalTransfer.ReversedAmount)
+
+	// more than what is left cannot be reversed
+	_, err = store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID, Amount: 71})
+	require.ErrorIs(t, err, ErrInvalidReversalAmount)
+
+	// without an amount the rest is reversed
+	full, err := store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(70), full.Transfer.Amount)
+	require.Equal(t, TransferReversed, full.OriginalTransfer.Status)
+	require.Equal(t, original.Transfer.Amount, full.OriginalTransfer.ReversedAmount)
+	require.Equal(t, from.Balance, full.ToAccount.Balance)
+	require.Equal(t, to.Balance, full.FromAccount.Balance)
+
+	_, err = store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.ErrorIs(t, err, ErrInvalidReversalAmount)
+
+	_, err = store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: reversal.ID})
+	require.ErrorIs(t, err, ErrReversalOfReversal)
+
+	reversals, err := testQueries.ListTransferReversals(ctx, reversal.ReversalOf)
+	require.NoError(t, err)
+	require.Len(t, reversals, 2)
+	require.Equal(t, reversal.ID, reversals[0].ID)
+	require.Equal(t, full.Transfer.ID, reversals[1].ID)
+}
+
+func TestReverseTransferTxInsufficientFunds(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFundedAccounts(t, 1000)
+
+	original, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+
+	// the recipient already spent the money
+	_, err = testQueries.ChangeAccountBalance(ctx, ChangeAccountBalanceParams{ID: to.ID, Amount: -original.ToAccount.Balance})
+	require.NoError(t, err)
+
+	_, err = store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	transfer, err := testQueries.GetTransfer(ctx, original.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferCompleted, transfer.Status)
+	require.Zero(t, transfer.ReversedAmount)
+}
+
+func TestReverseFXTransferTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	from, to := createFXAccounts(t, 1000)
+	quote := createRandomFXQuote(t, from.Owner, 1000, time.Minute)
+
+	original, err := store.FXTransferTx(ctx, FXTransferTxParams{
+		TransferTxParams: TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 1000},
+		Username:         from.Owner,
+		QuoteID:          quote.ID,
+	})
+	require.NoError(t, err)
+
+	// partial reversals are converted at the original rate and add up to the credited amount
+	first, err := store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID, Amount: 333})
+	require.NoError(t, err)
+	require.Equal(t, int64(333), first.Transfer.ToAmount)
+	require.Equal(t, quote.ToAmount*333/1000, first.Transfer.Amount)
+
+	rest, err := store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(667), rest.Transfer.ToAmount)
+	require.Equal(t, quote.ToAmount, first.Transfer.Amount+rest.Transfer.Amount)
+	require.Equal(t, from.Balance, rest.ToAccount.Balance)
+	require.Equal(t, to.Balance, rest.FromAccount.Balance)
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// ScheduledTransferTxParams contains the input parameters of the transaction that runs a scheduled transfer
+type ScheduledTransferTxParams struct {
+	TransferTxParams
+	// Owner is the owner of the schedule, who must still own the source account
+	Owner string
+}
+
+// ScheduledTransferTx performs one run of a scheduled transfer like TransferTx. The schedule was checked
+// when it was created or changed, so it first checks again, on the locked accounts, that the owner
+// still owns the source account and that both accounts are in the same currency, and returns
+// ErrScheduledTransferMismatch otherwise.
+func (store *SQLStore) ScheduledTransferTx(ctx context.Context, arg ScheduledTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		fromAccount, toAccount, err := lockAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+		if fromAccount.Owner != arg.Owner {
+			return fmt.Errorf("%w: account %d does not belong to %s", ErrScheduledTransferMismatch, fromAccount.ID, arg.Owner)
+		}
+		if fromAccount.Currency != toAccount.Currency {
+			return fmt.Errorf("%w: accounts are in %s and %s", ErrScheduledTransferMismatch, fromAccount.Currency, toAccount.Currency)
+		}
+
+		result, err = store.transfer(ctx, q, arg.TransferTxParams)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledTransferTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	owner, _ := createRandomUser(t)
+	recipient, _ := createRandomUser(t)
+	from, err := testQueries.CreateAccount(ctx, CreateAccountParams{
+		Owner:    owner.Username,
+		Balance:  100,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	to, _ := createRandomAccountForUser(t, recipient.Username, util.USD)
+	other, _ := createRandomAccountForUser(t, recipient.Username, util.EUR)
+	t.Cleanup(func() {
+		accountIDs := []int64{from.ID, to.ID, other.ID}
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM entries WHERE account_id = ANY($1)", accountIDs)
+		_, _ = testQueries.db.Exec(ctx, "DELETE FROM transfers WHERE from_account_id = ANY($1) OR to_account_id = ANY($1)", accountIDs)
+		for _, id := range accountIDs {
+			_ = testQueries.DeleteAccount(ctx, id)
+		}
+		deleteUser(t, recipient.Username)
+		deleteUser(t, owner.Username)
+	})
+
+	testCases := []struct {
+		name        string
+		owner       string
+		toAccountID int64
+		wantErr     error
+	}{
+		{"AnotherOwner", recipient.Username, to.ID, ErrScheduledTransferMismatch},
+		{"CurrencyMismatch", owner.Username, other.ID, ErrScheduledTransferMismatch},
+		{"OK", owner.Username, to.ID, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := store.ScheduledTransferTx(ctx, ScheduledTransferTxParams{
+				TransferTxParams: TransferTxParams{
+					FromAccountID: from.ID,
+					ToAccountID:   tc.toAccountID,
+					Amount:        1,
+				},
+				Owner: tc.owner,
+			})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(99), result.FromAccount.Balance)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+)
+
+// UpdateUserRoleTx sets the user's role. When the role changes, every session of the user is blocked and
+// all tokens issued to them so far are revoked in the same db transaction, since they carry the old role.
+func (store *SQLStore) UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		current, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+		if current.Role == arg.Role {
+			user = current
+			return nil
+		}
+
+		user, err = q.UpdateUserRole(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.RevokeUserTokens(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		return q.BlockUserSessions(ctx, arg.Username)
+	})
+
+	return user, err
+}
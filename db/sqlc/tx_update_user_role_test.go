@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateUserRoleTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	user, _ := createRandomUser(t)
+	session, _ := createRandomSession(t, user.Username)
+	t.Cleanup(func() {
+		deleteSession(t, session.ID)
+		deleteUser(t, user.Username)
+	})
+	require.Equal(t, util.DepositorRole, user.Role)
+
+	// setting the role the user already has leaves their tokens and sessions alone
+	got, err := store.UpdateUserRoleTx(ctx, UpdateUserRoleParams{Username: user.Username, Role: util.DepositorRole})
+	require.NoError(t, err)
+	require.Equal(t, user.TokensRevokedAt, got.TokensRevokedAt)
+
+	s, err := store.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.False(t, s.IsBlocked)
+
+	got, err = store.UpdateUserRoleTx(ctx, UpdateUserRoleParams{Username: user.Username, Role: util.BankerRole})
+	require.NoError(t, err)
+	require.Equal(t, util.BankerRole, got.Role)
+	require.WithinDuration(t, time.Now(), got.TokensRevokedAt.Time, time.Second)
+
+	s, err = store.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.True(t, s.IsBlocked)
+
+	_, err = store.UpdateUserRoleTx(ctx, UpdateUserRoleParams{Username: "unknown", Role: util.BankerRole})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// VerifyEmailTx marks the email a verification code was sent to as verified and uses up the code,
+// within a single db transaction. An unknown or used code, or one sent to an address the user no
+// longer has, is reported as ErrVerificationCodeInvalid and an expired one as ErrVerificationCodeExpired.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, codeHash string) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// locking the code makes a concurrent verification with the same code wait and then see it used
+		verifyEmail, err := q.GetVerifyEmailForUpdate(ctx, codeHash)
+		if errors.Is(err, ErrRecordNotFound) {
+			return ErrVerificationCodeInvalid
+		} else if err != nil {
+			return err
+		}
+		if verifyEmail.UsedAt.Valid {
+			return ErrVerificationCodeInvalid
+		}
+		if !time.Now().Before(verifyEmail.ExpiresAt.Time) {
+			return ErrVerificationCodeExpired
+		}
+
+		_, err = q.MarkVerifyEmailUsed(ctx, verifyEmail.ID)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: verifyEmail.Username,
+			Email:    verifyEmail.Email,
+		})
+		if errors.Is(err, ErrRecordNotFound) {
+			return ErrVerificationCodeInvalid
+		}
+		return err
+	})
+
+	return user, err
+}
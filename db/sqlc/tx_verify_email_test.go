@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmailTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	code, err := util.RandomOneTimeCode()
+	require.NoError(t, err)
+	result, err := store.CreateUserTx(ctx, randomCreateUserTxParams(t, code, time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		deleteUserWithVerifyEmails(t, result.User.Username)
+	})
+
+	user, err := store.VerifyEmailTx(ctx, util.HashOneTimeCode(code))
+	require.NoError(t, err)
+	require.Equal(t, result.User.Username, user.Username)
+	require.True(t, user.IsEmailVerified)
+
+	// the code cannot be used twice
+	_, err = store.VerifyEmailTx(ctx, util.HashOneTimeCode(code))
+	require.ErrorIs(t, err, ErrVerificationCodeInvalid)
+
+	_, err = store.VerifyEmailTx(ctx, util.HashOneTimeCode("unknown"))
+	require.ErrorIs(t, err, ErrVerificationCodeInvalid)
+}
+
+func TestVerifyEmailTxExpiredCode(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+
+	code, err := util.RandomOneTimeCode()
+	require.NoError(t, err)
+	result, err := store.CreateUserTx(ctx, randomCreateUserTxParams(t, code, time.Now().Add(-time.Second)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		deleteUserWithVerifyEmails(t, result.User.Username)
+	})
+
+	_, err = store.VerifyEmailTx(ctx, util.HashOneTimeCode(code))
+	require.ErrorIs(t, err, ErrVerificationCodeExpired)
+
+	user, err := store.GetUser(ctx, result.User.Username)
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+}
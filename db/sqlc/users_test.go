@@ -8,6 +8,7 @@ import (
 	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -44,6 +45,21 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user1.CreatedAt.Time, user2.CreatedAt.Time, time.Second)
 }
 
+func TestGetUserByEmail(t *testing.T) {
+	ctx := context.Background()
+	user1, _ := createRandomUser(t)
+	t.Cleanup(func() {
+		deleteUser(t, user1.Username)
+	})
+
+	user2, err := testQueries.GetUserByEmail(ctx, user1.Email)
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+
+	_, err = testQueries.GetUserByEmail(ctx, "nonexistent@example.com")
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
 func TestGetUserNotFound(t *testing.T) {
 	ctx := context.Background()
 	_, err := testQueries.GetUser(ctx, "nonexistent_user")
@@ -91,3 +107,108 @@ func TestCreateUserDuplicateEmail(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "duplicate key")
 }
+
+func TestRevokeUserTokens(t *testing.T) {
+	ctx := context.Background()
+	user1, _ := createRandomUser(t)
+	t.Cleanup(func() {
+		deleteUser(t, user1.Username)
+	})
+	require.True(t, user1.TokensRevokedAt.Time.IsZero())
+
+	user2, err := testQueries.RevokeUserTokens(ctx, user1.Username)
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+	require.WithinDuration(t, time.Now(), user2.TokensRevokedAt.Time, time.Second)
+
+	rows, err := testQueries.ListUserTokenRevocations(ctx, pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true})
+	require.NoError(t, err)
+
+	var found bool
+	for _, row := range rows {
+		if row.Username == user1.Username {
+			found = true
+			require.WithinDuration(t, user2.TokensRevokedAt.Time, row.TokensRevokedAt.Time, time.Second)
+		}
+	}
+	require.True(t, found)
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	ctx := context.Background()
+	user1, _ := createRandomUser(t)
+	t.Cleanup(func() {
+		deleteUser(t, user1.Username)
+	})
+	require.Equal(t, util.DepositorRole, user1.Role)
+
+	user2, err := testQueries.UpdateUserRole(ctx, UpdateUserRoleParams{
+		Username: user1.Username,
+		Role:     util.BankerRole,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, util.BankerRole, user2.Role)
+}
+
+func TestUpdateUserRoleUnsupported(t *testing.T) {
+	ctx := context.Background()
+	user, _ := createRandomUser(t)
+	t.Cleanup(func() {
+		deleteUser(t, user.Username)
+	})
+
+	_, err := testQueries.UpdateUserRole(ctx, UpdateUserRoleParams{
+		Username: user.Username,
+		Role:     "teller",
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "role_supported")
+}
+
+func TestClaimLoginAttempt(t *testing.T) {
+	ctx := context.Background()
+	user, _ := createRandomUser(t)
+	t.Cleanup(func() {
+		deleteUser(t, user.Username)
+	})
+	require.Zero(t, user.FailedLoginAttempts)
+
+	lockedUntil := time.Now().Add(time.Minute)
+	arg := ClaimLoginAttemptParams{
+		Username:            user.Username,
+		FailedLoginAttempts: user.FailedLoginAttempts,
+		LockedUntil:         pgtype.Timestamptz{Time: lockedUntil, Valid: true},
+	}
+	claimed, err := testQueries.ClaimLoginAttempt(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), claimed.FailedLoginAttempts)
+	require.WithinDuration(t, lockedUntil, claimed.LockedUntil.Time, time.Millisecond)
+
+	// a concurrent attempt that read the same row cannot claim it too
+	_, err = testQueries.ClaimLoginAttempt(ctx, arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// nor can a later one while the user is locked out
+	arg.FailedLoginAttempts = claimed.FailedLoginAttempts
+	_, err = testQueries.ClaimLoginAttempt(ctx, arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	released, err := testQueries.ReleaseLoginAttempt(ctx, user.Username)
+	require.NoError(t, err)
+	require.Zero(t, released.FailedLoginAttempts)
+	require.True(t, released.LockedUntil.Time.Before(time.Now()))
+
+	claimed, err = testQueries.ClaimLoginAttempt(ctx, ClaimLoginAttemptParams{
+		Username:            user.Username,
+		FailedLoginAttempts: released.FailedLoginAttempts,
+		LockedUntil:         pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), claimed.FailedLoginAttempts)
+
+	unlocked, err := testQueries.UnlockUser(ctx, user.Username)
+	require.NoError(t, err)
+	require.Zero(t, unlocked.FailedLoginAttempts)
+	require.True(t, unlocked.LockedUntil.Time.Before(time.Now()))
+}
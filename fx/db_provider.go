@@ -0,0 +1,56 @@
+package fx
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+)
+
+// DBProvider is a Provider serving the rates stored in the fx_rates table.
+// A pair without a row of its own is served by the inverse of the opposite pair.
+type DBProvider struct {
+	querier db.Querier
+}
+
+// NewDBProvider creates a DBProvider reading rates through the given querier
+func NewDBProvider(querier db.Querier) *DBProvider {
+	return &DBProvider{querier: querier}
+}
+
+// Rate returns the rate for converting from one currency into another
+func (provider *DBProvider) Rate(ctx context.Context, from string, to string) (Rate, error) {
+	if from == to {
+		return identity(from), nil
+	}
+
+	rate, err := provider.lookup(ctx, from, to)
+	if errors.Is(err, ErrRateNotFound) {
+		rate, err = provider.lookup(ctx, to, from)
+		if err != nil {
+			return Rate{}, err
+		}
+		return rate.Inverse(), nil
+	}
+	return rate, err
+}
+
+func (provider *DBProvider) lookup(ctx context.Context, from string, to string) (Rate, error) {
+	row, err := provider.querier.GetFXRate(ctx, db.GetFXRateParams{
+		FromCurrency: from,
+		ToCurrency:   to,
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return Rate{}, ErrRateNotFound
+	}
+	if err != nil {
+		return Rate{}, err
+	}
+
+	return Rate{
+		From:      row.FromCurrency,
+		To:        row.ToCurrency,
+		Rate:      row.Rate,
+		SpreadBps: row.SpreadBps,
+	}, nil
+}
@@ -0,0 +1,109 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDBProvider(t *testing.T) {
+	usdToEUR := db.FxRate{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.8, SpreadBps: 50}
+
+	testCases := []struct {
+		name       string
+		from, to   string
+		buildStubs func(store *mockdb.MockStore)
+		want       Rate
+		wantErr    error
+	}{
+		{
+			name: "Direct",
+			from: "USD",
+			to:   "EUR",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetFXRate(gomock.Any(), gomock.Eq(db.GetFXRateParams{FromCurrency: "USD", ToCurrency: "EUR"})).
+					Times(1).
+					Return(usdToEUR, nil)
+			},
+			want: Rate{From: "USD", To: "EUR", Rate: 0.8, SpreadBps: 50},
+		},
+		{
+			name: "Inverse",
+			from: "EUR",
+			to:   "USD",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetFXRate(gomock.Any(), gomock.Eq(db.GetFXRateParams{FromCurrency: "EUR", ToCurrency: "USD"})).
+					Times(1).
+					Return(db.FxRate{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					GetFXRate(gomock.Any(), gomock.Eq(db.GetFXRateParams{FromCurrency: "USD", ToCurrency: "EUR"})).
+					Times(1).
+					Return(usdToEUR, nil)
+			},
+			want: Rate{From: "EUR", To: "USD", Rate: 1.25, SpreadBps: 50},
+		},
+		{
+			name: "SameCurrency",
+			from: "USD",
+			to:   "USD",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFXRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			want: Rate{From: "USD", To: "USD", Rate: 1},
+		},
+		{
+			name: "NotFound",
+			from: "USD",
+			to:   "CAD",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetFXRate(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(db.FxRate{}, db.ErrRecordNotFound)
+			},
+			wantErr: ErrRateNotFound,
+		},
+		{
+			name: "InternalError",
+			from: "USD",
+			to:   "CAD",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetFXRate(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.FxRate{}, errors.New("connection refused"))
+			},
+			wantErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			rate, err := NewDBProvider(store).Rate(context.Background(), tc.from, tc.to)
+			if tc.wantErr != nil {
+				if err == nil || err.Error() != tc.wantErr.Error() {
+					t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rate != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, rate)
+			}
+		})
+	}
+}
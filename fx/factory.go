@@ -0,0 +1,15 @@
+package fx
+
+import (
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/util"
+)
+
+// NewProvider creates the rate provider selected by the configuration: the rate file
+// in FX_RATES_FILE when it is set, otherwise the fx_rates table.
+func NewProvider(config util.Config, querier db.Querier) (Provider, error) {
+	if config.FXRatesFile != "" {
+		return LoadRateTable(config.FXRatesFile)
+	}
+	return NewDBProvider(querier), nil
+}
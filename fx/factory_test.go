@@ -0,0 +1,39 @@
+package fx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	"github.com/WilliamOdinson/simplebank/util"
+	"go.uber.org/mock/gomock"
+)
+
+func TestNewProvider(t *testing.T) {
+	store := mockdb.NewMockStore(gomock.NewController(t))
+
+	provider, err := NewProvider(util.Config{}, store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := provider.(*DBProvider); !ok {
+		t.Errorf("expected *DBProvider, got %T", provider)
+	}
+
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`[{"from": "USD", "to": "EUR", "rate": 0.92}]`), 0o600); err != nil {
+		t.Fatalf("failed to write rate file: %v", err)
+	}
+	provider, err = NewProvider(util.Config{FXRatesFile: path}, store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := provider.(*RateTable); !ok {
+		t.Errorf("expected *RateTable, got %T", provider)
+	}
+
+	if _, err := NewProvider(util.Config{FXRatesFile: filepath.Join(t.TempDir(), "missing.json")}, store); err == nil {
+		t.Errorf("expected error for missing rate file")
+	}
+}
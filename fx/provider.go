@@ -0,0 +1,69 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// ErrRateNotFound is returned when no rate is known for a currency pair
+var ErrRateNotFound = errors.New("fx rate not found")
+
+// ErrAmountOutOfRange is returned when a converted amount does not fit in an int64
+var ErrAmountOutOfRange = errors.New("converted amount is out of range")
+
+// Provider is an interface for looking up foreign exchange rates
+type Provider interface {
+	// Rate returns the rate for converting an amount in the from currency into the to currency
+	Rate(ctx context.Context, from string, to string) (Rate, error)
+}
+
+const (
+	// rateScale is the precision rates are rounded to before converting amounts
+	rateScale = 100_000_000
+	// bpsScale is the number of basis points in one
+	bpsScale = 10_000
+)
+
+// Rate is the price of one unit of From in To. SpreadBps is the bank's margin in basis points,
+// taken from the converted amount.
+type Rate struct {
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Rate      float64 `json:"rate"`
+	SpreadBps int32   `json:"spread_bps"`
+}
+
+// Convert converts an amount in minor units of From into minor units of To after the spread.
+// The rate is rounded to 8 decimal places and the result rounded down, so the customer never
+// receives more than the quoted rate gives. Results too large for an int64 are ErrAmountOutOfRange.
+func (r Rate) Convert(amount int64) (int64, error) {
+	converted := new(big.Int).Mul(big.NewInt(amount), big.NewInt(int64(math.Round(r.Rate*rateScale))))
+	converted.Mul(converted, big.NewInt(int64(bpsScale-r.SpreadBps)))
+	converted.Quo(converted, big.NewInt(rateScale*bpsScale))
+	if !converted.IsInt64() {
+		return 0, fmt.Errorf("%w: %d %s at %v", ErrAmountOutOfRange, amount, r.From, r.Rate)
+	}
+	return converted.Int64(), nil
+}
+
+// Inverse returns the rate for converting in the opposite direction with the same spread
+func (r Rate) Inverse() Rate {
+	return Rate{
+		From:      r.To,
+		To:        r.From,
+		Rate:      1 / r.Rate,
+		SpreadBps: r.SpreadBps,
+	}
+}
+
+func (r Rate) valid() bool {
+	return r.From != "" && r.To != "" && r.Rate > 0 && r.SpreadBps >= 0 && r.SpreadBps < bpsScale
+}
+
+// identity is the rate between a currency and itself
+func identity(currency string) Rate {
+	return Rate{From: currency, To: currency, Rate: 1}
+}
@@ -0,0 +1,53 @@
+package fx
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestRateConvert(t *testing.T) {
+	testCases := []struct {
+		name   string
+		rate   Rate
+		amount int64
+		want   int64
+		err    error
+	}{
+		{"NoSpread", Rate{From: "USD", To: "EUR", Rate: 0.92}, 10000, 9200, nil},
+		{"WithSpread", Rate{From: "USD", To: "EUR", Rate: 0.92, SpreadBps: 50}, 10000, 9154, nil},
+		{"RoundsDown", Rate{From: "USD", To: "CAD", Rate: 1.3333}, 1, 1, nil},
+		{"DecimalRate", Rate{From: "USD", To: "EUR", Rate: 0.29}, 100, 29, nil},
+		{"TooSmall", Rate{From: "CAD", To: "USD", Rate: 0.73}, 1, 0, nil},
+		{"Identity", identity("USD"), 12345, 12345, nil},
+		{"LargeAmount", Rate{From: "USD", To: "EUR", Rate: 0.5}, math.MaxInt64 / 4, math.MaxInt64 / 8, nil},
+		{"Overflow", Rate{From: "EUR", To: "USD", Rate: 2}, math.MaxInt64/2 + 1, 0, ErrAmountOutOfRange},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.rate.Convert(tc.amount)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRateInverse(t *testing.T) {
+	rate := Rate{From: "USD", To: "EUR", Rate: 0.8, SpreadBps: 25}
+	inverse := rate.Inverse()
+
+	if inverse.From != "EUR" || inverse.To != "USD" {
+		t.Errorf("expected EUR to USD, got %s to %s", inverse.From, inverse.To)
+	}
+	if inverse.Rate != 1.25 {
+		t.Errorf("expected rate 1.25, got %v", inverse.Rate)
+	}
+	if inverse.SpreadBps != rate.SpreadBps {
+		t.Errorf("expected spread %d, got %d", rate.SpreadBps, inverse.SpreadBps)
+	}
+}
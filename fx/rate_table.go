@@ -0,0 +1,62 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type currencyPair struct {
+	from string
+	to   string
+}
+
+// RateTable is a Provider serving a fixed set of rates held in memory.
+// A pair without a rate of its own is served by the inverse of the opposite pair.
+type RateTable struct {
+	rates map[currencyPair]Rate
+}
+
+// NewRateTable creates a RateTable holding the given rates
+func NewRateTable(rates ...Rate) (*RateTable, error) {
+	table := &RateTable{rates: make(map[currencyPair]Rate, len(rates))}
+	for _, rate := range rates {
+		if !rate.valid() {
+			return nil, fmt.Errorf("invalid fx rate %s to %s: rate %v, spread %d bps", rate.From, rate.To, rate.Rate, rate.SpreadBps)
+		}
+		table.rates[currencyPair{rate.From, rate.To}] = rate
+	}
+	return table, nil
+}
+
+// LoadRateTable reads a RateTable from a JSON file holding an array of rates, e.g.
+// [{"from": "USD", "to": "EUR", "rate": 0.92, "spread_bps": 50}]
+func LoadRateTable(path string) (*RateTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []Rate
+	err = json.Unmarshal(data, &rates)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse fx rate file %s: %w", path, err)
+	}
+
+	return NewRateTable(rates...)
+}
+
+// Rate returns the rate for converting from one currency into another
+func (table *RateTable) Rate(ctx context.Context, from string, to string) (Rate, error) {
+	if from == to {
+		return identity(from), nil
+	}
+	if rate, ok := table.rates[currencyPair{from, to}]; ok {
+		return rate, nil
+	}
+	if rate, ok := table.rates[currencyPair{to, from}]; ok {
+		return rate.Inverse(), nil
+	}
+	return Rate{}, ErrRateNotFound
+}
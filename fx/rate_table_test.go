@@ -0,0 +1,93 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRateTable(t *testing.T) {
+	table, err := NewRateTable(
+		Rate{From: "USD", To: "EUR", Rate: 0.8, SpreadBps: 50},
+		Rate{From: "EUR", To: "CAD", Rate: 1.5},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		from, to string
+		want     Rate
+		wantErr  error
+	}{
+		{"Direct", "USD", "EUR", Rate{From: "USD", To: "EUR", Rate: 0.8, SpreadBps: 50}, nil},
+		{"Inverse", "EUR", "USD", Rate{From: "EUR", To: "USD", Rate: 1.25, SpreadBps: 50}, nil},
+		{"SameCurrency", "CAD", "CAD", Rate{From: "CAD", To: "CAD", Rate: 1}, nil},
+		{"NotFound", "USD", "CAD", Rate{}, ErrRateNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rate, err := table.Rate(context.Background(), tc.from, tc.to)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if rate != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, rate)
+			}
+		})
+	}
+}
+
+func TestNewRateTableInvalidRate(t *testing.T) {
+	invalid := []Rate{
+		{From: "USD", To: "EUR", Rate: 0},
+		{From: "USD", To: "EUR", Rate: -1},
+		{From: "USD", To: "EUR", Rate: 0.9, SpreadBps: -1},
+		{From: "USD", To: "EUR", Rate: 0.9, SpreadBps: 10000},
+		{From: "", To: "EUR", Rate: 0.9},
+	}
+
+	for _, rate := range invalid {
+		if _, err := NewRateTable(rate); err == nil {
+			t.Errorf("expected error for %+v", rate)
+		}
+	}
+}
+
+func TestLoadRateTable(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "rates.json")
+	data := `[{"from": "USD", "to": "EUR", "rate": 0.92, "spread_bps": 50}]`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write rate file: %v", err)
+	}
+
+	table, err := LoadRateTable(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rate, err := table.Rate(context.Background(), "USD", "EUR")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rate.Rate != 0.92 || rate.SpreadBps != 50 {
+		t.Errorf("unexpected rate %+v", rate)
+	}
+
+	badPath := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(badPath, []byte("not json"), 0o600); err != nil {
+		t.Fatalf("failed to write rate file: %v", err)
+	}
+	if _, err := LoadRateTable(badPath); err == nil {
+		t.Errorf("expected error for invalid JSON")
+	}
+
+	if _, err := LoadRateTable(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
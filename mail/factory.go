@@ -0,0 +1,23 @@
+package mail
+
+import (
+	"errors"
+
+	"github.com/WilliamOdinson/simplebank/util"
+)
+
+// ErrNoMailer is returned when neither an SMTP server nor the development outbox is configured
+var ErrNoMailer = errors.New("no mailer configured: set SMTP_HOST, or MAIL_OUTBOX=true for local development")
+
+// NewMailer creates the mailer selected by the configuration: an outbox that keeps the messages, in MAIL_OUTBOX_DIR
+// if set, when MAIL_OUTBOX is true, otherwise delivery through the SMTP server in SMTP_HOST.
+// The outbox delivers nothing, so it is never picked just because SMTP_HOST is missing.
+func NewMailer(config util.Config) (Mailer, error) {
+	if config.MailOutbox {
+		return NewOutbox(config.MailOutboxDir)
+	}
+	if config.SMTPHost == "" {
+		return nil, ErrNoMailer
+	}
+	return NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+}
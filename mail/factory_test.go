@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/WilliamOdinson/simplebank/util"
+)
+
+func TestNewMailer(t *testing.T) {
+	if _, err := NewMailer(util.Config{}); !errors.Is(err, ErrNoMailer) {
+		t.Errorf("expected ErrNoMailer without SMTP_HOST or MAIL_OUTBOX, got %v", err)
+	}
+
+	mailer, err := NewMailer(util.Config{MailOutbox: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := mailer.(*Outbox); !ok {
+		t.Errorf("expected *Outbox, got %T", mailer)
+	}
+
+	mailer, err = NewMailer(util.Config{SMTPHost: "smtp.example.com", SMTPPort: 587, MailFrom: "no-reply@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := mailer.(*SMTPMailer); !ok {
+		t.Errorf("expected *SMTPMailer, got %T", mailer)
+	}
+
+	if _, err := NewMailer(util.Config{SMTPHost: "smtp.example.com", MailFrom: "invalid"}); err == nil {
+		t.Errorf("expected error for an invalid sender address")
+	}
+}
@@ -0,0 +1,16 @@
+package mail
+
+import "context"
+
+// Message is a plain text email to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is an interface for delivering emails to users
+type Mailer interface {
+	// Send delivers the message or returns an error if it could not be handed over
+	Send(ctx context.Context, msg Message) error
+}
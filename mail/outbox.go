@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outbox keeps emails instead of delivering them, for local development and tests.
+// Every message is kept in memory and, when a directory is set, also written to a file in it.
+type Outbox struct {
+	mu       sync.Mutex
+	dir      string
+	messages []Message
+}
+
+// NewOutbox creates an outbox that writes messages to dir, creating it if needed.
+// An empty dir keeps messages in memory only.
+func NewOutbox(dir string) (*Outbox, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+		}
+	}
+
+	return &Outbox{dir: dir}, nil
+}
+
+// Send stores the message in the outbox
+func (outbox *Outbox) Send(ctx context.Context, msg Message) error {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	outbox.messages = append(outbox.messages, msg)
+	if outbox.dir == "" {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s-%04d.txt", time.Now().UTC().Format("20060102T150405"), len(outbox.messages))
+	data := fmt.Sprintf("To: %s\nSubject: %s\n\n%s", msg.To, msg.Subject, msg.Body)
+	if err := os.WriteFile(filepath.Join(outbox.dir, name), []byte(data), 0o600); err != nil {
+		return fmt.Errorf("failed to write email to outbox: %w", err)
+	}
+
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first
+func (outbox *Outbox) Messages() []Message {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	return append([]Message(nil), outbox.messages...)
+}
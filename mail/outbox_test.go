@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutbox(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	outbox, err := NewOutbox(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := []Message{
+		{To: "alice@example.com", Subject: "First", Body: "one"},
+		{To: "bob@example.com", Subject: "Second", Body: "two"},
+	}
+	for _, msg := range messages {
+		if err := outbox.Send(context.Background(), msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	got := outbox.Messages()
+	if len(got) != len(messages) {
+		t.Fatalf("expected %d messages, got %d", len(messages), len(got))
+	}
+	for i := range messages {
+		if got[i] != messages[i] {
+			t.Errorf("expected message %+v, got %+v", messages[i], got[i])
+		}
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read outbox directory: %v", err)
+	}
+	if len(files) != len(messages) {
+		t.Fatalf("expected %d files, got %d", len(messages), len(files))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatalf("failed to read email file: %v", err)
+	}
+	if !strings.Contains(string(data), "To: alice@example.com") || !strings.HasSuffix(string(data), "one") {
+		t.Errorf("unexpected email file contents %q", data)
+	}
+}
+
+func TestOutboxInMemory(t *testing.T) {
+	outbox, err := NewOutbox("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := outbox.Send(context.Background(), Message{To: "alice@example.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(outbox.Messages()) != 1 {
+		t.Errorf("expected 1 message, got %d", len(outbox.Messages()))
+	}
+}
@@ -0,0 +1,139 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPMailer delivers emails through an SMTP server
+type SMTPMailer struct {
+	host string
+	addr string
+	auth smtp.Auth
+	from *netmail.Address
+}
+
+// NewSMTPMailer creates a mailer that sends from the from address through the SMTP server at host and port.
+// It authenticates with PLAIN auth when a username is given, which net/smtp only allows over TLS or to localhost.
+func NewSMTPMailer(host string, port int, username, password, from string) (*SMTPMailer, error) {
+	fromAddress, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", from, err)
+	}
+
+	mailer := &SMTPMailer{
+		host: host,
+		addr: host + ":" + strconv.Itoa(port),
+		from: fromAddress,
+	}
+	if username != "" {
+		mailer.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return mailer, nil
+}
+
+// Send delivers the message. The whole SMTP conversation is abandoned when ctx is done.
+func (mailer *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := mailer.format(msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", mailer.addr)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	defer conn.Close()
+
+	// net/smtp does not support contexts, so a done ctx fails the next read or write on the connection instead
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if err := mailer.deliver(conn, msg.To, data); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// deliver runs the SMTP conversation of smtp.SendMail over conn
+func (mailer *SMTPMailer) deliver(conn net.Conn, to string, data []byte) error {
+	client, err := smtp.NewClient(conn, mailer.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: mailer.host}); err != nil {
+			return err
+		}
+	}
+	if mailer.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		if err := client.Auth(mailer.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(mailer.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// format renders the message with the headers and CRLF line endings SMTP expects
+func (mailer *SMTPMailer) format(msg Message, date time.Time) ([]byte, error) {
+	// parsing the recipient also keeps line breaks out of the headers
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address %q: %w", msg.To, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", mailer.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,123 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSMTPMailer(t *testing.T) {
+	mailer, err := NewSMTPMailer("smtp.example.com", 587, "", "", "SimpleBank <no-reply@example.com>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mailer.addr != "smtp.example.com:587" {
+		t.Errorf("expected address smtp.example.com:587, got %s", mailer.addr)
+	}
+	if mailer.auth != nil {
+		t.Errorf("expected no auth without a username")
+	}
+
+	mailer, err = NewSMTPMailer("smtp.example.com", 587, "user", "secret", "no-reply@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mailer.auth == nil {
+		t.Errorf("expected auth with a username")
+	}
+
+	if _, err := NewSMTPMailer("smtp.example.com", 587, "", "", "not an address"); err == nil {
+		t.Errorf("expected error for an invalid sender address")
+	}
+}
+
+func TestSMTPMailerFormat(t *testing.T) {
+	mailer, err := NewSMTPMailer("smtp.example.com", 587, "", "", "SimpleBank <no-reply@example.com>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		msg     Message
+		wantErr bool
+		want    []string
+	}{
+		{
+			name: "OK",
+			msg:  Message{To: "alice@example.com", Subject: "Reset your password", Body: "line one\nline two"},
+			want: []string{
+				"From: \"SimpleBank\" <no-reply@example.com>\r\n",
+				"To: <alice@example.com>\r\n",
+				"Subject: Reset your password\r\n",
+				"Content-Type: text/plain; charset=UTF-8\r\n",
+				"\r\n\r\nline one\r\nline two",
+			},
+		},
+		{
+			name: "EncodedSubject",
+			msg:  Message{To: "alice@example.com", Subject: "Réinitialisation", Body: "body"},
+			want: []string{"Subject: =?utf-8?q?R=C3=A9initialisation?=\r\n"},
+		},
+		{
+			name:    "HeaderInjection",
+			msg:     Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hi", Body: "body"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := mailer.format(tc.msg, time.Now())
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got message %q", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("expected message to contain %q, got %q", want, data)
+				}
+			}
+		})
+	}
+}
+
+func TestSMTPMailerSendTimeout(t *testing.T) {
+	// the server accepts connections but never greets, like a hung mail server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	mailer, err := NewSMTPMailer("127.0.0.1", port, "", "", "no-reply@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err = mailer.Send(ctx, Message{To: "alice@example.com", Subject: "Hi", Body: "body"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the send to time out, got %v", err)
+	}
+}
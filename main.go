@@ -3,15 +3,25 @@ package main
 import (
 	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/WilliamOdinson/simplebank/api"
 	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/scheduler"
 	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// shutdownTimeout bounds how long the server waits for requests and background tasks when it is stopped
+const shutdownTimeout = time.Minute
+
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	config, err := util.LoadConfig(".")
 
 	if err != nil {
@@ -24,15 +34,34 @@ func main() {
 	}
 
 	defer pool.Close()
-	store := db.NewStore(pool)
+	store := db.NewStore(pool, db.WithTransferLimits(config.TransferLimits))
 	server, err := api.NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create server:", err)
 	}
 
-	log.Printf("Starting server at %s", config.ServerAddress)
-	err = server.Start(config.ServerAddress)
+	err = server.WatchRevocations(ctx, config.RevocationSyncInterval)
+	if err != nil {
+		log.Fatal("cannot load token revocations:", err)
+	}
+
+	scheduler.New(store, config.SchedulerBatchSize).Start(ctx, config.SchedulerInterval)
+
+	go func() {
+		log.Printf("Starting server at %s", config.ServerAddress)
+		err := server.Start(config.ServerAddress)
+		if err != nil {
+			log.Fatal("cannot start server:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
 	if err != nil {
-		log.Fatal("cannot start server:", err)
+		log.Fatal("cannot shut down server:", err)
 	}
 }
@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/util"
+)
+
+// occurrence returns the nth occurrence after start of a recurrence with the given
+// frequency and interval. Occurrences are computed in UTC; a monthly recurrence
+// starting on a day the month does not have falls on its last day instead.
+func occurrence(start time.Time, frequency string, interval int, n int) time.Time {
+	start = start.UTC()
+	switch frequency {
+	case util.DailyFrequency:
+		return start.AddDate(0, 0, n*interval)
+	case util.WeeklyFrequency:
+		return start.AddDate(0, 0, 7*n*interval)
+	case util.MonthlyFrequency:
+		year, month, day := start.Date()
+		first := time.Date(year, month+time.Month(n*interval), 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), time.UTC)
+		lastDay := first.AddDate(0, 1, -1).Day()
+		return first.AddDate(0, 0, min(day, lastDay)-1)
+	default:
+		return start
+	}
+}
+
+// advance returns when a scheduled transfer runs next once its current occurrence is claimed
+// at now, and its status afterwards. Occurrences missed while the schedule was paused or
+// the scheduler was down are skipped. A schedule without further occurrences is completed
+// and keeps its current next_run_at.
+func advance(schedule db.ScheduledTransfer, now time.Time) (time.Time, string) {
+	current := schedule.NextRunAt.Time
+	if schedule.Frequency == util.OnceFrequency {
+		return current, db.ScheduleCompleted
+	}
+	if schedule.MaxRuns.Valid && schedule.RunCount+1 >= schedule.MaxRuns.Int32 {
+		return current, db.ScheduleCompleted
+	}
+
+	after := current
+	if now.After(after) {
+		after = now
+	}
+
+	next := current
+	for n := 1; !next.After(after); n++ {
+		next = occurrence(schedule.StartAt.Time, schedule.Frequency, int(schedule.IntervalCount), n)
+	}
+
+	if schedule.EndAt.Valid && next.After(schedule.EndAt.Time) {
+		return current, db.ScheduleCompleted
+	}
+	return next, db.ScheduleActive
+}
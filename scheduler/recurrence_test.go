@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+func TestOccurrence(t *testing.T) {
+	start := time.Date(2026, 1, 31, 9, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		frequency string
+		interval  int
+		n         int
+		want      time.Time
+	}{
+		{name: "Daily", frequency: util.DailyFrequency, interval: 1, n: 3, want: time.Date(2026, 2, 3, 9, 30, 0, 0, time.UTC)},
+		{name: "EveryOtherWeek", frequency: util.WeeklyFrequency, interval: 2, n: 1, want: time.Date(2026, 2, 14, 9, 30, 0, 0, time.UTC)},
+		{name: "MonthlyClampsToMonthEnd", frequency: util.MonthlyFrequency, interval: 1, n: 1, want: time.Date(2026, 2, 28, 9, 30, 0, 0, time.UTC)},
+		{name: "MonthlyKeepsStartDay", frequency: util.MonthlyFrequency, interval: 1, n: 2, want: time.Date(2026, 3, 31, 9, 30, 0, 0, time.UTC)},
+		{name: "Quarterly", frequency: util.MonthlyFrequency, interval: 3, n: 1, want: time.Date(2026, 4, 30, 9, 30, 0, 0, time.UTC)},
+		{name: "AcrossYears", frequency: util.MonthlyFrequency, interval: 1, n: 13, want: time.Date(2027, 2, 28, 9, 30, 0, 0, time.UTC)},
+		{name: "Once", frequency: util.OnceFrequency, interval: 1, n: 1, want: start},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := occurrence(start, tc.frequency, tc.interval, tc.n)
+			if !got.Equal(tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	start := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		schedule   db.ScheduledTransfer
+		now        time.Time
+		wantNext   time.Time
+		wantStatus string
+	}{
+		{
+			name: "Once",
+			schedule: db.ScheduledTransfer{
+				Frequency: util.OnceFrequency, IntervalCount: 1,
+				StartAt: timestamptz(start), NextRunAt: timestamptz(start),
+			},
+			now:        start,
+			wantNext:   start,
+			wantStatus: db.ScheduleCompleted,
+		},
+		{
+			name: "NextDay",
+			schedule: db.ScheduledTransfer{
+				Frequency: util.DailyFrequency, IntervalCount: 1,
+				StartAt: timestamptz(start), NextRunAt: timestamptz(start),
+			},
+			now:        start.Add(time.Minute),
+			wantNext:   start.AddDate(0, 0, 1),
+			wantStatus: db.ScheduleActive,
+		},
+		{
+			name: "SkipsMissedOccurrences",
+			schedule: db.ScheduledTransfer{
+				Frequency: util.WeeklyFrequency, IntervalCount: 1, RunCount: 1,
+				StartAt: timestamptz(start), NextRunAt: timestamptz(start.AddDate(0, 0, 7)),
+			},
+			now:        start.AddDate(0, 0, 30),
+			wantNext:   start.AddDate(0, 0, 35),
+			wantStatus: db.ScheduleActive,
+		},
+		{
+			name: "MaxRunsReached",
+			schedule: db.ScheduledTransfer{
+				Frequency: util.MonthlyFrequency, IntervalCount: 1, RunCount: 2,
+				MaxRuns: pgtype.Int4{Int32: 3, Valid: true},
+				StartAt: timestamptz(start), NextRunAt: timestamptz(start.AddDate(0, 2, 0)),
+			},
+			now:        start.AddDate(0, 2, 0),
+			wantNext:   start.AddDate(0, 2, 0),
+			wantStatus: db.ScheduleCompleted,
+		},
+		{
+			name: "BeforeMaxRuns",
+			schedule: db.ScheduledTransfer{
+				Frequency: util.MonthlyFrequency, IntervalCount: 1, RunCount: 1,
+				MaxRuns: pgtype.Int4{Int32: 3, Valid: true},
+				StartAt: timestamptz(start), NextRunAt: timestamptz(start.AddDate(0, 1, 0)),
+			},
+			now:        start.AddDate(0, 1, 0),
+			wantNext:   start.AddDate(0, 2, 0),
+			wantStatus: db.ScheduleActive,
+		},
+		{
+			name: "PastEndDate",
+			schedule: db.ScheduledTransfer{
+				Frequency: util.DailyFrequency, IntervalCount: 1,
+				StartAt: timestamptz(start), NextRunAt: timestamptz(start),
+				EndAt: timestamptz(start.Add(12 * time.Hour)),
+			},
+			now:        start,
+			wantNext:   start,
+			wantStatus: db.ScheduleCompleted,
+		},
+		{
+			name: "OnEndDate",
+			schedule: db.ScheduledTransfer{
+				Frequency: util.DailyFrequency, IntervalCount: 1,
+				StartAt: timestamptz(start), NextRunAt: timestamptz(start),
+				EndAt: timestamptz(start.AddDate(0, 0, 1)),
+			},
+			now:        start,
+			wantNext:   start.AddDate(0, 0, 1),
+			wantStatus: db.ScheduleActive,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next, status := advance(tc.schedule, tc.now)
+			if !next.Equal(tc.wantNext) {
+				t.Errorf("expected next run at %v, got %v", tc.wantNext, next)
+			}
+			if status != tc.wantStatus {
+				t.Errorf("expected status %s, got %s", tc.wantStatus, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scheduler executes due scheduled transfers and releases expired holds in the background.
+// Several instances may run against the same database: each due schedule or expired hold
+// is claimed by exactly one of them with FOR UPDATE SKIP LOCKED.
+type Scheduler struct {
+	store     db.Store
+	batchSize int32
+}
+
+// claimedRun is an occurrence of a scheduled transfer claimed for execution
+type claimedRun struct {
+	schedule db.ScheduledTransfer
+	run      db.ScheduledTransferRun
+}
+
+// New creates a scheduler claiming at most batchSize due schedules or expired holds at a time
+func New(store db.Store, batchSize int32) *Scheduler {
+	return &Scheduler{
+		store:     store,
+		batchSize: batchSize,
+	}
+}
+
+// Start runs the due scheduled transfers and expires stale holds every interval until ctx is done.
+func (scheduler *Scheduler) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := scheduler.RunDue(ctx); err != nil {
+					log.Printf("cannot run scheduled transfers: %v", err)
+				}
+				if _, err := scheduler.ExpireHolds(ctx); err != nil {
+					log.Printf("cannot expire holds: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// RunDue claims the due scheduled transfers and executes them, returning the number of runs.
+// A batch of batchSize runs means more schedules may be due already.
+func (scheduler *Scheduler) RunDue(ctx context.Context) (int, error) {
+	claimed, err := scheduler.claim(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range claimed {
+		if err := scheduler.execute(ctx, c); err != nil {
+			log.Printf("cannot record run %d of scheduled transfer %d: %v", c.run.ID, c.schedule.ID, err)
+		}
+	}
+
+	return len(claimed), nil
+}
+
+// ExpireHolds releases the pending holds whose expiry has passed, returning how many were released
+func (scheduler *Scheduler) ExpireHolds(ctx context.Context) (int, error) {
+	holds, err := scheduler.store.ExpireHoldsTx(ctx, scheduler.batchSize)
+	return len(holds), err
+}
+
+// claim records a pending run for every due schedule and moves the schedules to their next
+// occurrence in a single db transaction. The money is moved after the claim is committed, so
+// a crash in between leaves a pending run behind instead of repeating the transfer.
+func (scheduler *Scheduler) claim(ctx context.Context, now time.Time) ([]claimedRun, error) {
+	var claimed []claimedRun
+
+	err := scheduler.store.ExecTx(ctx, db.DefaultTxOptions, func(q *db.Queries) error {
+		claimed = nil
+
+		schedules, err := q.ClaimDueScheduledTransfers(ctx, scheduler.batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, schedule := range schedules {
+			run, err := q.CreateScheduledTransferRun(ctx, db.CreateScheduledTransferRunParams{
+				ScheduledTransferID: schedule.ID,
+				ScheduledFor:        schedule.NextRunAt,
+			})
+			if err != nil {
+				return err
+			}
+
+			nextRunAt, status := advance(schedule, now)
+			_, err = q.AdvanceScheduledTransfer(ctx, db.AdvanceScheduledTransferParams{
+				ID:        schedule.ID,
+				NextRunAt: pgtype.Timestamptz{Time: nextRunAt, Valid: true},
+				Status:    status,
+			})
+			if err != nil {
+				return err
+			}
+
+			claimed = append(claimed, claimedRun{schedule: schedule, run: run})
+		}
+
+		return nil
+	})
+
+	return claimed, err
+}
+
+// execute moves the money of a claimed run through ScheduledTransferTx and records the outcome.
+// A run whose schedule no longer matches its accounts fails with the reason, like one without funds.
+func (scheduler *Scheduler) execute(ctx context.Context, c claimedRun) error {
+	arg := db.FinishScheduledTransferRunParams{
+		ID:     c.run.ID,
+		Status: db.RunSucceeded,
+	}
+
+	result, err := scheduler.store.ScheduledTransferTx(ctx, db.ScheduledTransferTxParams{
+		TransferTxParams: db.TransferTxParams{
+			FromAccountID: c.schedule.FromAccountID,
+			ToAccountID:   c.schedule.ToAccountID,
+			Amount:        c.schedule.Amount,
+		},
+		Owner: c.schedule.Owner,
+	})
+	if err != nil {
+		arg.Status = db.RunFailed
+		arg.Error = pgtype.Text{String: err.Error(), Valid: true}
+	} else {
+		arg.TransferID = pgtype.Int8{Int64: result.Transfer.ID, Valid: true}
+	}
+
+	_, err = scheduler.store.FinishScheduledTransferRun(ctx, arg)
+	return err
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	mockdb "github.com/WilliamOdinson/simplebank/db/mock"
+	db "github.com/WilliamOdinson/simplebank/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/mock/gomock"
+)
+
+func TestExecute(t *testing.T) {
+	schedule := db.ScheduledTransfer{ID: 7, Owner: "alice", FromAccountID: 1, ToAccountID: 2, Amount: 150}
+	run := db.ScheduledTransferRun{ID: 11, ScheduledTransferID: schedule.ID, Status: db.RunPending}
+	transferArg := db.ScheduledTransferTxParams{
+		TransferTxParams: db.TransferTxParams{FromAccountID: 1, ToAccountID: 2, Amount: 150},
+		Owner:            "alice",
+	}
+	fundsErr := &db.InsufficientFundsError{AccountID: 1, Available: 100, Requested: 150}
+	mismatchErr := fmt.Errorf("%w: accounts are in USD and EUR", db.ErrScheduledTransferMismatch)
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name: "Succeeded",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ScheduledTransferTx(gomock.Any(), gomock.Eq(transferArg)).
+					Times(1).
+					Return(db.TransferTxResult{Transfer: db.Transfer{ID: 99}}, nil)
+				store.EXPECT().
+					FinishScheduledTransferRun(gomock.Any(), gomock.Eq(db.FinishScheduledTransferRunParams{
+						ID:         run.ID,
+						Status:     db.RunSucceeded,
+						TransferID: pgtype.Int8{Int64: 99, Valid: true},
+					})).
+					Times(1)
+			},
+		},
+		{
+			name: "Failed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ScheduledTransferTx(gomock.Any(), gomock.Eq(transferArg)).
+					Times(1).
+					Return(db.TransferTxResult{}, fundsErr)
+				store.EXPECT().
+					FinishScheduledTransferRun(gomock.Any(), gomock.Eq(db.FinishScheduledTransferRunParams{
+						ID:     run.ID,
+						Status: db.RunFailed,
+						Error:  pgtype.Text{String: fundsErr.Error(), Valid: true},
+					})).
+					Times(1)
+			},
+		},
+		{
+			// the accounts changed since the schedule was checked, so the run fails with the reason
+			name: "Mismatch",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ScheduledTransferTx(gomock.Any(), gomock.Eq(transferArg)).
+					Times(1).
+					Return(db.TransferTxResult{}, mismatchErr)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					FinishScheduledTransferRun(gomock.Any(), gomock.Eq(db.FinishScheduledTransferRunParams{
+						ID:     run.ID,
+						Status: db.RunFailed,
+						Error:  pgtype.Text{String: mismatchErr.Error(), Valid: true},
+					})).
+					Times(1)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			scheduler := New(store, 10)
+			err := scheduler.execute(context.Background(), claimedRun{schedule: schedule, run: run})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
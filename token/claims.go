@@ -0,0 +1,78 @@
+package token
+
+import (
+	"slices"
+	"time"
+)
+
+// DefaultIssuer is stamped into tokens when no issuer is configured
+const DefaultIssuer = "SimpleBank Inc."
+
+// Option configures the issuer and audience a maker stamps into and requires of its tokens
+type Option func(*claimsPolicy)
+
+// WithIssuer sets the issuer of created tokens. Verified tokens must carry the same issuer.
+func WithIssuer(issuer string) Option {
+	return func(policy *claimsPolicy) {
+		policy.issuer = issuer
+	}
+}
+
+// WithAudience sets the audience of created tokens. Verified tokens must be
+// addressed to at least one of these audiences.
+func WithAudience(audience ...string) Option {
+	return func(policy *claimsPolicy) {
+		policy.audience = audience
+	}
+}
+
+// WithKeyID sets the ID that a symmetric key embeds in its tokens, so a KeyRing can pick the key to verify them with.
+// Without it the key gets a random ID, and its tokens can only be verified by the same maker.
+// Public keys are always identified by their thumbprint.
+func WithKeyID(keyID string) Option {
+	return func(policy *claimsPolicy) {
+		policy.keyID = keyID
+	}
+}
+
+type claimsPolicy struct {
+	issuer   string
+	audience []string
+	// keyID is not a claim, but is set through the same options
+	keyID string
+}
+
+func newClaimsPolicy(opts []Option) claimsPolicy {
+	policy := claimsPolicy{issuer: DefaultIssuer}
+	for _, opt := range opts {
+		opt(&policy)
+	}
+	return policy
+}
+
+// newPayload creates a payload carrying the configured issuer and audience
+func (policy claimsPolicy) newPayload(username string, role string, tokenType TokenType, duration time.Duration) (*Payload, error) {
+	payload, err := NewPayload(username, role, tokenType, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.Issuer = policy.issuer
+	payload.Audience = policy.audience
+	return payload, nil
+}
+
+// verify checks the issuer and audience of a payload whose signature has already been verified
+func (policy claimsPolicy) verify(payload *Payload) error {
+	if payload.Issuer != policy.issuer {
+		return ErrInvalidIssuer
+	}
+
+	if len(policy.audience) > 0 && !slices.ContainsFunc(payload.Audience, func(audience string) bool {
+		return slices.Contains(policy.audience, audience)
+	}) {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
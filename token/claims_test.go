@@ -0,0 +1,53 @@
+package token
+
+import (
+	"crypto/elliptic"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+func TestClaimsPolicy_AllMakers(t *testing.T) {
+	edKey := randomEd25519Key(t)
+	ecKey := randomECDSAKey(t, elliptic.P256())
+	secretKey := gofakeit.LetterN(32)
+
+	builders := map[string]func(opts ...Option) (Maker, error){
+		"PasetoMaker": func(opts ...Option) (Maker, error) { return NewPasetoMaker(secretKey, opts...) },
+		"JWTMaker":    func(opts ...Option) (Maker, error) { return NewJWTMaker(secretKey, opts...) },
+		"PasetoPublicMaker": func(opts ...Option) (Maker, error) {
+			return NewPasetoPublicMaker(edKey, edKey.Public(), opts...)
+		},
+		"JWTPublicMaker": func(opts ...Option) (Maker, error) {
+			return NewJWTPublicMaker(ecKey, ecKey.Public(), opts...)
+		},
+	}
+
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			signer, err := build(WithIssuer("a"), WithAudience("x"))
+			if err != nil {
+				t.Fatalf("failed to create maker: %v", err)
+			}
+			wrongIssuer, _ := build(WithIssuer("b"), WithAudience("x"))
+			wrongAudience, _ := build(WithIssuer("a"), WithAudience("y"))
+
+			token, _, err := signer.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+			if err != nil {
+				t.Fatalf("failed to create token: %v", err)
+			}
+
+			if _, err := signer.VerifyToken(token); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if _, err := wrongIssuer.VerifyToken(token); err != ErrInvalidIssuer {
+				t.Errorf("expected ErrInvalidIssuer, got %v", err)
+			}
+			if _, err := wrongAudience.VerifyToken(token); err != ErrInvalidAudience {
+				t.Errorf("expected ErrInvalidAudience, got %v", err)
+			}
+		})
+	}
+}
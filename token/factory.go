@@ -0,0 +1,85 @@
+package token
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/WilliamOdinson/simplebank/util"
+)
+
+// NewMaker creates the token maker selected by TOKEN_TYPE. The result is a key ring
+// that signs with the configured key and still verifies tokens signed by the
+// rotated-out keys in TOKEN_VERIFY_SYMMETRIC_KEYS and TOKEN_VERIFY_PUBLIC_KEY_PATHS,
+// which must belong to the same family (PASETO or JWT) as TOKEN_TYPE.
+// Symmetric keys are named by TOKEN_SYMMETRIC_KEY_ID, and verify-only ones are given as KEY_ID:KEY.
+func NewMaker(config util.Config) (*KeyRing, error) {
+	var opts []Option
+	if config.TokenIssuer != "" {
+		opts = append(opts, WithIssuer(config.TokenIssuer))
+	}
+	if len(config.TokenAudience) > 0 {
+		opts = append(opts, WithAudience(config.TokenAudience...))
+	}
+
+	var newSymmetric func(key string, opts ...Option) (Maker, error)
+	var newPublic func(privateKey crypto.Signer, publicKey crypto.PublicKey, opts ...Option) (Maker, error)
+
+	switch config.TokenType {
+	case util.PasetoTokenType, util.PasetoPublicTokenType:
+		newSymmetric, newPublic = NewPasetoMaker, NewPasetoPublicMaker
+	case util.JWTTokenType, util.JWTPublicTokenType:
+		newSymmetric, newPublic = NewJWTMaker, NewJWTPublicMaker
+	default:
+		return nil, fmt.Errorf("unsupported token type %q", config.TokenType)
+	}
+
+	var active Maker
+	var err error
+
+	if util.IsSymmetricTokenType(config.TokenType) {
+		if config.TokenSymmetricKeyID == "" {
+			return nil, errors.New("a symmetric key needs an ID in TOKEN_SYMMETRIC_KEY_ID")
+		}
+		active, err = newSymmetric(config.TokenSymmetricKey, append([]Option{WithKeyID(config.TokenSymmetricKeyID)}, opts...)...)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		privateKey, publicKey, err := LoadKeyPair(config.TokenPrivateKeyPath, config.TokenPublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		active, err = newPublic(privateKey, publicKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var verifyOnly []Maker
+	for _, entry := range config.TokenVerifySymmetricKeys {
+		keyID, key, ok := strings.Cut(entry, ":")
+		if !ok || keyID == "" {
+			return nil, errors.New("verify-only symmetric keys must be given as KEY_ID:KEY")
+		}
+		maker, err := newSymmetric(key, append([]Option{WithKeyID(keyID)}, opts...)...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid verify-only symmetric key %s: %w", keyID, err)
+		}
+		verifyOnly = append(verifyOnly, maker)
+	}
+	for _, path := range config.TokenVerifyPublicKeyPaths {
+		_, publicKey, err := LoadKeyPair("", path)
+		if err != nil {
+			return nil, err
+		}
+		maker, err := newPublic(nil, publicKey, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid verify-only public key %s: %w", path, err)
+		}
+		verifyOnly = append(verifyOnly, maker)
+	}
+
+	return NewKeyRing(active, verifyOnly...)
+}
@@ -0,0 +1,216 @@
+package token
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+func TestNewMaker(t *testing.T) {
+	edPrivatePath, edPublicPath := writeKeyPair(t, randomEd25519Key(t))
+	ecPrivatePath, _ := writeKeyPair(t, randomECDSAKey(t, elliptic.P256()))
+
+	testCases := []struct {
+		name     string
+		config   util.Config
+		wantType Maker
+		wantErr  bool
+	}{
+		{
+			name:     "Paseto",
+			config:   util.Config{TokenType: util.PasetoTokenType, TokenSymmetricKey: gofakeit.LetterN(32), TokenSymmetricKeyID: "primary"},
+			wantType: &PasetoMaker{},
+		},
+		{
+			name:     "JWT",
+			config:   util.Config{TokenType: util.JWTTokenType, TokenSymmetricKey: gofakeit.LetterN(32), TokenSymmetricKeyID: "primary"},
+			wantType: &JWTMaker{},
+		},
+		{
+			name:     "PasetoPublic",
+			config:   util.Config{TokenType: util.PasetoPublicTokenType, TokenPrivateKeyPath: edPrivatePath, TokenPublicKeyPath: edPublicPath},
+			wantType: &PasetoPublicMaker{},
+		},
+		{
+			name:     "JWTPublic",
+			config:   util.Config{TokenType: util.JWTPublicTokenType, TokenPrivateKeyPath: ecPrivatePath},
+			wantType: &JWTPublicMaker{},
+		},
+		{
+			name:    "UnsupportedType",
+			config:  util.Config{TokenType: "saml", TokenSymmetricKey: gofakeit.LetterN(32)},
+			wantErr: true,
+		},
+		{
+			name:    "PasetoPublicWithECDSAKey",
+			config:  util.Config{TokenType: util.PasetoPublicTokenType, TokenPrivateKeyPath: ecPrivatePath},
+			wantErr: true,
+		},
+		{
+			name:    "MissingSymmetricKeyID",
+			config:  util.Config{TokenType: util.PasetoTokenType, TokenSymmetricKey: gofakeit.LetterN(32)},
+			wantErr: true,
+		},
+		{
+			name: "VerifyOnlySymmetricKeyWithoutID",
+			config: util.Config{
+				TokenType:                util.PasetoTokenType,
+				TokenSymmetricKey:        gofakeit.LetterN(32),
+				TokenSymmetricKeyID:      "primary",
+				TokenVerifySymmetricKeys: []string{gofakeit.LetterN(32)},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "ShortSymmetricKey",
+			config:  util.Config{TokenType: util.PasetoTokenType, TokenSymmetricKey: gofakeit.LetterN(16), TokenSymmetricKeyID: "primary"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ring, err := NewMaker(tc.config)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if got, want := fmt.Sprintf("%T", ring.active), fmt.Sprintf("%T", tc.wantType); got != want {
+				t.Errorf("expected active maker %s, got %s", want, got)
+			}
+
+			token, _, err := ring.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+			if err != nil {
+				t.Fatalf("failed to create token: %v", err)
+			}
+			if _, err := ring.VerifyToken(token); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewMaker_VerifyOnlyKeys(t *testing.T) {
+	oldKey := gofakeit.LetterN(32)
+	oldPrivatePath, oldPublicPath := writeKeyPair(t, randomEd25519Key(t))
+	newPrivatePath, _ := writeKeyPair(t, randomEd25519Key(t))
+
+	oldSymmetric, err := NewMaker(util.Config{TokenType: util.PasetoTokenType, TokenSymmetricKey: oldKey, TokenSymmetricKeyID: "previous"})
+	if err != nil {
+		t.Fatalf("failed to create maker: %v", err)
+	}
+	oldPublic, err := NewMaker(util.Config{TokenType: util.PasetoPublicTokenType, TokenPrivateKeyPath: oldPrivatePath})
+	if err != nil {
+		t.Fatalf("failed to create maker: %v", err)
+	}
+
+	rotated, err := NewMaker(util.Config{
+		TokenType:                 util.PasetoPublicTokenType,
+		TokenPrivateKeyPath:       newPrivatePath,
+		TokenVerifySymmetricKeys:  []string{"previous:" + oldKey},
+		TokenVerifyPublicKeyPaths: []string{oldPublicPath},
+	})
+	if err != nil {
+		t.Fatalf("failed to create rotated maker: %v", err)
+	}
+
+	for name, maker := range map[string]Maker{"Symmetric": oldSymmetric, "Public": oldPublic} {
+		t.Run(name, func(t *testing.T) {
+			token, _, err := maker.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+			if err != nil {
+				t.Fatalf("failed to create token: %v", err)
+			}
+			if _, err := rotated.VerifyToken(token); err != nil {
+				t.Errorf("expected token signed with rotated key to verify, got %v", err)
+			}
+		})
+	}
+
+	if keys := rotated.JWKS().Keys; len(keys) != 2 {
+		t.Errorf("expected active and rotated public keys in JWKS, got %d", len(keys))
+	}
+}
+
+func TestNewMaker_IssuerAndAudience(t *testing.T) {
+	key := gofakeit.LetterN(32)
+
+	newMaker := func(issuer string, audience ...string) Maker {
+		maker, err := NewMaker(util.Config{
+			TokenType:           util.JWTTokenType,
+			TokenSymmetricKey:   key,
+			TokenSymmetricKeyID: "primary",
+			TokenIssuer:         issuer,
+			TokenAudience:       audience,
+		})
+		if err != nil {
+			t.Fatalf("failed to create maker: %v", err)
+		}
+		return maker
+	}
+
+	issuer := newMaker("https://auth.simplebank.test", "simplebank-api", "simplebank-admin")
+
+	token, payload, err := issuer.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if payload.Issuer != "https://auth.simplebank.test" {
+		t.Errorf("expected configured issuer, got %s", payload.Issuer)
+	}
+	if len(payload.Audience) != 2 {
+		t.Errorf("expected configured audience, got %v", payload.Audience)
+	}
+
+	testCases := []struct {
+		name     string
+		verifier Maker
+		wantErr  error
+	}{
+		{
+			name:     "SameIssuerAndAudience",
+			verifier: issuer,
+		},
+		{
+			name:     "OneMatchingAudience",
+			verifier: newMaker("https://auth.simplebank.test", "simplebank-admin"),
+		},
+		{
+			name:     "NoAudienceRequired",
+			verifier: newMaker("https://auth.simplebank.test"),
+		},
+		{
+			name:     "DifferentIssuer",
+			verifier: newMaker("https://evil.test", "simplebank-api"),
+			wantErr:  ErrInvalidIssuer,
+		},
+		{
+			name:     "DefaultIssuer",
+			verifier: newMaker(""),
+			wantErr:  ErrInvalidIssuer,
+		},
+		{
+			name:     "DifferentAudience",
+			verifier: newMaker("https://auth.simplebank.test", "reporting"),
+			wantErr:  ErrInvalidAudience,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.verifier.VerifyToken(token)
+			if err != tc.wantErr {
+				t.Errorf("expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
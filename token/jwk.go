@@ -0,0 +1,72 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// JWK is the JSON Web Key (RFC 7517) representation of a public verification key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg,omitempty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// newPublicJWK builds the JWK for an Ed25519 or P-256 public key.
+// The key ID is the RFC 7638 thumbprint of the key.
+func newPublicJWK(publicKey crypto.PublicKey, algorithm string) (JWK, error) {
+	jwk := JWK{Use: "sig", Algorithm: algorithm}
+
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+		jwk.KeyID = thumbprint(fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, jwk.Curve, jwk.KeyType, jwk.X))
+	case *ecdsa.PublicKey:
+		ecdhKey, err := key.ECDH()
+		if err != nil {
+			return JWK{}, ErrUnsupportedKey
+		}
+		// uncompressed point: 0x04 || X || Y
+		point := ecdhKey.Bytes()
+		size := (len(point) - 1) / 2
+		jwk.KeyType = "EC"
+		jwk.Curve = key.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(point[1 : 1+size])
+		jwk.Y = base64.RawURLEncoding.EncodeToString(point[1+size:])
+		jwk.KeyID = thumbprint(fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, jwk.Curve, jwk.KeyType, jwk.X, jwk.Y))
+	default:
+		return JWK{}, ErrUnsupportedKey
+	}
+
+	return jwk, nil
+}
+
+// symmetricKeyID returns the configured ID of a symmetric key, or else a random one.
+// Unlike a thumbprint, neither reveals anything about the secret to whoever reads a token.
+func symmetricKeyID(policy claimsPolicy) string {
+	if policy.keyID != "" {
+		return policy.keyID
+	}
+	return rand.Text()
+}
+
+func thumbprint(canonicalJSON string) string {
+	sum := sha256.Sum256([]byte(canonicalJSON))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -16,30 +16,36 @@ const (
 // JWTMaker is a JSON Web Token maker
 type JWTMaker struct {
 	secretKey string
+	keyID     string
+	claims    claimsPolicy
 }
 
 // CreateToken creates a new token for a specific username and valid duration
-func NewJWTMaker(secretKey string) (Maker, error) {
+func NewJWTMaker(secretKey string, opts ...Option) (Maker, error) {
 	if len(secretKey) < MIN_SECRET_KEY_SIZE {
 		return nil, fmt.Errorf("invalid secret key size: must be at least 32 characters")
 	}
 
-	return &JWTMaker{secretKey}, nil
+	claims := newClaimsPolicy(opts)
+	return &JWTMaker{secretKey, symmetricKeyID(claims), claims}, nil
 }
 
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *JWTMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := maker.claims.newPayload(username, role, tokenType, duration)
 	if err != nil {
-		return "", fmt.Errorf("failed to create payload: %w", err)
+		return "", nil, fmt.Errorf("failed to create payload: %w", err)
 	}
 
-	jwtToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, payload).SignedString([]byte(maker.secretKey))
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	unsigned.Header["kid"] = maker.keyID
+
+	jwtToken, err := unsigned.SignedString([]byte(maker.secretKey))
 
 	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
+		return "", nil, fmt.Errorf("failed to sign token: %w", err)
 	}
 
-	return jwtToken, nil
+	return jwtToken, payload, nil
 }
 
 func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
@@ -65,5 +71,15 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
+	err = maker.claims.verify(payload)
+	if err != nil {
+		return nil, err
+	}
+
 	return payload, nil
 }
+
+// KeyID returns the ID embedded in the header of the tokens created by this maker
+func (maker *JWTMaker) KeyID() string {
+	return maker.keyID
+}
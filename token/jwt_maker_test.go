@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -121,7 +122,7 @@ func TestJWTMaker_CreateToken(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			token, err := maker.CreateToken(tc.username, tc.duration)
+			token, payload, err := maker.CreateToken(tc.username, util.DepositorRole, AccessToken, tc.duration)
 			if tc.wantErr {
 				if err == nil {
 					t.Errorf("expected error, got nil")
@@ -133,6 +134,9 @@ func TestJWTMaker_CreateToken(t *testing.T) {
 				if token == "" {
 					t.Errorf("expected non-empty token")
 				}
+				if payload == nil || payload.Username != tc.username {
+					t.Errorf("expected payload for username %s, got %v", tc.username, payload)
+				}
 			}
 		})
 	}
@@ -149,7 +153,7 @@ func TestJWTMaker_VerifyToken(t *testing.T) {
 		username := gofakeit.LetterN(10)
 		duration := time.Minute
 
-		token, err := maker.CreateToken(username, duration)
+		token, _, err := maker.CreateToken(username, util.DepositorRole, AccessToken, duration)
 		if err != nil {
 			t.Fatalf("failed to create token: %v", err)
 		}
@@ -170,7 +174,7 @@ func TestJWTMaker_VerifyToken(t *testing.T) {
 		username := gofakeit.LetterN(10)
 		duration := -time.Minute // Already expired
 
-		token, err := maker.CreateToken(username, duration)
+		token, _, err := maker.CreateToken(username, util.DepositorRole, AccessToken, duration)
 		if err != nil {
 			t.Fatalf("failed to create token: %v", err)
 		}
@@ -221,7 +225,7 @@ func TestJWTMaker_VerifyToken(t *testing.T) {
 			t.Fatalf("failed to create different JWT maker: %v", err)
 		}
 
-		token, err := differentMaker.CreateToken("testuser", time.Minute)
+		token, _, err := differentMaker.CreateToken("testuser", util.DepositorRole, AccessToken, time.Minute)
 		if err != nil {
 			t.Fatalf("failed to create token: %v", err)
 		}
@@ -238,7 +242,7 @@ func TestJWTMaker_VerifyToken(t *testing.T) {
 
 	t.Run("TokenWithNoneAlgorithm", func(t *testing.T) {
 		// Create a token with "none" algorithm (security attack attempt)
-		payload, _ := NewPayload(gofakeit.LetterN(10), time.Minute)
+		payload, _ := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
 		jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
 		token, _ := jwtToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
 
@@ -262,7 +266,7 @@ func TestJWTMaker_TokenPayloadContent(t *testing.T) {
 	username := gofakeit.LetterN(10)
 	duration := time.Hour
 
-	token, err := maker.CreateToken(username, duration)
+	token, _, err := maker.CreateToken(username, util.BankerRole, RefreshToken, duration)
 	if err != nil {
 		t.Fatalf("failed to create token: %v", err)
 	}
@@ -277,6 +281,14 @@ func TestJWTMaker_TokenPayloadContent(t *testing.T) {
 		t.Errorf("expected username %s, got %s", username, payload.Username)
 	}
 
+	if payload.Role != util.BankerRole {
+		t.Errorf("expected role %s, got %s", util.BankerRole, payload.Role)
+	}
+
+	if payload.Type != RefreshToken {
+		t.Errorf("expected token type %s, got %s", RefreshToken, payload.Type)
+	}
+
 	if payload.ID.String() == "" {
 		t.Error("expected non-empty ID")
 	}
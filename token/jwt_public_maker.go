@@ -0,0 +1,110 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTPublicMaker is a JSON Web Token maker that signs with EdDSA or ES256
+// depending on the type of the configured key pair
+type JWTPublicMaker struct {
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+	jwk        JWK
+	claims     claimsPolicy
+}
+
+// NewJWTPublicMaker creates a new JWTPublicMaker. Ed25519 keys select EdDSA and
+// P-256 keys select ES256. The private key may be nil, in which case the maker
+// can only verify tokens.
+func NewJWTPublicMaker(privateKey crypto.Signer, publicKey crypto.PublicKey, opts ...Option) (Maker, error) {
+	if err := checkSigningKeys(privateKey, publicKey); err != nil {
+		return nil, err
+	}
+
+	var method jwt.SigningMethod = jwt.SigningMethodES256
+	if _, ok := publicKey.(ed25519.PublicKey); ok {
+		method = jwt.SigningMethodEdDSA
+	}
+
+	jwk, err := newPublicJWK(publicKey, method.Alg())
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWTPublicMaker{
+		method:     method,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		jwk:        jwk,
+		claims:     newClaimsPolicy(opts),
+	}, nil
+}
+
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *JWTPublicMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	if maker.privateKey == nil {
+		return "", nil, ErrVerifyOnly
+	}
+
+	payload, err := maker.claims.newPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create payload: %w", err)
+	}
+
+	unsigned := jwt.NewWithClaims(maker.method, payload)
+	unsigned.Header["kid"] = maker.jwk.KeyID
+
+	jwtToken, err := unsigned.SignedString(maker.privateKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return jwtToken, payload, nil
+}
+
+// VerifyToken checks if the token is valid or not
+func (maker *JWTPublicMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (any, error) {
+		if token.Method.Alg() != maker.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return maker.publicKey, nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrExpiredToken
+	} else if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	err = maker.claims.verify(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// KeyID returns the ID embedded in the header of the tokens created by this maker
+func (maker *JWTPublicMaker) KeyID() string {
+	return maker.jwk.KeyID
+}
+
+// JWK returns the public key of this maker as a JSON Web Key
+func (maker *JWTPublicMaker) JWK() JWK {
+	return maker.jwk
+}
@@ -0,0 +1,212 @@
+package token
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestNewJWTPublicMaker(t *testing.T) {
+	edKey := randomEd25519Key(t)
+	p256Key := randomECDSAKey(t, elliptic.P256())
+	p384Key := randomECDSAKey(t, elliptic.P384())
+
+	testCases := []struct {
+		name       string
+		privateKey crypto.Signer
+		publicKey  crypto.PublicKey
+		wantAlg    string
+		wantErr    bool
+	}{
+		{
+			name:       "Ed25519",
+			privateKey: edKey,
+			publicKey:  edKey.Public(),
+			wantAlg:    "EdDSA",
+		},
+		{
+			name:       "P256",
+			privateKey: p256Key,
+			publicKey:  p256Key.Public(),
+			wantAlg:    "ES256",
+		},
+		{
+			name:      "VerifyOnly",
+			publicKey: p256Key.Public(),
+			wantAlg:   "ES256",
+		},
+		{
+			name:       "P384",
+			privateKey: p384Key,
+			publicKey:  p384Key.Public(),
+			wantErr:    true,
+		},
+		{
+			name:       "MismatchedKeys",
+			privateKey: edKey,
+			publicKey:  p256Key.Public(),
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := NewJWTPublicMaker(tc.privateKey, tc.publicKey)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if maker != nil {
+					t.Errorf("expected nil maker, got %v", maker)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if alg := maker.(*JWTPublicMaker).method.Alg(); alg != tc.wantAlg {
+				t.Errorf("expected alg %s, got %s", tc.wantAlg, alg)
+			}
+		})
+	}
+}
+
+func TestJWTPublicMaker_VerifyToken(t *testing.T) {
+	for _, alg := range []string{"EdDSA", "ES256"} {
+		t.Run(alg, func(t *testing.T) {
+			var privateKey crypto.Signer = randomEd25519Key(t)
+			if alg == "ES256" {
+				privateKey = randomECDSAKey(t, elliptic.P256())
+			}
+
+			privatePath, publicPath := writeKeyPair(t, privateKey)
+			signingKey, _, err := LoadKeyPair(privatePath, "")
+			if err != nil {
+				t.Fatalf("failed to load private key: %v", err)
+			}
+			_, verifyKey, err := LoadKeyPair("", publicPath)
+			if err != nil {
+				t.Fatalf("failed to load public key: %v", err)
+			}
+
+			maker, err := NewJWTPublicMaker(signingKey, signingKey.Public())
+			if err != nil {
+				t.Fatalf("failed to create JWT public maker: %v", err)
+			}
+			verifier, err := NewJWTPublicMaker(nil, verifyKey)
+			if err != nil {
+				t.Fatalf("failed to create verify-only maker: %v", err)
+			}
+
+			t.Run("ValidToken", func(t *testing.T) {
+				username := gofakeit.LetterN(10)
+
+				token, _, err := maker.CreateToken(username, util.DepositorRole, AccessToken, time.Minute)
+				if err != nil {
+					t.Fatalf("failed to create token: %v", err)
+				}
+
+				payload, err := verifier.VerifyToken(token)
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if payload.Username != username {
+					t.Errorf("expected username %s, got %s", username, payload.Username)
+				}
+				if payload.Role != util.DepositorRole {
+					t.Errorf("expected role %s, got %s", util.DepositorRole, payload.Role)
+				}
+			})
+
+			t.Run("VerifyOnlyCannotCreate", func(t *testing.T) {
+				_, _, err := verifier.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+				if err != ErrVerifyOnly {
+					t.Errorf("expected ErrVerifyOnly, got %v", err)
+				}
+			})
+
+			t.Run("ExpiredToken", func(t *testing.T) {
+				token, _, err := maker.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, -time.Minute)
+				if err != nil {
+					t.Fatalf("failed to create token: %v", err)
+				}
+
+				payload, err := verifier.VerifyToken(token)
+				if err != ErrExpiredToken {
+					t.Errorf("expected ErrExpiredToken, got %v", err)
+				}
+				if payload != nil {
+					t.Errorf("expected nil payload for expired token")
+				}
+			})
+
+			t.Run("InvalidToken", func(t *testing.T) {
+				payload, err := verifier.VerifyToken(gofakeit.LetterN(50))
+				if err != ErrInvalidToken {
+					t.Errorf("expected ErrInvalidToken, got %v", err)
+				}
+				if payload != nil {
+					t.Errorf("expected nil payload for invalid token")
+				}
+			})
+		})
+	}
+}
+
+func TestJWTPublicMaker_RejectsOtherAlgorithms(t *testing.T) {
+	edKey := randomEd25519Key(t)
+	verifier, err := NewJWTPublicMaker(nil, edKey.Public())
+	if err != nil {
+		t.Fatalf("failed to create verify-only maker: %v", err)
+	}
+
+	payload, err := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create payload: %v", err)
+	}
+
+	testCases := []struct {
+		name  string
+		token func() string
+	}{
+		{
+			name: "HS256",
+			token: func() string {
+				token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, payload).SignedString([]byte(gofakeit.LetterN(32)))
+				return token
+			},
+		},
+		{
+			name: "None",
+			token: func() string {
+				token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, payload).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				return token
+			},
+		},
+		{
+			name: "ES256",
+			token: func() string {
+				token, _ := jwt.NewWithClaims(jwt.SigningMethodES256, payload).SignedString(randomECDSAKey(t, elliptic.P256()))
+				return token
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := verifier.VerifyToken(tc.token())
+			if err != ErrInvalidToken {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+			if result != nil {
+				t.Errorf("expected nil payload, got %v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+func newTestPasetoMaker(t *testing.T) Maker {
+	t.Helper()
+	maker, err := NewPasetoMaker(gofakeit.LetterN(chacha20poly1305.KeySize))
+	if err != nil {
+		t.Fatalf("failed to create PASETO maker: %v", err)
+	}
+	return maker
+}
+
+func newTestPasetoPublicMaker(t *testing.T) Maker {
+	t.Helper()
+	key := randomEd25519Key(t)
+	maker, err := NewPasetoPublicMaker(key, key.Public())
+	if err != nil {
+		t.Fatalf("failed to create PASETO public maker: %v", err)
+	}
+	return maker
+}
+
+func TestKeyRing_Rotation(t *testing.T) {
+	oldMaker := newTestPasetoMaker(t)
+	newMaker := newTestPasetoPublicMaker(t)
+
+	oldToken, _, err := oldMaker.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	ring, err := NewKeyRing(newMaker, oldMaker)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+
+	t.Run("NewTokensUseActiveKey", func(t *testing.T) {
+		token, _, err := ring.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		if kid := tokenKeyID(token); kid != newMaker.(keyedMaker).KeyID() {
+			t.Errorf("expected kid %s, got %s", newMaker.(keyedMaker).KeyID(), kid)
+		}
+		if _, err := ring.VerifyToken(token); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("RotatedKeyStillVerifies", func(t *testing.T) {
+		if _, err := ring.VerifyToken(oldToken); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("RemovedKeyRejected", func(t *testing.T) {
+		rotatedOut, err := NewKeyRing(newMaker)
+		if err != nil {
+			t.Fatalf("failed to create key ring: %v", err)
+		}
+		payload, err := rotatedOut.VerifyToken(oldToken)
+		if err != ErrInvalidToken {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+		if payload != nil {
+			t.Errorf("expected nil payload, got %v", payload)
+		}
+	})
+
+	t.Run("ExpiredToken", func(t *testing.T) {
+		token, _, err := oldMaker.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, -time.Minute)
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		if _, err := ring.VerifyToken(token); err != ErrExpiredToken {
+			t.Errorf("expected ErrExpiredToken, got %v", err)
+		}
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		if _, err := ring.VerifyToken(gofakeit.LetterN(50)); err != ErrInvalidToken {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+}
+
+func TestNewKeyRing_DuplicateKeyID(t *testing.T) {
+	first, _ := NewPasetoMaker(gofakeit.LetterN(chacha20poly1305.KeySize), WithKeyID("primary"))
+	second, _ := NewPasetoMaker(gofakeit.LetterN(chacha20poly1305.KeySize), WithKeyID("primary"))
+
+	ring, err := NewKeyRing(first, second)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if ring != nil {
+		t.Errorf("expected nil ring, got %v", ring)
+	}
+}
+
+func TestTokenKeyID(t *testing.T) {
+	jwtMaker, err := NewJWTMaker(gofakeit.LetterN(32))
+	if err != nil {
+		t.Fatalf("failed to create JWT maker: %v", err)
+	}
+	ecKey := randomECDSAKey(t, elliptic.P256())
+	jwtPublicMaker, err := NewJWTPublicMaker(ecKey, ecKey.Public())
+	if err != nil {
+		t.Fatalf("failed to create JWT public maker: %v", err)
+	}
+
+	makers := map[string]Maker{
+		"PasetoMaker":       newTestPasetoMaker(t),
+		"PasetoPublicMaker": newTestPasetoPublicMaker(t),
+		"JWTMaker":          jwtMaker,
+		"JWTPublicMaker":    jwtPublicMaker,
+	}
+
+	for name, maker := range makers {
+		t.Run(name, func(t *testing.T) {
+			token, _, err := maker.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+			if err != nil {
+				t.Fatalf("failed to create token: %v", err)
+			}
+
+			want := maker.(keyedMaker).KeyID()
+			if want == "" {
+				t.Fatal("expected non-empty key ID")
+			}
+			if kid := tokenKeyID(token); kid != want {
+				t.Errorf("expected kid %s, got %s", want, kid)
+			}
+		})
+	}
+
+	t.Run("Garbage", func(t *testing.T) {
+		if kid := tokenKeyID(gofakeit.LetterN(50)); kid != "" {
+			t.Errorf("expected empty kid, got %s", kid)
+		}
+	})
+}
+
+func TestKeyRing_JWKS(t *testing.T) {
+	publicMaker := newTestPasetoPublicMaker(t)
+	ecKey := randomECDSAKey(t, elliptic.P256())
+	jwtPublicMaker, err := NewJWTPublicMaker(nil, ecKey.Public())
+	if err != nil {
+		t.Fatalf("failed to create JWT public maker: %v", err)
+	}
+
+	ring, err := NewKeyRing(publicMaker, newTestPasetoMaker(t), jwtPublicMaker)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+
+	set := ring.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 public keys, got %d", len(set.Keys))
+	}
+
+	okp := set.Keys[0]
+	if okp.KeyType != "OKP" || okp.Curve != "Ed25519" || okp.KeyID != publicMaker.(keyedMaker).KeyID() {
+		t.Errorf("unexpected active key %+v", okp)
+	}
+
+	ec := set.Keys[1]
+	if ec.KeyType != "EC" || ec.Curve != "P-256" || ec.Algorithm != "ES256" || ec.Y == "" {
+		t.Errorf("unexpected verify-only key %+v", ec)
+	}
+
+	symmetricOnly, err := NewKeyRing(newTestPasetoMaker(t))
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	if keys := symmetricOnly.JWKS().Keys; len(keys) != 0 {
+		t.Errorf("expected no public keys, got %v", keys)
+	}
+}
+
+func TestNewPublicJWK_Thumbprint(t *testing.T) {
+	// RFC 8037 appendix A.2 and A.3
+	x, _ := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+
+	jwk, err := newPublicJWK(ed25519.PublicKey(x), "EdDSA")
+	if err != nil {
+		t.Fatalf("failed to build JWK: %v", err)
+	}
+	if jwk.KeyID != "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+		t.Errorf("unexpected thumbprint %s", jwk.KeyID)
+	}
+}
+
+func TestSymmetricKeyID(t *testing.T) {
+	secretKey := gofakeit.LetterN(chacha20poly1305.KeySize)
+
+	configured, err := NewPasetoMaker(secretKey, WithKeyID("primary"))
+	if err != nil {
+		t.Fatalf("failed to create maker: %v", err)
+	}
+	if kid := configured.(keyedMaker).KeyID(); kid != "primary" {
+		t.Errorf("expected the configured key ID, got %s", kid)
+	}
+
+	// without a configured ID each maker gets a random one, never one derived from the secret
+	first, _ := NewJWTMaker(secretKey)
+	second, _ := NewJWTMaker(secretKey)
+	if first.(keyedMaker).KeyID() == second.(keyedMaker).KeyID() {
+		t.Errorf("expected random key IDs, got %s twice", first.(keyedMaker).KeyID())
+	}
+}
@@ -0,0 +1,127 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrUnsupportedKey is returned when a key is not of a type supported by the maker
+	ErrUnsupportedKey = errors.New("unsupported key type")
+
+	// ErrVerifyOnly is returned when a maker without a private key is asked to create a token
+	ErrVerifyOnly = errors.New("maker has no private key and can only verify tokens")
+)
+
+// LoadKeyPair loads a PKCS#8 private key and a PKIX public key from PEM files.
+// The private key path may be empty for services that only verify tokens, and
+// the public key path may be empty when it can be derived from the private key.
+func LoadKeyPair(privateKeyPath string, publicKeyPath string) (crypto.Signer, crypto.PublicKey, error) {
+	if privateKeyPath == "" && publicKeyPath == "" {
+		return nil, nil, fmt.Errorf("no key files configured")
+	}
+
+	var privateKey crypto.Signer
+	var publicKey crypto.PublicKey
+
+	if privateKeyPath != "" {
+		key, err := loadPrivateKey(privateKeyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		privateKey = key
+		publicKey = key.Public()
+	}
+
+	if publicKeyPath != "" {
+		key, err := loadPublicKey(publicKeyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if privateKey != nil && !publicKeyEqual(publicKey, key) {
+			return nil, nil, fmt.Errorf("public key %s does not match private key %s", publicKeyPath, privateKeyPath)
+		}
+		publicKey = key
+	}
+
+	return privateKey, publicKey, nil
+}
+
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %w", path, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+
+	return signer, nil
+}
+
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	return block, nil
+}
+
+func publicKeyEqual(a crypto.PublicKey, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(b)
+}
+
+// checkSigningKeys verifies that the private key, if any, belongs to the public key
+// and that the public key is an Ed25519 or P-256 ECDSA key
+func checkSigningKeys(privateKey crypto.Signer, publicKey crypto.PublicKey) error {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return ErrUnsupportedKey
+		}
+	default:
+		return ErrUnsupportedKey
+	}
+
+	if privateKey != nil && !publicKeyEqual(privateKey.Public(), publicKey) {
+		return fmt.Errorf("public key does not match private key")
+	}
+
+	return nil
+}
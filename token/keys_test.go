@@ -0,0 +1,147 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeKeyPair writes the key pair as PKCS#8/PKIX PEM files into a temp directory
+// and returns their paths
+func writeKeyPair(t *testing.T, privateKey crypto.Signer) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+	writePEM(t, privatePath, "PRIVATE KEY", privateDER)
+	writePEM(t, publicPath, "PUBLIC KEY", publicDER)
+
+	return privatePath, publicPath
+}
+
+func writePEM(t *testing.T, path string, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func randomEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+	return privateKey
+}
+
+func randomECDSAKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+	return privateKey
+}
+
+func TestLoadKeyPair(t *testing.T) {
+	privatePath, publicPath := writeKeyPair(t, randomEd25519Key(t))
+	_, otherPublicPath := writeKeyPair(t, randomEd25519Key(t))
+
+	garbagePath := filepath.Join(t.TempDir(), "garbage.pem")
+	if err := os.WriteFile(garbagePath, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("failed to write garbage file: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		privatePath string
+		publicPath  string
+		wantPrivate bool
+		wantErr     bool
+	}{
+		{
+			name:        "BothKeys",
+			privatePath: privatePath,
+			publicPath:  publicPath,
+			wantPrivate: true,
+		},
+		{
+			name:        "PrivateKeyOnly",
+			privatePath: privatePath,
+			wantPrivate: true,
+		},
+		{
+			name:       "PublicKeyOnly",
+			publicPath: publicPath,
+		},
+		{
+			name:    "NoKeys",
+			wantErr: true,
+		},
+		{
+			name:        "MismatchedKeys",
+			privatePath: privatePath,
+			publicPath:  otherPublicPath,
+			wantErr:     true,
+		},
+		{
+			name:       "MissingFile",
+			publicPath: filepath.Join(t.TempDir(), "missing.pem"),
+			wantErr:    true,
+		},
+		{
+			name:       "NotPEM",
+			publicPath: garbagePath,
+			wantErr:    true,
+		},
+		{
+			name:        "PublicKeyAsPrivateKey",
+			privatePath: publicPath,
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			privateKey, publicKey, err := LoadKeyPair(tc.privatePath, tc.publicPath)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if publicKey == nil {
+				t.Errorf("expected public key, got nil")
+			}
+			if tc.wantPrivate && privateKey == nil {
+				t.Errorf("expected private key, got nil")
+			}
+			if !tc.wantPrivate && privateKey != nil {
+				t.Errorf("expected no private key, got %v", privateKey)
+			}
+		})
+	}
+}
@@ -12,14 +12,26 @@ var (
 	// ErrInvalidToken is returned when the token is invalid
 	ErrInvalidToken = errors.New("token is invalid")
 
+	// ErrRevokedToken is returned when the token has been revoked before it expired
+	ErrRevokedToken = errors.New("token has been revoked")
+
+	// ErrInvalidIssuer is returned when the token was issued by someone else
+	ErrInvalidIssuer = errors.New("token has an invalid issuer")
+
+	// ErrInvalidAudience is returned when the token is not addressed to this service
+	ErrInvalidAudience = errors.New("token has an invalid audience")
+
+	// ErrWrongTokenType is returned when a token is used for something its type does not allow
+	ErrWrongTokenType = errors.New("token has the wrong type")
+
 	// ErrInvalidKeySize is returned when the key size is invalid
 	ErrInvalidKeySize = errors.New("invalid key size")
 )
 
 // Maker is an interface for managing tokens
 type Maker interface {
-	// CreateToken creates a new token for a specific username and valid duration
-	CreateToken(username string, duration time.Duration) (string, error)
+	// CreateToken creates a new token of a type for a specific username, role and valid duration
+	CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error)
 
 	// VerifyToken checks if the token is valid or not
 	VerifyToken(token string) (*Payload, error)
@@ -11,34 +11,39 @@ import (
 type PasetoMaker struct {
 	paseto       *paseto.V2
 	symmetricKey []byte
+	keyID        string
+	claims       claimsPolicy
 }
 
 // NewPasetoMaker creates a new PasetoMaker
-func NewPasetoMaker(symmetricKey string) (Maker, error) {
+func NewPasetoMaker(symmetricKey string, opts ...Option) (Maker, error) {
 	if len(symmetricKey) != chacha20poly1305.KeySize {
 		return nil, ErrInvalidKeySize
 	}
 
+	claims := newClaimsPolicy(opts)
 	maker := &PasetoMaker{
 		paseto:       paseto.NewV2(),
 		symmetricKey: []byte(symmetricKey),
+		keyID:        symmetricKeyID(claims),
+		claims:       claims,
 	}
 	return maker, nil
 }
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *PasetoMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := maker.claims.newPayload(username, role, tokenType, duration)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	token, err := maker.paseto.Encrypt([]byte(maker.symmetricKey), payload, nil)
+	token, err := maker.paseto.Encrypt([]byte(maker.symmetricKey), payload, keyFooter{KeyID: maker.keyID})
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return token, nil
+	return token, payload, nil
 }
 
 // VerifyToken checks if the token is valid or not
@@ -55,5 +60,15 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, err
 	}
 
+	err = maker.claims.verify(payload)
+	if err != nil {
+		return nil, err
+	}
+
 	return payload, nil
 }
+
+// KeyID returns the ID embedded in the footer of the tokens created by this maker
+func (maker *PasetoMaker) KeyID() string {
+	return maker.keyID
+}
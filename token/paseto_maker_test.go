@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/brianvoe/gofakeit/v7"
 	"golang.org/x/crypto/chacha20poly1305"
 )
@@ -114,7 +115,7 @@ func TestPasetoMaker_CreateToken(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			token, err := maker.CreateToken(tc.username, tc.duration)
+			token, payload, err := maker.CreateToken(tc.username, util.DepositorRole, AccessToken, tc.duration)
 			if tc.wantErr {
 				if err == nil {
 					t.Errorf("expected error, got nil")
@@ -126,6 +127,9 @@ func TestPasetoMaker_CreateToken(t *testing.T) {
 				if token == "" {
 					t.Errorf("expected non-empty token")
 				}
+				if payload == nil || payload.Username != tc.username {
+					t.Errorf("expected payload for username %s, got %v", tc.username, payload)
+				}
 			}
 		})
 	}
@@ -142,7 +146,7 @@ func TestPasetoMaker_VerifyToken(t *testing.T) {
 		username := gofakeit.LetterN(10)
 		duration := time.Minute
 
-		token, err := maker.CreateToken(username, duration)
+		token, _, err := maker.CreateToken(username, util.DepositorRole, AccessToken, duration)
 		if err != nil {
 			t.Fatalf("failed to create token: %v", err)
 		}
@@ -163,7 +167,7 @@ func TestPasetoMaker_VerifyToken(t *testing.T) {
 		username := gofakeit.LetterN(10)
 		duration := -time.Minute // Already expired
 
-		token, err := maker.CreateToken(username, duration)
+		token, _, err := maker.CreateToken(username, util.DepositorRole, AccessToken, duration)
 		if err != nil {
 			t.Fatalf("failed to create token: %v", err)
 		}
@@ -214,7 +218,7 @@ func TestPasetoMaker_VerifyToken(t *testing.T) {
 			t.Fatalf("failed to create different PASETO maker: %v", err)
 		}
 
-		token, err := differentMaker.CreateToken("testuser", time.Minute)
+		token, _, err := differentMaker.CreateToken("testuser", util.DepositorRole, AccessToken, time.Minute)
 		if err != nil {
 			t.Fatalf("failed to create token: %v", err)
 		}
@@ -240,7 +244,7 @@ func TestPasetoMaker_TokenPayloadContent(t *testing.T) {
 	username := gofakeit.LetterN(10)
 	duration := time.Hour
 
-	token, err := maker.CreateToken(username, duration)
+	token, _, err := maker.CreateToken(username, util.BankerRole, RefreshToken, duration)
 	if err != nil {
 		t.Fatalf("failed to create token: %v", err)
 	}
@@ -255,6 +259,14 @@ func TestPasetoMaker_TokenPayloadContent(t *testing.T) {
 		t.Errorf("expected username %s, got %s", username, payload.Username)
 	}
 
+	if payload.Role != util.BankerRole {
+		t.Errorf("expected role %s, got %s", util.BankerRole, payload.Role)
+	}
+
+	if payload.Type != RefreshToken {
+		t.Errorf("expected token type %s, got %s", RefreshToken, payload.Type)
+	}
+
 	if payload.ID.String() == "" {
 		t.Error("expected non-empty ID")
 	}
@@ -0,0 +1,127 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+// PasetoPublicMaker is a PASETO v4.public token maker backed by an Ed25519 key pair
+type PasetoPublicMaker struct {
+	secretKey *paseto.V4AsymmetricSecretKey
+	publicKey paseto.V4AsymmetricPublicKey
+	jwk       JWK
+	claims    claimsPolicy
+}
+
+// NewPasetoPublicMaker creates a new PasetoPublicMaker. The private key may be nil,
+// in which case the maker can only verify tokens.
+func NewPasetoPublicMaker(privateKey crypto.Signer, publicKey crypto.PublicKey, opts ...Option) (Maker, error) {
+	edPublicKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	if err := checkSigningKeys(privateKey, publicKey); err != nil {
+		return nil, err
+	}
+
+	jwk, err := newPublicJWK(publicKey, "")
+	if err != nil {
+		return nil, err
+	}
+
+	maker := &PasetoPublicMaker{jwk: jwk, claims: newClaimsPolicy(opts)}
+
+	if privateKey != nil {
+		edPrivateKey, ok := privateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, ErrUnsupportedKey
+		}
+		secretKey, err := paseto.NewV4AsymmetricSecretKeyFromEd25519(edPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		maker.secretKey = &secretKey
+	}
+
+	verifyKey, err := paseto.NewV4AsymmetricPublicKeyFromEd25519(edPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	maker.publicKey = verifyKey
+
+	return maker, nil
+}
+
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	if maker.secretKey == nil {
+		return "", nil, ErrVerifyOnly
+	}
+
+	payload, err := maker.claims.newPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	pasetoToken, err := paseto.NewTokenFromClaimsJSON(claims, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to build token: %w", err)
+	}
+
+	footer, err := json.Marshal(keyFooter{KeyID: maker.jwk.KeyID})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode footer: %w", err)
+	}
+	pasetoToken.SetFooter(footer)
+
+	return pasetoToken.V4Sign(*maker.secretKey, nil), payload, nil
+}
+
+// VerifyToken checks if the token is valid or not
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	// expiry is checked by payload.Valid, which uses our own claim names
+	parser := paseto.NewParserWithoutExpiryCheck()
+
+	pasetoToken, err := parser.ParseV4Public(maker.publicKey, token, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	err = json.Unmarshal(pasetoToken.ClaimsJSON(), payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+
+	err = maker.claims.verify(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// KeyID returns the ID embedded in the footer of the tokens created by this maker
+func (maker *PasetoPublicMaker) KeyID() string {
+	return maker.jwk.KeyID
+}
+
+// JWK returns the public key of this maker as a JSON Web Key
+func (maker *PasetoPublicMaker) JWK() JWK {
+	return maker.jwk
+}
@@ -0,0 +1,173 @@
+package token
+
+import (
+	"crypto/elliptic"
+	"testing"
+	"time"
+
+	"github.com/WilliamOdinson/simplebank/util"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+func TestNewPasetoPublicMaker(t *testing.T) {
+	edKey := randomEd25519Key(t)
+	ecKey := randomECDSAKey(t, elliptic.P256())
+
+	testCases := []struct {
+		name    string
+		build   func() (Maker, error)
+		wantErr bool
+	}{
+		{
+			name: "KeyPair",
+			build: func() (Maker, error) {
+				return NewPasetoPublicMaker(edKey, edKey.Public())
+			},
+		},
+		{
+			name: "PublicKeyOnly",
+			build: func() (Maker, error) {
+				return NewPasetoPublicMaker(nil, edKey.Public())
+			},
+		},
+		{
+			name: "MismatchedKeys",
+			build: func() (Maker, error) {
+				return NewPasetoPublicMaker(edKey, randomEd25519Key(t).Public())
+			},
+			wantErr: true,
+		},
+		{
+			name: "ECDSAKey",
+			build: func() (Maker, error) {
+				return NewPasetoPublicMaker(ecKey, ecKey.Public())
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := tc.build()
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if maker != nil {
+					t.Errorf("expected nil maker, got %v", maker)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				if maker == nil {
+					t.Errorf("expected maker, got nil")
+				}
+			}
+		})
+	}
+}
+
+func TestPasetoPublicMaker_VerifyToken(t *testing.T) {
+	privatePath, publicPath := writeKeyPair(t, randomEd25519Key(t))
+
+	privateKey, publicKey, err := LoadKeyPair(privatePath, publicPath)
+	if err != nil {
+		t.Fatalf("failed to load key pair: %v", err)
+	}
+	maker, err := NewPasetoPublicMaker(privateKey, publicKey)
+	if err != nil {
+		t.Fatalf("failed to create PASETO public maker: %v", err)
+	}
+
+	_, verifyOnlyKey, err := LoadKeyPair("", publicPath)
+	if err != nil {
+		t.Fatalf("failed to load public key: %v", err)
+	}
+	verifier, err := NewPasetoPublicMaker(nil, verifyOnlyKey)
+	if err != nil {
+		t.Fatalf("failed to create verify-only maker: %v", err)
+	}
+
+	t.Run("ValidToken", func(t *testing.T) {
+		username := gofakeit.LetterN(10)
+
+		token, created, err := maker.CreateToken(username, util.BankerRole, AccessToken, time.Minute)
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+
+		payload, err := verifier.VerifyToken(token)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if payload.ID != created.ID {
+			t.Errorf("expected ID %s, got %s", created.ID, payload.ID)
+		}
+		if payload.Username != username {
+			t.Errorf("expected username %s, got %s", username, payload.Username)
+		}
+		if payload.Role != util.BankerRole {
+			t.Errorf("expected role %s, got %s", util.BankerRole, payload.Role)
+		}
+		if !payload.ExpiredAt.Equal(created.ExpiredAt) {
+			t.Errorf("expected ExpiredAt %v, got %v", created.ExpiredAt, payload.ExpiredAt)
+		}
+	})
+
+	t.Run("VerifyOnlyCannotCreate", func(t *testing.T) {
+		token, payload, err := verifier.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+		if err != ErrVerifyOnly {
+			t.Errorf("expected ErrVerifyOnly, got %v", err)
+		}
+		if token != "" || payload != nil {
+			t.Errorf("expected no token, got %q %v", token, payload)
+		}
+	})
+
+	t.Run("ExpiredToken", func(t *testing.T) {
+		token, _, err := maker.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, -time.Minute)
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+
+		payload, err := verifier.VerifyToken(token)
+		if err != ErrExpiredToken {
+			t.Errorf("expected ErrExpiredToken, got %v", err)
+		}
+		if payload != nil {
+			t.Errorf("expected nil payload for expired token")
+		}
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		payload, err := verifier.VerifyToken(gofakeit.LetterN(50))
+		if err != ErrInvalidToken {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+		if payload != nil {
+			t.Errorf("expected nil payload for invalid token")
+		}
+	})
+
+	t.Run("TokenWithDifferentKey", func(t *testing.T) {
+		otherKey := randomEd25519Key(t)
+		otherMaker, err := NewPasetoPublicMaker(otherKey, otherKey.Public())
+		if err != nil {
+			t.Fatalf("failed to create different maker: %v", err)
+		}
+
+		token, _, err := otherMaker.CreateToken(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+
+		payload, err := verifier.VerifyToken(token)
+		if err != ErrInvalidToken {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+		if payload != nil {
+			t.Errorf("expected nil payload for token with different key")
+		}
+	})
+}
@@ -8,15 +8,32 @@ import (
 	"github.com/google/uuid"
 )
 
+// TokenType tells what a token may be used for
+type TokenType string
+
+const (
+	// AccessToken authorizes API requests
+	AccessToken TokenType = "access"
+	// RefreshToken can only be exchanged for new access tokens
+	RefreshToken TokenType = "refresh"
+	// MFAChallengeToken proves the password of a user with MFA and can only be exchanged,
+	// together with a second factor, for an access and a refresh token
+	MFAChallengeToken TokenType = "mfa_challenge"
+)
+
 // Payload contains the payload data of the token
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Type      TokenType `json:"token_type"`
+	Issuer    string    `json:"issuer"`
+	Audience  []string  `json:"audience,omitempty"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, role string, tokenType TokenType, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token ID: %w", err)
@@ -25,6 +42,9 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID:        tokenID,
 		Username:  username,
+		Role:      role,
+		Type:      tokenType,
+		Issuer:    DefaultIssuer,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
@@ -52,7 +72,7 @@ func (p *Payload) GetNotBefore() (*jwt.NumericDate, error) {
 }
 
 func (p *Payload) GetIssuer() (string, error) {
-	return "SimpleBank Inc.", nil
+	return p.Issuer, nil
 }
 
 func (p *Payload) GetSubject() (string, error) {
@@ -60,5 +80,5 @@ func (p *Payload) GetSubject() (string, error) {
 }
 
 func (p *Payload) GetAudience() (jwt.ClaimStrings, error) {
-	return nil, nil
+	return p.Audience, nil
 }
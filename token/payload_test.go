@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/WilliamOdinson/simplebank/util"
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -44,7 +45,7 @@ func TestNewPayload(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			beforeCreate := time.Now()
-			payload, err := NewPayload(tc.username, tc.duration)
+			payload, err := NewPayload(tc.username, util.DepositorRole, AccessToken, tc.duration)
 			afterCreate := time.Now()
 
 			if err != nil {
@@ -59,10 +60,16 @@ func TestNewPayload(t *testing.T) {
 				t.Error("expected valid UUID for ID")
 			}
 
-			// Verify username
+			// Verify username and role
 			if payload.Username != tc.username {
 				t.Errorf("expected username %s, got %s", tc.username, payload.Username)
 			}
+			if payload.Role != util.DepositorRole {
+				t.Errorf("expected role %s, got %s", util.DepositorRole, payload.Role)
+			}
+			if payload.Type != AccessToken {
+				t.Errorf("expected token type %s, got %s", AccessToken, payload.Type)
+			}
 
 			// Verify IssuedAt is within the creation window
 			if payload.IssuedAt.Before(beforeCreate) || payload.IssuedAt.After(afterCreate) {
@@ -87,7 +94,7 @@ func TestPayload_UniqueIDs(t *testing.T) {
 	numPayloads := 100
 
 	for i := 0; i < numPayloads; i++ {
-		payload, err := NewPayload(gofakeit.LetterN(10), time.Minute)
+		payload, err := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Minute)
 		if err != nil {
 			t.Fatalf("unexpected error creating payload %d: %v", i, err)
 		}
@@ -101,7 +108,7 @@ func TestPayload_UniqueIDs(t *testing.T) {
 
 func TestPayload_Valid(t *testing.T) {
 	t.Run("NotExpired", func(t *testing.T) {
-		payload, err := NewPayload(gofakeit.LetterN(10), time.Hour)
+		payload, err := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Hour)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -112,7 +119,7 @@ func TestPayload_Valid(t *testing.T) {
 	})
 
 	t.Run("Expired", func(t *testing.T) {
-		payload, err := NewPayload(gofakeit.LetterN(10), -time.Hour)
+		payload, err := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, -time.Hour)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -123,7 +130,7 @@ func TestPayload_Valid(t *testing.T) {
 	})
 
 	t.Run("JustExpired", func(t *testing.T) {
-		payload, err := NewPayload(gofakeit.LetterN(10), -time.Nanosecond)
+		payload, err := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, -time.Nanosecond)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -135,7 +142,7 @@ func TestPayload_Valid(t *testing.T) {
 }
 
 func TestPayload_GetExpirationTime(t *testing.T) {
-	payload, err := NewPayload(gofakeit.LetterN(10), time.Hour)
+	payload, err := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Hour)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -155,7 +162,7 @@ func TestPayload_GetExpirationTime(t *testing.T) {
 }
 
 func TestPayload_GetIssuedAt(t *testing.T) {
-	payload, err := NewPayload(gofakeit.LetterN(10), time.Hour)
+	payload, err := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Hour)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -175,7 +182,7 @@ func TestPayload_GetIssuedAt(t *testing.T) {
 }
 
 func TestPayload_GetNotBefore(t *testing.T) {
-	payload, err := NewPayload(gofakeit.LetterN(10), time.Hour)
+	payload, err := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Hour)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -196,7 +203,7 @@ func TestPayload_GetNotBefore(t *testing.T) {
 }
 
 func TestPayload_GetIssuer(t *testing.T) {
-	payload, err := NewPayload(gofakeit.LetterN(10), time.Hour)
+	payload, err := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Hour)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -231,7 +238,7 @@ func TestPayload_GetSubject(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			payload, err := NewPayload(tc.username, time.Hour)
+			payload, err := NewPayload(tc.username, util.DepositorRole, AccessToken, time.Hour)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -248,7 +255,7 @@ func TestPayload_GetSubject(t *testing.T) {
 }
 
 func TestPayload_GetAudience(t *testing.T) {
-	payload, err := NewPayload(gofakeit.LetterN(10), time.Hour)
+	payload, err := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Hour)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -263,7 +270,7 @@ func TestPayload_GetAudience(t *testing.T) {
 }
 
 func TestPayload_ImplementsJWTClaims(t *testing.T) {
-	payload, _ := NewPayload(gofakeit.LetterN(10), time.Hour)
+	payload, _ := NewPayload(gofakeit.LetterN(10), util.DepositorRole, AccessToken, time.Hour)
 
 	// Verify that Payload implements jwt.Claims interface
 	var _ jwt.Claims = payload
@@ -9,10 +9,48 @@ import (
 // Config stores all configuration of the application
 // loaded from environment variables
 type Config struct {
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBSource                  string        `mapstructure:"DB_SOURCE"`
+	ServerAddress             string        `mapstructure:"SERVER_ADDRESS"`
+	TokenType                 string        `mapstructure:"TOKEN_TYPE"`
+	TokenSymmetricKey         string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenSymmetricKeyID       string        `mapstructure:"TOKEN_SYMMETRIC_KEY_ID"`
+	TokenIssuer               string        `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience             []string      `mapstructure:"TOKEN_AUDIENCE"`
+	AccessTokenDuration       time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration      time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	RevocationSyncInterval    time.Duration `mapstructure:"REVOCATION_SYNC_INTERVAL"`
+	TokenPrivateKeyPath       string        `mapstructure:"TOKEN_PRIVATE_KEY_PATH"`
+	TokenPublicKeyPath        string        `mapstructure:"TOKEN_PUBLIC_KEY_PATH"`
+	TokenVerifySymmetricKeys  []string      `mapstructure:"TOKEN_VERIFY_SYMMETRIC_KEYS"`
+	TokenVerifyPublicKeyPaths []string      `mapstructure:"TOKEN_VERIFY_PUBLIC_KEY_PATHS"`
+	FXRatesFile               string        `mapstructure:"FX_RATES_FILE"`
+	FXQuoteDuration           time.Duration `mapstructure:"FX_QUOTE_DURATION"`
+	SchedulerInterval         time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	SchedulerBatchSize        int32         `mapstructure:"SCHEDULER_BATCH_SIZE"`
+	HoldDuration              time.Duration `mapstructure:"HOLD_DURATION"`
+	PasswordResetDuration     time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	PasswordResetMaxRequests  int           `mapstructure:"PASSWORD_RESET_MAX_REQUESTS"`
+	EmailVerificationDuration time.Duration `mapstructure:"EMAIL_VERIFICATION_DURATION"`
+	EmailVerificationURL      string        `mapstructure:"EMAIL_VERIFICATION_URL"`
+	RequireVerifiedEmail      bool          `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	MFAChallengeDuration      time.Duration `mapstructure:"MFA_CHALLENGE_DURATION"`
+	LoginDelay                time.Duration `mapstructure:"LOGIN_DELAY"`
+	LoginMaxAttempts          int32         `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginLockoutDuration      time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginIPMaxAttempts        int           `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`
+	SMTPHost                  string        `mapstructure:"SMTP_HOST"`
+	SMTPPort                  int           `mapstructure:"SMTP_PORT"`
+	SMTPUsername              string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword              string        `mapstructure:"SMTP_PASSWORD"`
+	MailFrom                  string        `mapstructure:"MAIL_FROM"`
+	MailOutbox                bool          `mapstructure:"MAIL_OUTBOX"`
+	MailOutboxDir             string        `mapstructure:"MAIL_OUTBOX_DIR"`
+	// TransferLimits are the default limits of accounts by currency, read from
+	// TRANSFER_LIMIT_PER_TRANSFER, TRANSFER_LIMIT_DAILY and TRANSFER_LIMIT_MONTHLY
+	TransferLimits map[string]TransferLimits `mapstructure:"-"`
+	// MFAStepUpAmounts are the transfer amounts by currency above which a TOTP code is required,
+	// read from MFA_STEP_UP_AMOUNT
+	MFAStepUpAmounts map[string]int64 `mapstructure:"-"`
 }
 
 // LoadConfig reads configuration from file or environment variables
@@ -26,8 +64,63 @@ func LoadConfig(path string) (config Config, err error) {
 	// environment variables
 	viper.BindEnv("DB_SOURCE")
 	viper.BindEnv("SERVER_ADDRESS")
+	viper.BindEnv("TOKEN_TYPE")
 	viper.BindEnv("TOKEN_SYMMETRIC_KEY")
+	viper.BindEnv("TOKEN_SYMMETRIC_KEY_ID")
+	viper.BindEnv("TOKEN_ISSUER")
+	viper.BindEnv("TOKEN_AUDIENCE")
 	viper.BindEnv("ACCESS_TOKEN_DURATION")
+	viper.BindEnv("REFRESH_TOKEN_DURATION")
+	viper.BindEnv("REVOCATION_SYNC_INTERVAL")
+	viper.BindEnv("TOKEN_PRIVATE_KEY_PATH")
+	viper.BindEnv("TOKEN_PUBLIC_KEY_PATH")
+	viper.BindEnv("TOKEN_VERIFY_SYMMETRIC_KEYS")
+	viper.BindEnv("TOKEN_VERIFY_PUBLIC_KEY_PATHS")
+	viper.BindEnv("FX_RATES_FILE")
+	viper.BindEnv("FX_QUOTE_DURATION")
+	viper.BindEnv("SCHEDULER_INTERVAL")
+	viper.BindEnv("SCHEDULER_BATCH_SIZE")
+	viper.BindEnv("HOLD_DURATION")
+	viper.BindEnv("PASSWORD_RESET_DURATION")
+	viper.BindEnv("PASSWORD_RESET_MAX_REQUESTS")
+	viper.BindEnv("EMAIL_VERIFICATION_DURATION")
+	viper.BindEnv("EMAIL_VERIFICATION_URL")
+	viper.BindEnv("REQUIRE_VERIFIED_EMAIL")
+	viper.BindEnv("MFA_CHALLENGE_DURATION")
+	viper.BindEnv("MFA_STEP_UP_AMOUNT")
+	viper.BindEnv("LOGIN_DELAY")
+	viper.BindEnv("LOGIN_MAX_ATTEMPTS")
+	viper.BindEnv("LOGIN_LOCKOUT_DURATION")
+	viper.BindEnv("LOGIN_IP_MAX_ATTEMPTS")
+	viper.BindEnv("SMTP_HOST")
+	viper.BindEnv("SMTP_PORT")
+	viper.BindEnv("SMTP_USERNAME")
+	viper.BindEnv("SMTP_PASSWORD")
+	viper.BindEnv("MAIL_FROM")
+	viper.BindEnv("MAIL_OUTBOX")
+	viper.BindEnv("MAIL_OUTBOX_DIR")
+	viper.BindEnv("TRANSFER_LIMIT_PER_TRANSFER")
+	viper.BindEnv("TRANSFER_LIMIT_DAILY")
+	viper.BindEnv("TRANSFER_LIMIT_MONTHLY")
+
+	// optional settings
+	viper.SetDefault("REVOCATION_SYNC_INTERVAL", 30*time.Second)
+	viper.SetDefault("TOKEN_TYPE", PasetoTokenType)
+	viper.SetDefault("FX_QUOTE_DURATION", 30*time.Second)
+	viper.SetDefault("SCHEDULER_INTERVAL", time.Minute)
+	viper.SetDefault("SCHEDULER_BATCH_SIZE", 100)
+	viper.SetDefault("HOLD_DURATION", 7*24*time.Hour)
+	viper.SetDefault("PASSWORD_RESET_DURATION", 15*time.Minute)
+	viper.SetDefault("PASSWORD_RESET_MAX_REQUESTS", 5)
+	viper.SetDefault("EMAIL_VERIFICATION_DURATION", 24*time.Hour)
+	viper.SetDefault("EMAIL_VERIFICATION_URL", "http://localhost:8080/users/verify_email")
+	viper.SetDefault("MFA_CHALLENGE_DURATION", 5*time.Minute)
+	viper.SetDefault("LOGIN_DELAY", time.Second)
+	viper.SetDefault("LOGIN_MAX_ATTEMPTS", 5)
+	viper.SetDefault("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	viper.SetDefault("LOGIN_IP_MAX_ATTEMPTS", 50)
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("MAIL_FROM", "SimpleBank <no-reply@simplebank.local>")
 
 	// Try to read config file (if it exists)
 	viper.ReadInConfig()
@@ -43,12 +136,35 @@ func LoadConfig(path string) (config Config, err error) {
 	if config.ServerAddress == "" {
 		panic("SERVER_ADDRESS is required")
 	}
-	if config.TokenSymmetricKey == "" {
+	if !IsSupportedTokenType(config.TokenType) {
+		panic("TOKEN_TYPE must be one of paseto, jwt, paseto_public or jwt_public")
+	}
+	if IsSymmetricTokenType(config.TokenType) && config.TokenSymmetricKey == "" {
 		panic("TOKEN_SYMMETRIC_KEY is required")
 	}
+	if IsSymmetricTokenType(config.TokenType) && config.TokenSymmetricKeyID == "" {
+		panic("TOKEN_SYMMETRIC_KEY_ID is required")
+	}
+	if !IsSymmetricTokenType(config.TokenType) && config.TokenPrivateKeyPath == "" {
+		panic("TOKEN_PRIVATE_KEY_PATH is required")
+	}
 	if config.AccessTokenDuration == 0 {
 		panic("ACCESS_TOKEN_DURATION is required")
 	}
+	if config.RefreshTokenDuration == 0 {
+		panic("REFRESH_TOKEN_DURATION is required")
+	}
+
+	config.TransferLimits, err = ParseTransferLimits(
+		viper.GetString("TRANSFER_LIMIT_PER_TRANSFER"),
+		viper.GetString("TRANSFER_LIMIT_DAILY"),
+		viper.GetString("TRANSFER_LIMIT_MONTHLY"),
+	)
+	if err != nil {
+		return
+	}
+
+	config.MFAStepUpAmounts, err = ParseCurrencyAmounts(viper.GetString("MFA_STEP_UP_AMOUNT"))
 
 	return
 }
@@ -14,6 +14,7 @@ func TestLoadConfig(t *testing.T) {
 	require.NotEmpty(t, config.ServerAddress)
 	require.NotEmpty(t, config.TokenSymmetricKey)
 	require.NotZero(t, config.AccessTokenDuration)
+	require.NotZero(t, config.RefreshTokenDuration)
 }
 
 func TestLoadConfigNotFound(t *testing.T) {
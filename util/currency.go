@@ -1,5 +1,11 @@
 package util
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	USD = "USD"
 	EUR = "EUR"
@@ -14,3 +20,29 @@ func IsSupportedCurrency(currency string) bool {
 	}
 	return false
 }
+
+// ParseCurrencyAmounts parses a comma-separated CURRENCY:AMOUNT list, e.g. "USD:100000,EUR:90000",
+// into the positive amount of each currency
+func ParseCurrencyAmounts(value string) (map[string]int64, error) {
+	amounts := make(map[string]int64)
+
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		currency, value, found := strings.Cut(item, ":")
+		if !found || !IsSupportedCurrency(currency) {
+			return nil, fmt.Errorf("invalid amount %q: expected CURRENCY:AMOUNT", item)
+		}
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("invalid amount %q: amount must be a positive integer", item)
+		}
+
+		amounts[currency] = amount
+	}
+
+	return amounts, nil
+}
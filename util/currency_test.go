@@ -26,3 +26,31 @@ func TestIsSupportedCurrency(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCurrencyAmounts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected map[string]int64
+		wantErr  bool
+	}{
+		{name: "Empty", expected: map[string]int64{}},
+		{name: "PerCurrency", value: "USD:1000, EUR:900,", expected: map[string]int64{USD: 1000, EUR: 900}},
+		{name: "MissingAmount", value: "USD", wantErr: true},
+		{name: "UnsupportedCurrency", value: "GBP:100", wantErr: true},
+		{name: "NotANumber", value: "USD:lots", wantErr: true},
+		{name: "NotPositive", value: "USD:0", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			amounts, err := ParseCurrencyAmounts(tc.value)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, amounts)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package util
+
+// Frequencies of a scheduled transfer
+const (
+	OnceFrequency    = "once"
+	DailyFrequency   = "daily"
+	WeeklyFrequency  = "weekly"
+	MonthlyFrequency = "monthly"
+)
+
+// IsSupportedFrequency checks if the given frequency is supported.
+func IsSupportedFrequency(frequency string) bool {
+	switch frequency {
+	case OnceFrequency, DailyFrequency, WeeklyFrequency, MonthlyFrequency:
+		return true
+	}
+	return false
+}
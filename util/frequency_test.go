@@ -0,0 +1,29 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSupportedFrequency(t *testing.T) {
+	testCases := []struct {
+		frequency string
+		expected  bool
+	}{
+		{OnceFrequency, true},
+		{DailyFrequency, true},
+		{WeeklyFrequency, true},
+		{MonthlyFrequency, true},
+		{"yearly", false},
+		{"Daily", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.frequency, func(t *testing.T) {
+			result := IsSupportedFrequency(tc.frequency)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// oneTimeCodeBytes is the number of random bytes in a one-time code, 80 bits encode to 16 characters
+const oneTimeCodeBytes = 10
+
+// RandomOneTimeCode returns a random code to send to a user, e.g. to reset their password.
+// Only its hash should be stored.
+func RandomOneTimeCode() (string, error) {
+	b := make([]byte, oneTimeCodeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate one-time code: %w", err)
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// HashOneTimeCode returns the hex SHA-256 hash of a one-time code. Codes are random enough
+// not to need a salted hash, so the hash can be looked up directly. Surrounding whitespace
+// and letter case are ignored, since users type the code back in.
+func HashOneTimeCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,48 @@
+package util
+
+import "testing"
+
+func TestOneTimeCode(t *testing.T) {
+	code, err := RandomOneTimeCode()
+	if err != nil {
+		t.Fatal("Failed to generate code:", err)
+	}
+	if len(code) != 16 {
+		t.Errorf("expected a 16 character code, got %q", code)
+	}
+
+	code2, err := RandomOneTimeCode()
+	if err != nil {
+		t.Fatal("Failed to generate code:", err)
+	}
+	if code == code2 {
+		t.Error("expected different codes")
+	}
+
+	hash := HashOneTimeCode(code)
+	if len(hash) != 64 {
+		t.Errorf("expected a hex SHA-256 hash, got %q", hash)
+	}
+	if HashOneTimeCode(code2) == hash {
+		t.Error("expected different codes to have different hashes")
+	}
+}
+
+func TestHashOneTimeCodeNormalizes(t *testing.T) {
+	testCases := []struct {
+		name string
+		code string
+	}{
+		{name: "LowerCase", code: "abcdefgh234567qr"},
+		{name: "Whitespace", code: " ABCDEFGH234567QR\n"},
+	}
+
+	want := HashOneTimeCode("ABCDEFGH234567QR")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := HashOneTimeCode(tc.code); got != want {
+				t.Errorf("expected %s, got %s", want, got)
+			}
+		})
+	}
+}
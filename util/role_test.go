@@ -0,0 +1,28 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSupportedRole(t *testing.T) {
+	testCases := []struct {
+		role     string
+		expected bool
+	}{
+		{DepositorRole, true},
+		{BankerRole, true},
+		{AdminRole, true},
+		{"teller", false},
+		{"Admin", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.role, func(t *testing.T) {
+			result := IsSupportedRole(tc.role)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package util
+
+const (
+	PasetoTokenType       = "paseto"
+	JWTTokenType          = "jwt"
+	PasetoPublicTokenType = "paseto_public"
+	JWTPublicTokenType    = "jwt_public"
+)
+
+// IsSupportedTokenType checks if the given token type is supported.
+func IsSupportedTokenType(tokenType string) bool {
+	switch tokenType {
+	case PasetoTokenType, JWTTokenType, PasetoPublicTokenType, JWTPublicTokenType:
+		return true
+	}
+	return false
+}
+
+// IsSymmetricTokenType checks if tokens of the given type are signed with TOKEN_SYMMETRIC_KEY
+// rather than with the key pair at TOKEN_PRIVATE_KEY_PATH.
+func IsSymmetricTokenType(tokenType string) bool {
+	return tokenType == PasetoTokenType || tokenType == JWTTokenType
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSupportedTokenType(t *testing.T) {
+	testCases := []struct {
+		tokenType string
+		supported bool
+		symmetric bool
+	}{
+		{PasetoTokenType, true, true},
+		{JWTTokenType, true, true},
+		{PasetoPublicTokenType, true, false},
+		{JWTPublicTokenType, true, false},
+		{"PASETO", false, false},
+		{"", false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.tokenType, func(t *testing.T) {
+			require.Equal(t, tc.supported, IsSupportedTokenType(tc.tokenType))
+			require.Equal(t, tc.symmetric, IsSymmetricTokenType(tc.tokenType))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret is the SHA1 secret of the RFC 6238 test vectors
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	// the RFC 6238 appendix B vectors for SHA1, truncated to 6 digits
+	testCases := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tc.unix, 0)))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, code)
+		})
+	}
+
+	_, err := TOTPCode("not base32!", 1)
+	require.Error(t, err)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := RandomTOTPSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+
+	now := time.Now()
+	step := TOTPStep(now)
+	code, err := TOTPCode(secret, step)
+	require.NoError(t, err)
+
+	got, ok := ValidateTOTP(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, step, got)
+
+	// one period of clock drift either way is accepted
+	got, ok = ValidateTOTP(secret, code, now.Add(TOTPPeriod))
+	require.True(t, ok)
+	require.Equal(t, step, got)
+	_, ok = ValidateTOTP(secret, code, now.Add(-TOTPPeriod))
+	require.True(t, ok)
+
+	_, ok = ValidateTOTP(secret, code, now.Add(3*TOTPPeriod))
+	require.False(t, ok)
+	_, ok = ValidateTOTP(secret, "12345", now)
+	require.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(TOTPURI("SimpleBank", "alice", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/SimpleBank:alice", uri.Path)
+
+	query := uri.Query()
+	require.Equal(t, "JBSWY3DPEHPK3PXP", query.Get("secret"))
+	require.Equal(t, "SimpleBank", query.Get("issuer"))
+	require.Equal(t, "6", query.Get("digits"))
+	require.Equal(t, "30", query.Get("period"))
+}
@@ -0,0 +1,40 @@
+package util
+
+import "fmt"
+
+// TransferLimits caps the money leaving an account, in the account's currency. Zero means no limit.
+type TransferLimits struct {
+	PerTransfer int64 `json:"per_transfer"`
+	Daily       int64 `json:"daily"`
+	Monthly     int64 `json:"monthly"`
+}
+
+// ParseTransferLimits builds the default limits of each currency from comma-separated
+// CURRENCY:AMOUNT lists, e.g. "USD:100000,EUR:90000", one for each kind of limit.
+func ParseTransferLimits(perTransfer, daily, monthly string) (map[string]TransferLimits, error) {
+	limits := make(map[string]TransferLimits)
+
+	settings := []struct {
+		value string
+		set   func(*TransferLimits, int64)
+	}{
+		{perTransfer, func(l *TransferLimits, amount int64) { l.PerTransfer = amount }},
+		{daily, func(l *TransferLimits, amount int64) { l.Daily = amount }},
+		{monthly, func(l *TransferLimits, amount int64) { l.Monthly = amount }},
+	}
+
+	for _, setting := range settings {
+		amounts, err := ParseCurrencyAmounts(setting.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transfer limit: %w", err)
+		}
+
+		for currency, amount := range amounts {
+			currencyLimits := limits[currency]
+			setting.set(&currencyLimits, amount)
+			limits[currency] = currencyLimits
+		}
+	}
+
+	return limits, nil
+}
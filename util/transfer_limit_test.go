@@ -0,0 +1,50 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTransferLimits(t *testing.T) {
+	testCases := []struct {
+		name        string
+		perTransfer string
+		daily       string
+		monthly     string
+		expected    map[string]TransferLimits
+		wantErr     bool
+	}{
+		{
+			name:     "Empty",
+			expected: map[string]TransferLimits{},
+		},
+		{
+			name:        "PerCurrency",
+			perTransfer: "USD:1000, EUR:900",
+			daily:       "USD:5000",
+			monthly:     "USD:20000,CAD:30000",
+			expected: map[string]TransferLimits{
+				USD: {PerTransfer: 1000, Daily: 5000, Monthly: 20000},
+				EUR: {PerTransfer: 900},
+				CAD: {Monthly: 30000},
+			},
+		},
+		{name: "MissingAmount", daily: "USD", wantErr: true},
+		{name: "UnsupportedCurrency", daily: "GBP:100", wantErr: true},
+		{name: "NotANumber", monthly: "USD:lots", wantErr: true},
+		{name: "NotPositive", perTransfer: "USD:0", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limits, err := ParseTransferLimits(tc.perTransfer, tc.daily, tc.monthly)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, limits)
+		})
+	}
+}